@@ -14,7 +14,10 @@ import (
 type DB interface {
 	GetTable(string) (Table, error)
 	CreateTable(string, core.Cols) error
+	CreateUnloggedTable(string, core.Cols) error
+	CreateTempTable(string, core.Cols, core.OnCommitAction) error
 	DropTable(string) error
+	AddNotice(string)
 }
 
 // Table is interface of table.
@@ -24,17 +27,32 @@ type Table interface {
 	GetColNames() core.ColumnNames
 	GetRows() []Row
 	GetCols() core.Cols
-	InsertValues(core.ColumnNames, core.ValuesList) error
+	SetCols(core.Cols)
+	GetPersistence() core.Persistence
+	InsertValues(core.ColumnNames, core.ValuesList) (Table, error)
+	Upsert(core.ColumnNames, core.ValuesList, *OnConflict, ReturningFunc) (Table, error)
+	GetUniqueKeys() []core.UniqueKey
+	SetUniqueKeys([]core.UniqueKey)
 	RenameTableName(string)
 	Project(core.ColumnNames, []func(Row) (core.Value, error)) (Table, error)
 	Where(func(Row) (core.Value, error)) (Table, error)
 	CrossJoin(Table) (Table, error)
-	OrderBy(core.ColumnNames, []int) (Table, error)
+	Join(Table, JoinType, func(Row) (core.Value, error)) (Table, error)
+	HashJoin(Table, JoinType, JoinKeys, func(Row) (core.Value, error)) (Table, error)
+	MergeJoin(Table, JoinType, JoinKeys, func(Row) (core.Value, error)) (Table, error)
+	LateralJoin(func(Row) (Table, error), JoinType, func(Row) (core.Value, error)) (Table, error)
+	OrderBy([]func(Row) (core.Value, error), func(x, y core.Values) (int, error)) (Table, error)
 	Limit(int) (Table, error)
-	Update(core.ColumnNames, func(Row) (core.Value, error), []func(Row) (core.Value, error)) (Table, error)
-	Delete(func(Row) (core.Value, error)) (Table, error)
+	Offset(int) (Table, error)
+	Update(Table, core.ColumnNames, func(Row) (core.Value, error), []func(Row) (core.Value, error), ReturningFunc) (Table, error)
+	Delete(Table, func(Row) (core.Value, error), ReturningFunc) (Table, error)
+	Truncate(bool) error
 }
 
+// ReturningFunc computes the result of INSERT, UPDATE or DELETE from the affected rows such as RETURNING clause.
+// It's called before the rows are applied to the table, so that the table is left unchanged if it fails.
+type ReturningFunc func(affected Table) (Table, error)
+
 // Row is interface of row of table.
 type Row interface {
 	// GetValueByColName is used in ColRefNode when getting value
@@ -47,54 +65,113 @@ type Row interface {
 // Database is struct for Database
 type Database struct {
 	Tables map[string]*DBTable
+	// nextOID is the oid which is assigned to the next created table
+	nextOID int
 }
 
 // NewDatabase is constructor of Database
 func NewDatabase() *Database {
 	return &Database{
-		Tables: make(map[string]*DBTable),
+		Tables:  make(map[string]*DBTable),
+		nextOID: firstUserOID,
 	}
 }
 
 // CreateTable is method to create table
 func (db *Database) CreateTable(tableName string, cols core.Cols) error {
+	return db.createTable(tableName, cols, core.Permanent)
+}
+
+// CreateUnloggedTable creates a table whose data changes skip the durability log
+func (db *Database) CreateUnloggedTable(tableName string, cols core.Cols) error {
+	return db.createTable(tableName, cols, core.Unlogged)
+}
+
+// CreateTempTable returns an error because temporary tables belong to a Session
+func (db *Database) CreateTempTable(tableName string, cols core.Cols, onCommit core.OnCommitAction) error {
+	return errors.New("ERROR:  cannot create temporary relation outside of a session")
+}
+
+func (db *Database) createTable(tableName string, cols core.Cols, persistence core.Persistence) error {
 	if _, ok := db.Tables[tableName]; ok {
 		return fmt.Errorf(`ERROR:  relation %v already exist`, tableName)
 	}
 
+	tb := newDBTable(tableName, cols, persistence)
+	tb.OID = db.newOID()
+	db.Tables[tableName] = tb
+	return nil
+}
+
+// newOID assigns an oid to a new table. An oid is never reused even if the table is dropped.
+func (db *Database) newOID() int {
+	oid := db.nextOID
+	db.nextOID++
+	return oid
+}
+
+func newDBTable(tableName string, cols core.Cols, persistence core.Persistence) *DBTable {
 	colNames := make(core.ColumnNames, 0, len(cols))
 	for _, col := range cols {
 		colNames = append(colNames, col.ColName)
 	}
 
-	db.Tables[tableName] = &DBTable{
-		Name:     tableName,
-		ColNames: colNames,
-		Cols:     cols,
-		Rows:     make(DBRows, 0),
+	return &DBTable{
+		Name:        tableName,
+		ColNames:    colNames,
+		Cols:        cols,
+		Rows:        make(DBRows, 0),
+		Persistence: persistence,
 	}
-	return nil
 }
 
-// GetTable gets table from DB
+// GetTable gets table from DB.
+// Tables of pg_catalog and information_schema are generated from metadata of DB.
 func (db *Database) GetTable(tableName string) (Table, error) {
-	if _, ok := db.Tables[tableName]; !ok {
-		return nil, fmt.Errorf(`ERROR:  relation "%v" does not exist`, tableName)
+	return db.getTable(tableName, nil)
+}
+
+func (db *Database) getTable(tableName string, tempTables map[string]*DBTable) (Table, error) {
+	schema, name := SplitTableName(tableName)
+	if def := findCatalogDef(schema, name); def != nil {
+		return newCatalogTable(def, db.Tables, tempTables), nil
 	}
 
-	tb := db.Tables[tableName]
-	return tb, nil
+	if schema == "" || schema == PublicSchema {
+		if tb, ok := db.Tables[name]; ok {
+			return tb, nil
+		}
+	}
+
+	return nil, fmt.Errorf(`ERROR:  relation "%v" does not exist`, tableName)
 }
 
 // DropTable drop table from DB
 func (db *Database) DropTable(tableName string) error {
-	if _, ok := db.Tables[tableName]; ok {
-		delete(db.Tables, tableName)
-		return nil
+	schema, name := SplitTableName(tableName)
+	if schema == "" || schema == PublicSchema {
+		if _, ok := db.Tables[name]; ok {
+			delete(db.Tables, name)
+			return nil
+		}
 	}
 	return fmt.Errorf(`ERROR: relation "%v" does not exist`, tableName)
 }
 
+// AddNotice drops the notice message because Database has no client to receive it
+// such as when the durability log is replayed. Notices are sent to the client of Session.
+func (db *Database) AddNotice(string) {}
+
+// NewTable is constructor of DBTable which holds an intermediate result and doesn't belong to DB
+func NewTable(tableName string, cols core.Cols, valsList core.ValuesList) *DBTable {
+	tb := newDBTable(tableName, cols, core.Temporary)
+	for _, vals := range valsList {
+		tb.Rows = append(tb.Rows, &DBRow{ColNames: tb.ColNames, Values: vals})
+	}
+
+	return tb
+}
+
 // DBRow is struct of row of table
 type DBRow struct {
 	ColNames core.ColumnNames
@@ -112,12 +189,19 @@ const (
 
 // GetValueByColName gets value from row by ColName
 func (r *DBRow) GetValueByColName(name core.ColumnName) (core.Value, error) {
+	idx := -1
 	for k, v := range r.ColNames {
-		if v == name {
-			return r.Values[k], nil
+		if name.Matches(v) {
+			if idx >= 0 {
+				return nil, fmt.Errorf(`ERROR:  column reference "%v" is ambiguous`, name.String())
+			}
+			idx = k
 		}
 	}
-	return nil, fmt.Errorf(`ERROR:  column "%v" does not exist`, name.String())
+	if idx < 0 {
+		return nil, fmt.Errorf(`ERROR:  column "%v" does not exist`, name.String())
+	}
+	return r.Values[idx], nil
 }
 
 // GetValues gets values from DBRow
@@ -184,18 +268,24 @@ func (c ColNameIndexes) Copy() ColNameIndexes {
 
 // DBTable is struct for DBTable
 type DBTable struct {
-	Name     string
-	ColNames core.ColumnNames
-	Cols     core.Cols
-	Rows     DBRows
+	// OID is pg_class.oid of the table, which is assigned when the table is created
+	OID         int
+	Name        string
+	ColNames    core.ColumnNames
+	Cols        core.Cols
+	Rows        DBRows
+	Persistence core.Persistence
+	UniqueKeys  []core.UniqueKey
 }
 
 // Copy copies DBTable
 func (t *DBTable) Copy() Table {
 	tb := &DBTable{
-		ColNames: t.ColNames.Copy(),
-		Cols:     t.Cols.Copy(),
-		Rows:     t.Rows.Copy(),
+		ColNames:    t.ColNames.Copy(),
+		Cols:        t.Cols.Copy(),
+		Rows:        t.Rows.Copy(),
+		Persistence: t.Persistence,
+		UniqueKeys:  t.UniqueKeys,
 	}
 	return tb
 }
@@ -215,6 +305,16 @@ func (t *DBTable) GetCols() core.Cols {
 	return t.Cols
 }
 
+// GetPersistence returns persistence of table
+func (t *DBTable) GetPersistence() core.Persistence {
+	return t.Persistence
+}
+
+// SetCols sets Cols in Table
+func (t *DBTable) SetCols(cols core.Cols) {
+	t.Cols = cols
+}
+
 // SetColNames sets ColNames in Table
 func (t *DBTable) SetColNames(names core.ColumnNames) {
 	t.ColNames = names
@@ -231,16 +331,26 @@ func (t *DBTable) GetRows() []Row {
 	return rows
 }
 
-// InsertValues inserts values into the table
-func (t *DBTable) InsertValues(names core.ColumnNames, valsList core.ValuesList) error {
+// InsertValues inserts values into the table.
+// It returns a table which holds the inserted rows.
+func (t *DBTable) InsertValues(names core.ColumnNames, valsList core.ValuesList) (Table, error) {
+	return t.Upsert(names, valsList, nil, nil)
+}
+
+// makeRows makes rows to be inserted. Columns which are not given are filled with default values.
+// Without names, the values are given to the leading columns.
+func (t *DBTable) makeRows(names core.ColumnNames, valsList core.ValuesList) (DBRows, error) {
+	colNames := t.GetColNames()
 	if len(names) == 0 {
-		names = t.GetColNames()
+		names = colNames
+		if len(valsList) > 0 && len(valsList[0]) < len(colNames) {
+			names = colNames[:len(valsList[0])]
+		}
 	}
-	colNames := t.GetColNames()
 
 	err := t.validateInsert(names, valsList)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	numCols := len(colNames)
@@ -253,12 +363,90 @@ func (t *DBTable) InsertValues(names core.ColumnNames, valsList core.ValuesList)
 		}
 	}
 
+	rows := make(DBRows, 0, len(valsList))
 	for _, vals := range valsList {
 		row := &DBRow{ColNames: colNames, Values: make(core.Values, numCols)}
 		for vi, ci := range indexes {
 			row.Values[ci] = vals[vi]
 		}
-		t.Rows = append(t.Rows, row)
+		if err := t.fillDefaults(row, indexes); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// affectedTable makes a table which holds copies of rows affected by INSERT, UPDATE or DELETE.
+// Rows affected by UPDATE ... FROM or DELETE ... USING are followed by the values of the joined row of other.
+func (t *DBTable) affectedTable(rows DBRows, other Table) *DBTable {
+	colNames, cols := t.ColNames.Copy(), t.Cols.Copy()
+	if other != nil {
+		colNames = uniteColNames(colNames, other.GetColNames())
+		cols = uniteCols(cols, other.GetCols())
+	}
+	affected := &DBTable{
+		Name:        t.Name,
+		ColNames:    colNames,
+		Cols:        cols,
+		Rows:        make(DBRows, 0, len(rows)),
+		Persistence: core.Temporary,
+	}
+	for _, row := range rows {
+		vals := make(core.Values, len(row.Values))
+		copy(vals, row.Values)
+		affected.Rows = append(affected.Rows, &DBRow{ColNames: colNames, Values: vals})
+	}
+
+	return affected
+}
+
+// returningResult calls returning with the affected rows. Without returning, the affected rows are the result.
+func returningResult(affected *DBTable, returning ReturningFunc) (Table, error) {
+	if returning == nil {
+		return affected, nil
+	}
+
+	return returning(affected)
+}
+
+// fillDefaults sets default values into columns which are not given or given as DEFAULT.
+func (t *DBTable) fillDefaults(row *DBRow, given []int) error {
+	for ci, col := range t.Cols {
+		if ci >= len(row.Values) {
+			continue
+		}
+		isGiven := false
+		for _, gi := range given {
+			if gi == ci {
+				isGiven = true
+			}
+		}
+		if isGiven && row.Values[ci] != core.Default {
+			continue
+		}
+
+		v, err := col.DefaultValue()
+		if err != nil {
+			return err
+		}
+		row.Values[ci] = v
+	}
+
+	return nil
+}
+
+// Truncate deletes all rows of the table.
+// If restartIdentity is true, sequences of the table's columns are restarted.
+func (t *DBTable) Truncate(restartIdentity bool) error {
+	t.Rows = make(DBRows, 0)
+	if restartIdentity {
+		for _, col := range t.Cols {
+			if col.Sequence != nil {
+				col.Sequence.Restart()
+			}
+		}
 	}
 
 	return nil
@@ -266,13 +454,14 @@ func (t *DBTable) InsertValues(names core.ColumnNames, valsList core.ValuesList)
 
 func (t *DBTable) validateInsert(names core.ColumnNames, valuesList core.ValuesList) error {
 	for _, vals := range valuesList {
-		if len(names) != len(vals) {
-			return errors.New("invalid insert elements")
+		if len(vals) > len(names) {
+			return errors.New("ERROR:  INSERT has more expressions than target columns")
+		}
+		if len(vals) < len(names) {
+			return errors.New("ERROR:  INSERT has more target columns than expressions")
 		}
 	}
 
-	// TODO: 型で validation かける
-
 	return nil
 }
 
@@ -298,6 +487,7 @@ func (t *DBTable) RenameTableName(name string) {
 func (t *DBTable) Project(TargetColNames core.ColumnNames, resFuncs []func(Row) (core.Value, error)) (Table, error) {
 	rows := t.GetRows()
 	if len(rows) == 0 {
+		t.ColNames = projectColNames(t.GetColNames(), TargetColNames)
 		return t, nil
 	}
 	newRows := make(DBRows, 0, len(rows))
@@ -346,12 +536,24 @@ func (t *DBTable) Project(TargetColNames core.ColumnNames, resFuncs []func(Row)
 		tbColNames = append(tbColNames, name)
 	}
 	t.ColNames = tbColNames
-	// TODO: implement SetCols if type validation is implemented
-	// newTable.SetCols(cols)
 
 	return t, nil
 }
 
+// projectColNames makes column names of projected table from target column names.
+func projectColNames(colNames, targetColNames core.ColumnNames) core.ColumnNames {
+	names := make(core.ColumnNames, 0, len(targetColNames))
+	for _, name := range targetColNames {
+		if (name == core.ColumnName{Name: "*"}) {
+			names = append(names, colNames...)
+		} else {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
 // Where filters rows by given where conditions
 func (t *DBTable) Where(condFn func(Row) (core.Value, error)) (Table, error) {
 	srcRows := t.Rows
@@ -391,6 +593,96 @@ func (t *DBTable) CrossJoin(rtb Table) (Table, error) {
 	}, nil
 }
 
+// JoinType is a type of join
+type JoinType int
+
+const (
+	// InnerJoin returns only joined rows satisfying the condition
+	InnerJoin JoinType = iota
+
+	// LeftJoin also returns rows of the left table which have no partner
+	LeftJoin
+
+	// RightJoin also returns rows of the right table which have no partner
+	RightJoin
+
+	// FullJoin also returns rows of both tables which have no partner
+	FullJoin
+)
+
+// Join joins the table with rtb by condFn.
+// In outer joins, rows which have no partner are padded with NULL.
+func (t *DBTable) Join(rtb Table, joinType JoinType, condFn func(Row) (core.Value, error)) (Table, error) {
+	partners := make([]int, len(rtb.GetRows()))
+	for k := range partners {
+		partners[k] = k
+	}
+
+	res := newJoinResult(t, rtb, joinType, condFn)
+	for _, lrow := range t.GetRows() {
+		if err := res.join(lrow, partners); err != nil {
+			return nil, err
+		}
+	}
+
+	return res.table(), nil
+}
+
+// LateralJoin joins each row of the table with rows of the table made by rfn from the row.
+// Only inner and left joins are allowed as in PostgreSQL.
+func (t *DBTable) LateralJoin(rfn func(Row) (Table, error), joinType JoinType, condFn func(Row) (core.Value, error)) (Table, error) {
+	if joinType != InnerJoin && joinType != LeftJoin {
+		return nil, errors.New("ERROR:  The combining JOIN type must be INNER or LEFT for a LATERAL reference.")
+	}
+
+	var rtb Table
+	rows := make([]*DBRow, 0)
+	for _, r1 := range t.GetRows() {
+		var err error
+		rtb, err = rfn(r1)
+		if err != nil {
+			return nil, err
+		}
+		matched := false
+		for _, r2 := range rtb.GetRows() {
+			row := uniteRow(r1, r2).(*DBRow)
+			v, err := condFn(row)
+			if err != nil {
+				return nil, err
+			}
+			if v == core.True {
+				rows = append(rows, row)
+				matched = true
+			}
+		}
+		if !matched && joinType == LeftJoin {
+			rows = append(rows, uniteRow(r1, nullRow(rtb.GetColNames())).(*DBRow))
+		}
+	}
+	if rtb == nil {
+		// columns of the right table are taken by evaluating it with NULLs
+		var err error
+		rtb, err = rfn(nullRow(t.GetColNames()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &DBTable{
+		ColNames: uniteColNames(t.GetColNames(), rtb.GetColNames()),
+		Cols:     uniteCols(t.GetCols(), rtb.GetCols()),
+		Rows:     rows,
+	}, nil
+}
+
+// nullRow makes a row whose values are all NULL
+func nullRow(names core.ColumnNames) Row {
+	return &DBRow{
+		ColNames: names,
+		Values:   make(core.Values, len(names)),
+	}
+}
+
 func uniteRow(r1, r2 Row) Row {
 	vals := make(core.Values, 0)
 	for _, v := range r1.GetValues() {
@@ -431,51 +723,57 @@ func uniteCols(l, r core.Cols) core.Cols {
 	for _, c := range l {
 		cs = append(cs, c)
 	}
-	for _, c := range l {
+	for _, c := range r {
 		cs = append(cs, c)
 	}
 
 	return cs
 }
 
-// OrderBy sorts rows by given column names
-func (t *DBTable) OrderBy(cols core.ColumnNames, sortDirs []int) (Table, error) {
-	if err := validateOrderByColumn(t.ColNames, cols); err != nil {
-		return nil, err
+// OrderBy sorts rows stably. keyFns are evaluated for each row
+// and rows are ordered by comparing the values with cmp.
+func (t *DBTable) OrderBy(keyFns []func(Row) (core.Value, error), cmp func(x, y core.Values) (int, error)) (Table, error) {
+	type sortRow struct {
+		row  *DBRow
+		keys core.Values
+	}
+	sortRows := make([]sortRow, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		keys := make(core.Values, 0, len(keyFns))
+		for _, fn := range keyFns {
+			v, err := fn(row)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, v)
+		}
+		sortRows = append(sortRows, sortRow{row: row, keys: keys})
 	}
 
-	rows := t.Rows
-	name := cols[0]
-	sortDir := sortDirs[0]
-	sort.Slice(rows, func(i, j int) bool {
-		l, _ := rows[i].GetValueByColName(name)
-		r, _ := rows[j].GetValueByColName(name)
-
-		return core.LessForSort(l, r, sortDir)
+	var sortErr error
+	sort.SliceStable(sortRows, func(i, j int) bool {
+		c, err := cmp(sortRows[i].keys, sortRows[j].keys)
+		if err != nil {
+			sortErr = err
+		}
+		return c < 0
 	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
 
+	rows := make([]*DBRow, 0, len(sortRows))
+	for _, r := range sortRows {
+		rows = append(rows, r.row)
+	}
 	t.Rows = rows
 
 	return t, nil
 }
 
-func validateOrderByColumn(tbCols, targets core.ColumnNames) error {
-	for _, tc := range targets {
-		if (tc == core.ColumnName{}) {
-			// expresison
-			continue
-		}
-		if !haveColumn(tc, tbCols) {
-			return fmt.Errorf(`column "%v" does not exist`, tc.String())
-		}
-	}
-
-	return nil
-}
-
 func haveColumn(c core.ColumnName, cs core.ColumnNames) bool {
 	for _, col := range cs {
-		if c == col {
+		if c.Matches(col) {
 			return true
 		}
 	}
@@ -506,43 +804,144 @@ func (t *DBTable) Limit(N int) (Table, error) {
 	}, nil
 }
 
-// Update updates records
-func (t *DBTable) Update(colNames core.ColumnNames, condFn func(Row) (core.Value, error), assignValFns []func(Row) (core.Value, error)) (Table, error) {
-	rows := t.Rows
-	for _, row := range rows {
-		a, err := condFn(row)
+// Offset skips given number of records
+func (t *DBTable) Offset(N int) (Table, error) {
+	if N == 0 {
+		return t, nil
+	}
+	oldRows := t.GetRows()
+	newRows := make([]*DBRow, 0)
+	for i := N; i < len(oldRows); i++ {
+		row := oldRows[i]
+		newRows = append(newRows,
+			&DBRow{
+				ColNames: row.GetColNames(),
+				Values:   row.GetValues(),
+			})
+	}
+
+	return &DBTable{
+		ColNames: t.GetColNames(),
+		Cols:     t.GetCols(),
+		Rows:     newRows,
+	}, nil
+}
+
+// Update updates rows which satisfy condFn.
+// If from is given, each row is joined with rows of from and updated by the first joined row
+// which satisfies condFn. Assigned values are computed from values before the update.
+// It returns the result of returning for the updated rows, which are followed by the joined values of from.
+func (t *DBTable) Update(from Table, colNames core.ColumnNames, condFn func(Row) (core.Value, error), assignValFns []func(Row) (core.Value, error), returning ReturningFunc) (Table, error) {
+	p := newPendingRows(t)
+	updatedRows := make(DBRows, 0)
+	for _, row := range t.Rows {
+		joined, err := matchRow(row, from, condFn)
 		if err != nil {
 			return nil, err
 		}
-		if a == core.True {
-			for k, name := range colNames {
-				v, err := assignValFns[k](row)
-				if err != nil {
-					return nil, err
-				}
-				row.UpdateValue(name, v)
+		if joined != nil {
+			vals, err := t.assignValues(joined, colNames, assignValFns)
+			if err != nil {
+				return nil, err
 			}
+			p.update(row, vals)
+			// the joined values of from follow the values of the table
+			joinedVals := append(append(core.Values{}, vals...), joined.GetValues()[len(vals):]...)
+			updatedRows = append(updatedRows, &DBRow{ColNames: joined.GetColNames(), Values: joinedVals})
 		}
 	}
 
-	return nil, nil
+	for _, row := range t.Rows {
+		if vals, ok := p.updated[row]; ok {
+			if err := p.checkKeys(vals, row); err != nil {
+				return nil, err
+			}
+		}
+	}
+	res, err := returningResult(t.affectedTable(updatedRows, from), returning)
+	if err != nil {
+		return nil, err
+	}
+	p.commit()
+
+	return res, nil
 }
 
-func (t *DBTable) Delete(condFn func(Row) (core.Value, error)) (Table, error) {
+// assignValues returns values of the row whose columns are replaced with assigned values.
+// Values of joined columns following the table's columns are dropped.
+func (t *DBTable) assignValues(row Row, colNames core.ColumnNames, assignValFns []func(Row) (core.Value, error)) (core.Values, error) {
+	vals := make(core.Values, len(t.ColNames))
+	copy(vals, row.GetValues())
+	for k, name := range colNames {
+		v, err := assignValFns[k](row)
+		if err != nil {
+			return nil, err
+		}
+		for ci, colName := range t.ColNames {
+			if colName.Name == name.Name {
+				vals[ci] = v
+			}
+		}
+	}
+
+	return vals, nil
+}
+
+// Delete deletes rows which satisfy condFn.
+// If using is given, a row is deleted when any row joined with rows of using satisfies condFn.
+// It returns the result of returning for the deleted rows, which are followed by the joined values of using.
+func (t *DBTable) Delete(using Table, condFn func(Row) (core.Value, error), returning ReturningFunc) (Table, error) {
 	updatedRows := make([]*DBRow, 0)
+	deletedRows := make(DBRows, 0)
 	for _, row := range t.Rows {
-		v, err := condFn(row)
+		joined, err := matchRow(row, using, condFn)
 		if err != nil {
 			return nil, err
 		}
-		if v == core.True {
-			continue
+		if joined != nil {
+			deletedRows = append(deletedRows, &DBRow{ColNames: joined.GetColNames(), Values: joined.GetValues()})
 		} else {
 			updatedRows = append(updatedRows, row)
 		}
 	}
 
+	res, err := returningResult(t.affectedTable(deletedRows, using), returning)
+	if err != nil {
+		return nil, err
+	}
 	t.Rows = updatedRows
+
+	return res, nil
+}
+
+// matchRow returns the first row which is made by joining row with rows of other table
+// and satisfies condFn. If other is nil, row itself is tested.
+// It returns nil if there is no such row.
+func matchRow(row *DBRow, other Table, condFn func(Row) (core.Value, error)) (Row, error) {
+	if other == nil {
+		v, err := condFn(row)
+		if err != nil || v != core.True {
+			return nil, err
+		}
+		return row, nil
+	}
+
+	for _, otherRow := range other.GetRows() {
+		names := make(core.ColumnNames, 0, len(row.ColNames)+len(otherRow.GetColNames()))
+		names = append(append(names, row.ColNames...), otherRow.GetColNames()...)
+		vals := make(core.Values, 0, len(names))
+		vals = append(append(vals, row.Values...), otherRow.GetValues()...)
+		joined := &DBRow{ColNames: names, Values: vals}
+
+		v, err := condFn(joined)
+		if err != nil {
+			return nil, err
+		}
+		if v == core.True {
+			return joined, nil
+		}
+	}
+
 	return nil, nil
 }
 
@@ -76,6 +76,7 @@ func TestCreate(t *testing.T) {
 				},
 			},
 			wantedTable: &DBTable{
+				OID:  firstUserOID,
 				Name: "hoge",
 				ColNames: core.ColumnNames{
 					{TableName: "hoge", Name: "id"},
@@ -267,3 +268,148 @@ func TestProject(t *testing.T) {
 		})
 	}
 }
+
+func TestJoin(t *testing.T) {
+	lcn := core.ColumnName{TableName: "hoge", Name: "id"}
+	rcn := core.ColumnName{TableName: "piyo", Name: "hoge_id"}
+	cond := func(row Row) (core.Value, error) {
+		l, _ := row.GetValueByColName(lcn)
+		r, _ := row.GetValueByColName(rcn)
+		if l == r {
+			return core.True, nil
+		}
+		return core.False, nil
+	}
+
+	newTables := func() (*DBTable, *DBTable) {
+		ltb := newDBTable("hoge", core.Cols{{ColName: lcn, ColType: core.Integer}}, core.Permanent)
+		ltb.InsertValues(nil, core.ValuesList{{1}, {2}})
+		rtb := newDBTable("piyo", core.Cols{{ColName: rcn, ColType: core.Integer}}, core.Permanent)
+		rtb.InsertValues(nil, core.ValuesList{{2}, {3}})
+		return ltb, rtb
+	}
+
+	tests := []struct {
+		name     string
+		joinType JoinType
+		expected core.ValuesList
+	}{
+		{
+			name:     "inner join",
+			joinType: InnerJoin,
+			expected: core.ValuesList{{2, 2}},
+		},
+		{
+			name:     "left join",
+			joinType: LeftJoin,
+			expected: core.ValuesList{{1, nil}, {2, 2}},
+		},
+		{
+			name:     "right join",
+			joinType: RightJoin,
+			expected: core.ValuesList{{2, 2}, {nil, 3}},
+		},
+		{
+			name:     "full join",
+			joinType: FullJoin,
+			expected: core.ValuesList{{1, nil}, {2, 2}, {nil, 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ltb, rtb := newTables()
+			tb, err := ltb.Join(rtb, tt.joinType, cond)
+			assert.NoError(t, err)
+
+			actual := make(core.ValuesList, 0)
+			for _, row := range tb.GetRows() {
+				actual = append(actual, row.GetValues())
+			}
+			assert.Equal(t, tt.expected, actual)
+			assert.Equal(t, core.ColumnNames{lcn, rcn}, tb.GetColNames())
+		})
+	}
+}
+
+func TestEquiJoin(t *testing.T) {
+	lcn := core.ColumnName{TableName: "hoge", Name: "id"}
+	rcn := core.ColumnName{TableName: "piyo", Name: "hoge_id"}
+	keys := JoinKeys{
+		Left: []func(Row) (core.Value, error){
+			func(row Row) (core.Value, error) { return row.GetValueByColName(lcn) },
+		},
+		Right: []func(Row) (core.Value, error){
+			func(row Row) (core.Value, error) { return row.GetValueByColName(rcn) },
+		},
+	}
+	cond := func(row Row) (core.Value, error) {
+		return core.True, nil
+	}
+
+	newTables := func() (*DBTable, *DBTable) {
+		ltb := newDBTable("hoge", core.Cols{{ColName: lcn, ColType: core.Integer}}, core.Permanent)
+		ltb.InsertValues(nil, core.ValuesList{{3}, {1}, {nil}, {2}})
+		rtb := newDBTable("piyo", core.Cols{{ColName: rcn, ColType: core.Integer}}, core.Permanent)
+		rtb.InsertValues(nil, core.ValuesList{{2}, {4}, {nil}, {3}, {2}})
+		return ltb, rtb
+	}
+
+	tests := []struct {
+		name     string
+		joinType JoinType
+		expected core.ValuesList
+	}{
+		{
+			name:     "inner join",
+			joinType: InnerJoin,
+			expected: core.ValuesList{{3, 3}, {2, 2}, {2, 2}},
+		},
+		{
+			name:     "left join",
+			joinType: LeftJoin,
+			expected: core.ValuesList{{3, 3}, {1, nil}, {nil, nil}, {2, 2}, {2, 2}},
+		},
+		{
+			name:     "right join",
+			joinType: RightJoin,
+			expected: core.ValuesList{{3, 3}, {2, 2}, {2, 2}, {nil, 4}, {nil, nil}},
+		},
+		{
+			name:     "full join",
+			joinType: FullJoin,
+			expected: core.ValuesList{{3, 3}, {1, nil}, {nil, nil}, {2, 2}, {2, 2}, {nil, 4}, {nil, nil}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		joins := map[string]func(*DBTable, Table) (Table, error){
+			"hash join": func(ltb *DBTable, rtb Table) (Table, error) {
+				return ltb.HashJoin(rtb, tt.joinType, keys, cond)
+			},
+			"merge join": func(ltb *DBTable, rtb Table) (Table, error) {
+				return ltb.MergeJoin(rtb, tt.joinType, keys, cond)
+			},
+			"equi join": func(ltb *DBTable, rtb Table) (Table, error) {
+				return EquiJoin(ltb, rtb, tt.joinType, keys, cond)
+			},
+		}
+		for method, join := range joins {
+			join := join
+			t.Run(tt.name+" by "+method, func(t *testing.T) {
+				ltb, rtb := newTables()
+				tb, err := join(ltb, rtb)
+				assert.NoError(t, err)
+
+				actual := make(core.ValuesList, 0)
+				for _, row := range tb.GetRows() {
+					actual = append(actual, row.GetValues())
+				}
+				assert.Equal(t, tt.expected, actual)
+				assert.Equal(t, core.ColumnNames{lcn, rcn}, tb.GetColNames())
+			})
+		}
+	}
+}
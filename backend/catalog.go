@@ -0,0 +1,487 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goropikari/psqlittle/core"
+)
+
+// Schema names
+const (
+	PublicSchema      = "public"
+	TempSchema        = "pg_temp"
+	CatalogSchema     = "pg_catalog"
+	InformationSchema = "information_schema"
+)
+
+// databaseName is reported as the catalog name in information_schema
+const databaseName = "psqlittle"
+
+// firstUserOID is the first oid assigned to user tables as in PostgreSQL
+const firstUserOID = 16384
+
+// The owner of all objects is the bootstrap superuser
+const (
+	OwnerOID  = 10
+	OwnerName = "postgres"
+)
+
+// defaultCollationOID is the oid of the collation of the database
+const defaultCollationOID = 100
+
+// heapAMOID is the oid of the access method of tables
+const heapAMOID = 2
+
+var namespaceOIDs = map[string]int{
+	CatalogSchema:     11,
+	PublicSchema:      2200,
+	InformationSchema: 13000,
+	TempSchema:        16383,
+}
+
+// pgType is a row of pg_type
+type pgType struct {
+	oid      int
+	name     string
+	length   int
+	category string
+	// collation is the oid of the default collation of the type
+	collation int
+	// sqlName is the name which information_schema.columns.data_type shows
+	sqlName string
+}
+
+var pgTypes = map[core.ColType]pgType{
+	core.Boolean: {oid: 16, name: "bool", length: 1, category: "B", sqlName: "boolean"},
+	core.Integer: {oid: 23, name: "int4", length: 4, category: "N", sqlName: "integer"},
+	core.VarChar: {oid: 1043, name: "varchar", length: -1, category: "S", collation: defaultCollationOID, sqlName: "character varying"},
+	core.Float:   {oid: 701, name: "float8", length: 8, category: "N", sqlName: "double precision"},
+	core.Date:    {oid: 1082, name: "date", length: 4, category: "D", sqlName: "date"},
+}
+
+// SplitTableName splits a possibly schema-qualified table name.
+func SplitTableName(tableName string) (string, string) {
+	if i := strings.LastIndex(tableName, "."); i >= 0 {
+		return tableName[:i], tableName[i+1:]
+	}
+
+	return "", tableName
+}
+
+// catalogDef is a definition of a virtual catalog table
+type catalogDef struct {
+	schema string
+	name   string
+	oid    int
+	cols   core.Cols
+	rows   func(*catalog) core.ValuesList
+}
+
+func catalogCols(tableName string, names []string, typs []core.ColType) core.Cols {
+	cols := make(core.Cols, 0, len(names))
+	for k, name := range names {
+		cols = append(cols, core.Col{
+			ColName: core.ColumnName{TableName: tableName, Name: name},
+			ColType: typs[k],
+		})
+	}
+
+	return cols
+}
+
+var catalogDefs = []*catalogDef{
+	{
+		schema: CatalogSchema,
+		name:   "pg_namespace",
+		oid:    2615,
+		cols: catalogCols("pg_namespace",
+			[]string{"oid", "nspname", "nspowner"},
+			[]core.ColType{core.Integer, core.VarChar, core.Integer}),
+		rows: (*catalog).pgNamespace,
+	},
+	{
+		schema: CatalogSchema,
+		name:   "pg_class",
+		oid:    1259,
+		cols: catalogCols("pg_class",
+			[]string{"oid", "relname", "relnamespace", "reltype", "relowner", "relam", "reltablespace", "relhasindex", "relpersistence", "relkind", "relnatts", "relchecks", "relhasrules", "relhastriggers", "relrowsecurity", "relforcerowsecurity", "relispartition", "reloftype", "reltoastrelid", "relreplident"},
+			[]core.ColType{core.Integer, core.VarChar, core.Integer, core.Integer, core.Integer, core.Integer, core.Integer, core.Boolean, core.VarChar, core.VarChar, core.Integer, core.Integer, core.Boolean, core.Boolean, core.Boolean, core.Boolean, core.Boolean, core.Integer, core.Integer, core.VarChar}),
+		rows: (*catalog).pgClass,
+	},
+	{
+		schema: CatalogSchema,
+		name:   "pg_attribute",
+		oid:    1249,
+		cols: catalogCols("pg_attribute",
+			[]string{"attrelid", "attname", "atttypid", "attlen", "attnum", "atttypmod", "attnotnull", "atthasdef", "attidentity", "attgenerated", "attisdropped", "attcollation"},
+			[]core.ColType{core.Integer, core.VarChar, core.Integer, core.Integer, core.Integer, core.Integer, core.Boolean, core.Boolean, core.VarChar, core.VarChar, core.Boolean, core.Integer}),
+		rows: (*catalog).pgAttribute,
+	},
+	{
+		schema: CatalogSchema,
+		name:   "pg_type",
+		oid:    1247,
+		cols: catalogCols("pg_type",
+			[]string{"oid", "typname", "typnamespace", "typowner", "typlen", "typtype", "typcategory", "typrelid", "typelem", "typnotnull", "typbasetype", "typtypmod", "typcollation"},
+			[]core.ColType{core.Integer, core.VarChar, core.Integer, core.Integer, core.Integer, core.VarChar, core.VarChar, core.Integer, core.Integer, core.Boolean, core.Integer, core.Integer, core.Integer}),
+		rows: (*catalog).pgType,
+	},
+	{
+		schema: CatalogSchema,
+		name:   "pg_am",
+		oid:    2601,
+		cols: catalogCols("pg_am",
+			[]string{"oid", "amname", "amtype"},
+			[]core.ColType{core.Integer, core.VarChar, core.VarChar}),
+		rows: (*catalog).pgAm,
+	},
+	{
+		schema: CatalogSchema,
+		name:   "pg_attrdef",
+		oid:    2604,
+		cols: catalogCols("pg_attrdef",
+			[]string{"adrelid", "adnum", "adbin"},
+			[]core.ColType{core.Integer, core.Integer, core.VarChar}),
+		rows: (*catalog).pgAttrdef,
+	},
+	{
+		schema: CatalogSchema,
+		name:   "pg_collation",
+		oid:    3456,
+		cols: catalogCols("pg_collation",
+			[]string{"oid", "collname", "collnamespace"},
+			[]core.ColType{core.Integer, core.VarChar, core.Integer}),
+		rows: (*catalog).pgCollation,
+	},
+	{
+		schema: InformationSchema,
+		name:   "tables",
+		oid:    13001,
+		cols: catalogCols("tables",
+			[]string{"table_catalog", "table_schema", "table_name", "table_type"},
+			[]core.ColType{core.VarChar, core.VarChar, core.VarChar, core.VarChar}),
+		rows: (*catalog).informationSchemaTables,
+	},
+	{
+		schema: InformationSchema,
+		name:   "columns",
+		oid:    13002,
+		cols: catalogCols("columns",
+			[]string{"table_catalog", "table_schema", "table_name", "column_name", "ordinal_position", "column_default", "is_nullable", "data_type", "udt_name"},
+			[]core.ColType{core.VarChar, core.VarChar, core.VarChar, core.VarChar, core.Integer, core.VarChar, core.VarChar, core.VarChar, core.VarChar}),
+		rows: (*catalog).informationSchemaColumns,
+	},
+}
+
+func findCatalogDef(schema, name string) *catalogDef {
+	for _, def := range catalogDefs {
+		if def.name != name {
+			continue
+		}
+		// pg_catalog is always searched before other schemas
+		if def.schema == schema || (schema == "" && def.schema == CatalogSchema) {
+			return def
+		}
+	}
+
+	return nil
+}
+
+// relation is a table listed in the catalogs
+type relation struct {
+	oid    int
+	schema string
+	name   string
+	kind   string
+	cols   core.Cols
+	// persistence is pg_class.relpersistence
+	persistence string
+	// notNull is a set of names of the columns which can't be NULL
+	notNull map[string]bool
+}
+
+// catalog is a snapshot of metadata of the database
+type catalog struct {
+	relations []relation
+}
+
+func newCatalog(tables, tempTables map[string]*DBTable) *catalog {
+	rels := make([]relation, 0)
+	for _, def := range catalogDefs {
+		kind := "r"
+		if def.schema == InformationSchema {
+			kind = "v"
+		}
+		rels = append(rels, relation{
+			oid:         def.oid,
+			schema:      def.schema,
+			name:        def.name,
+			kind:        kind,
+			cols:        def.cols,
+			persistence: "p",
+		})
+	}
+
+	userRels := make([]relation, 0, len(tables)+len(tempTables))
+	for _, tbs := range []map[string]*DBTable{tables, tempTables} {
+		for name, tb := range tbs {
+			schema := PublicSchema
+			persistence := "p"
+			switch tb.Persistence {
+			case core.Unlogged:
+				persistence = "u"
+			case core.Temporary:
+				schema = TempSchema
+				persistence = "t"
+			}
+			userRels = append(userRels, relation{
+				oid:         tb.OID,
+				schema:      schema,
+				name:        name,
+				kind:        "r",
+				cols:        tb.Cols,
+				persistence: persistence,
+				notNull:     notNullCols(tb),
+			})
+		}
+	}
+	// user tables are listed in the order of creation
+	sort.Slice(userRels, func(i, j int) bool {
+		return userRels[i].oid < userRels[j].oid
+	})
+	rels = append(rels, userRels...)
+
+	return &catalog{relations: rels}
+}
+
+// notNullCols returns names of the columns of the primary key
+func notNullCols(tb *DBTable) map[string]bool {
+	names := make(map[string]bool)
+	for _, key := range tb.UniqueKeys {
+		if key.Primary {
+			for _, name := range key.ColNames {
+				names[name.Name] = true
+			}
+		}
+	}
+
+	return names
+}
+
+func (c *catalog) pgNamespace() core.ValuesList {
+	valsList := make(core.ValuesList, 0)
+	for _, name := range []string{CatalogSchema, PublicSchema, InformationSchema, TempSchema} {
+		valsList = append(valsList, core.Values{namespaceOIDs[name], name, OwnerOID})
+	}
+
+	return valsList
+}
+
+func (c *catalog) pgClass() core.ValuesList {
+	valsList := make(core.ValuesList, 0)
+	for _, rel := range c.relations {
+		am := 0
+		if rel.kind == "r" {
+			am = heapAMOID
+		}
+		// catalogs have no replica identity
+		replident := "d"
+		if rel.oid < firstUserOID {
+			replident = "n"
+		}
+		valsList = append(valsList, core.Values{
+			rel.oid, rel.name, namespaceOIDs[rel.schema], 0, OwnerOID, am, 0, core.False,
+			rel.persistence, rel.kind, len(rel.cols), 0, core.False, core.False,
+			core.False, core.False, core.False, 0, 0, replident,
+		})
+	}
+
+	return valsList
+}
+
+func (c *catalog) pgAttribute() core.ValuesList {
+	valsList := make(core.ValuesList, 0)
+	for _, rel := range c.relations {
+		for k, col := range rel.cols {
+			typ := pgTypes[col.ColType]
+			valsList = append(valsList, core.Values{
+				rel.oid, col.ColName.Name, typ.oid, typ.length, k + 1, -1,
+				toBoolType(rel.notNull[col.ColName.Name]), toBoolType(col.Default != nil), "", "", core.False, typ.collation,
+			})
+		}
+	}
+
+	return valsList
+}
+
+func (c *catalog) pgType() core.ValuesList {
+	typs := make([]pgType, 0, len(pgTypes))
+	for _, typ := range pgTypes {
+		typs = append(typs, typ)
+	}
+	sort.Slice(typs, func(i, j int) bool {
+		return typs[i].oid < typs[j].oid
+	})
+
+	valsList := make(core.ValuesList, 0)
+	for _, typ := range typs {
+		valsList = append(valsList, core.Values{
+			typ.oid, typ.name, namespaceOIDs[CatalogSchema], OwnerOID, typ.length, "b",
+			typ.category, 0, 0, core.False, 0, -1, typ.collation,
+		})
+	}
+
+	return valsList
+}
+
+func (c *catalog) pgAm() core.ValuesList {
+	return core.ValuesList{
+		{heapAMOID, "heap", "t"},
+		{403, "btree", "i"},
+		{405, "hash", "i"},
+	}
+}
+
+// pgAttrdef lists the defaults of columns. adbin is the SQL text of the default
+// instead of the internal representation, so that pg_get_expr returns it as it is.
+func (c *catalog) pgAttrdef() core.ValuesList {
+	valsList := make(core.ValuesList, 0)
+	for _, rel := range c.relations {
+		for k, col := range rel.cols {
+			if col.Default != nil {
+				valsList = append(valsList, core.Values{rel.oid, k + 1, col.DefaultExpr})
+			}
+		}
+	}
+
+	return valsList
+}
+
+func (c *catalog) pgCollation() core.ValuesList {
+	catalogOID := namespaceOIDs[CatalogSchema]
+	return core.ValuesList{
+		{defaultCollationOID, "default", catalogOID},
+		{950, "C", catalogOID},
+		{951, "POSIX", catalogOID},
+	}
+}
+
+func (c *catalog) informationSchemaTables() core.ValuesList {
+	valsList := make(core.ValuesList, 0)
+	for _, rel := range c.relations {
+		typ := "BASE TABLE"
+		if rel.kind == "v" {
+			typ = "VIEW"
+		}
+		if rel.persistence == "t" {
+			typ = "LOCAL TEMPORARY"
+		}
+		valsList = append(valsList, core.Values{databaseName, rel.schema, rel.name, typ})
+	}
+
+	return valsList
+}
+
+func (c *catalog) informationSchemaColumns() core.ValuesList {
+	valsList := make(core.ValuesList, 0)
+	for _, rel := range c.relations {
+		for k, col := range rel.cols {
+			typ := pgTypes[col.ColType]
+			nullable := "YES"
+			if rel.notNull[col.ColName.Name] {
+				nullable = "NO"
+			}
+			var def core.Value = core.Null
+			if col.Default != nil {
+				def = col.DefaultExpr
+			}
+			valsList = append(valsList, core.Values{
+				databaseName, rel.schema, rel.name, col.ColName.Name, k + 1,
+				def, nullable, typ.sqlName, typ.name,
+			})
+		}
+	}
+
+	return valsList
+}
+
+// InternalTypeName returns the internal name of the column type such as int4
+func InternalTypeName(typ core.ColType) string {
+	return pgTypes[typ].name
+}
+
+// FormatType returns the SQL name of the type as format_type() does
+func FormatType(oid int) string {
+	for _, typ := range pgTypes {
+		if typ.oid == oid {
+			return typ.sqlName
+		}
+	}
+
+	return "???"
+}
+
+// TableNamespace returns the oid of the schema which the table belongs to
+func TableNamespace(tb Table) int {
+	if c, ok := tb.(*CatalogTable); ok {
+		return namespaceOIDs[c.schema]
+	}
+	if tb.GetPersistence() == core.Temporary {
+		return namespaceOIDs[TempSchema]
+	}
+
+	return namespaceOIDs[PublicSchema]
+}
+
+// CatalogTable is a read-only table generated from metadata of the database
+type CatalogTable struct {
+	*DBTable
+	schema string
+}
+
+func newCatalogTable(def *catalogDef, tables, tempTables map[string]*DBTable) *CatalogTable {
+	tb := newDBTable(def.name, def.cols.Copy(), core.Permanent)
+	tb.OID = def.oid
+	tb.InsertValues(nil, def.rows(newCatalog(tables, tempTables)))
+
+	return &CatalogTable{DBTable: tb, schema: def.schema}
+}
+
+// InsertValues returns an error because catalogs are read-only
+func (t *CatalogTable) InsertValues(core.ColumnNames, core.ValuesList) (Table, error) {
+	return nil, t.permissionDenied()
+}
+
+// Upsert returns an error because catalogs are read-only
+func (t *CatalogTable) Upsert(core.ColumnNames, core.ValuesList, *OnConflict, ReturningFunc) (Table, error) {
+	return nil, t.permissionDenied()
+}
+
+// Update returns an error because catalogs are read-only
+func (t *CatalogTable) Update(Table, core.ColumnNames, func(Row) (core.Value, error), []func(Row) (core.Value, error), ReturningFunc) (Table, error) {
+	return nil, t.permissionDenied()
+}
+
+// Delete returns an error because catalogs are read-only
+func (t *CatalogTable) Delete(Table, func(Row) (core.Value, error), ReturningFunc) (Table, error) {
+	return nil, t.permissionDenied()
+}
+
+// Truncate returns an error because catalogs are read-only
+func (t *CatalogTable) Truncate(bool) error {
+	return t.permissionDenied()
+}
+
+func (t *CatalogTable) permissionDenied() error {
+	return fmt.Errorf("ERROR:  permission denied for table %v", t.Name)
+}
+
+func toBoolType(b bool) core.BoolType {
+	if b {
+		return core.True
+	}
+
+	return core.False
+}
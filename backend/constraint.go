@@ -0,0 +1,380 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goropikari/psqlittle/core"
+)
+
+// ExcludedTableName is the name of the pseudo-table which holds the row proposed for insertion
+// in ON CONFLICT DO UPDATE.
+const ExcludedTableName = "excluded"
+
+// OnConflict is an action of INSERT ... ON CONFLICT
+type OnConflict struct {
+	// Target is the columns of the unique constraint which arbitrates conflicts.
+	// If both Target and ConstraintName are empty, all unique constraints arbitrate.
+	Target         core.ColumnNames
+	ConstraintName string
+	// Alias is an alias of the table which is used in DO UPDATE
+	Alias string
+
+	DoUpdate     bool
+	ColNames     core.ColumnNames
+	AssignValFns []func(Row) (core.Value, error)
+	Condition    func(Row) (core.Value, error)
+}
+
+// GetUniqueKeys returns primary key and unique constraints of the table
+func (t *DBTable) GetUniqueKeys() []core.UniqueKey {
+	return t.UniqueKeys
+}
+
+// SetUniqueKeys sets primary key and unique constraints of the table
+func (t *DBTable) SetUniqueKeys(keys []core.UniqueKey) {
+	t.UniqueKeys = keys
+}
+
+// Upsert inserts values into the table. The rows which conflict with existing rows
+// are skipped or update the existing rows according to onConflict.
+// It returns the result of returning for the inserted and updated rows.
+func (t *DBTable) Upsert(names core.ColumnNames, valsList core.ValuesList, onConflict *OnConflict, returning ReturningFunc) (Table, error) {
+	rows, err := t.makeRows(names, valsList)
+	if err != nil {
+		return nil, err
+	}
+	arbiters, err := t.arbiterKeys(onConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPendingRows(t)
+	affectedRows := make(DBRows, 0)
+	for _, row := range rows {
+		if dup := p.findConflict(arbiters, row.Values); dup != nil {
+			if !onConflict.DoUpdate {
+				continue
+			}
+			if p.touched[dup] {
+				return nil, errors.New("ERROR:  ON CONFLICT DO UPDATE command cannot affect row a second time")
+			}
+			vals, ok, err := p.conflictValues(dup, row, onConflict)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if err := p.checkKeys(vals, dup); err != nil {
+				return nil, err
+			}
+			p.update(dup, vals)
+			affectedRows = append(affectedRows, &DBRow{ColNames: t.ColNames, Values: vals})
+			continue
+		}
+
+		if err := p.checkKeys(row.Values, nil); err != nil {
+			return nil, err
+		}
+		p.insert(row)
+		affectedRows = append(affectedRows, row)
+	}
+	res, err := returningResult(t.affectedTable(affectedRows, nil), returning)
+	if err != nil {
+		return nil, err
+	}
+	p.commit()
+
+	return res, nil
+}
+
+func (t *DBTable) arbiterKeys(onConflict *OnConflict) ([]core.UniqueKey, error) {
+	if onConflict == nil {
+		return nil, nil
+	}
+	if len(onConflict.Target) == 0 && onConflict.ConstraintName == "" {
+		return t.UniqueKeys, nil
+	}
+
+	for _, key := range t.UniqueKeys {
+		if onConflict.ConstraintName != "" {
+			if key.Name == onConflict.ConstraintName {
+				return []core.UniqueKey{key}, nil
+			}
+			continue
+		}
+		if sameColumnSet(key.ColNames, onConflict.Target) {
+			return []core.UniqueKey{key}, nil
+		}
+	}
+	if onConflict.ConstraintName != "" {
+		return nil, fmt.Errorf(`ERROR:  constraint "%v" for table "%v" does not exist`, onConflict.ConstraintName, t.Name)
+	}
+
+	return nil, errors.New("ERROR:  there is no unique or exclusion constraint matching the ON CONFLICT specification")
+}
+
+func sameColumnSet(names, others core.ColumnNames) bool {
+	if len(names) != len(others) {
+		return false
+	}
+	for _, name := range names {
+		found := false
+		for _, other := range others {
+			if name.Name == other.Name {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pendingRows holds rows inserted or updated by a statement.
+// They are applied to the table only after all rows are checked.
+type pendingRows struct {
+	table    *DBTable
+	inserted DBRows
+	updated  map[*DBRow]core.Values
+	// touched is a set of rows which have been inserted or updated by the statement
+	touched map[*DBRow]bool
+	// indexes map values of each unique key to the rows which have the values including pending updates.
+	// An index is built when the key is checked first.
+	indexes map[string]map[interface{}][]*DBRow
+}
+
+func newPendingRows(t *DBTable) *pendingRows {
+	return &pendingRows{
+		table:    t,
+		inserted: make(DBRows, 0),
+		updated:  make(map[*DBRow]core.Values),
+		touched:  make(map[*DBRow]bool),
+		indexes:  make(map[string]map[interface{}][]*DBRow),
+	}
+}
+
+func (p *pendingRows) insert(row *DBRow) {
+	p.inserted = append(p.inserted, row)
+	p.touched[row] = true
+	for _, key := range p.table.UniqueKeys {
+		if index, ok := p.indexes[key.Name]; ok {
+			p.addToIndex(index, key, row, row.Values)
+		}
+	}
+}
+
+func (p *pendingRows) update(row *DBRow, vals core.Values) {
+	for _, key := range p.table.UniqueKeys {
+		if index, ok := p.indexes[key.Name]; ok {
+			p.removeFromIndex(index, key, row, p.valuesOf(row))
+			p.addToIndex(index, key, row, vals)
+		}
+	}
+	p.updated[row] = vals
+	p.touched[row] = true
+}
+
+func (p *pendingRows) commit() {
+	for row, vals := range p.updated {
+		row.Values = vals
+	}
+	p.table.Rows = append(p.table.Rows, p.inserted...)
+}
+
+// valuesOf returns values of the row including pending updates
+func (p *pendingRows) valuesOf(row *DBRow) core.Values {
+	if vals, ok := p.updated[row]; ok {
+		return vals
+	}
+
+	return row.Values
+}
+
+// findConflict finds a row which has the same values as vals in any of keys
+func (p *pendingRows) findConflict(keys []core.UniqueKey, vals core.Values) *DBRow {
+	for _, key := range keys {
+		if dup := p.findDuplicate(key, vals, nil); dup != nil {
+			return dup
+		}
+	}
+
+	return nil
+}
+
+// checkKeys checks that vals don't violate the primary key and unique constraints. The row self is not compared.
+func (p *pendingRows) checkKeys(vals core.Values, self *DBRow) error {
+	for _, key := range p.table.UniqueKeys {
+		if !key.Primary {
+			continue
+		}
+		for k, idx := range p.table.keyIndexes(key) {
+			if isNullValue(vals[idx]) {
+				return fmt.Errorf(`ERROR:  null value in column "%v" of relation "%v" violates not-null constraint`, key.ColNames[k].Name, p.table.Name)
+			}
+		}
+	}
+	for _, key := range p.table.UniqueKeys {
+		if dup := p.findDuplicate(key, vals, self); dup != nil {
+			return fmt.Errorf(`ERROR:  duplicate key value violates unique constraint "%v"`, key.Name)
+		}
+	}
+
+	return nil
+}
+
+// findDuplicate finds a row which has the same values as vals in the key.
+// Values including NULL never conflict as in PostgreSQL.
+func (p *pendingRows) findDuplicate(key core.UniqueKey, vals core.Values, self *DBRow) *DBRow {
+	keyVals, ok := p.table.keyValues(key, vals)
+	if !ok {
+		return nil
+	}
+	for _, row := range p.index(key)[core.HashKey(keyVals)] {
+		if row != self {
+			return row
+		}
+	}
+
+	return nil
+}
+
+// index returns the index of the key. It's built from the rows of the table and the pending rows.
+func (p *pendingRows) index(key core.UniqueKey) map[interface{}][]*DBRow {
+	if index, ok := p.indexes[key.Name]; ok {
+		return index
+	}
+
+	index := make(map[interface{}][]*DBRow)
+	for _, rows := range []DBRows{p.table.Rows, p.inserted} {
+		for _, row := range rows {
+			p.addToIndex(index, key, row, p.valuesOf(row))
+		}
+	}
+	p.indexes[key.Name] = index
+
+	return index
+}
+
+func (p *pendingRows) addToIndex(index map[interface{}][]*DBRow, key core.UniqueKey, row *DBRow, vals core.Values) {
+	if keyVals, ok := p.table.keyValues(key, vals); ok {
+		h := core.HashKey(keyVals)
+		index[h] = append(index[h], row)
+	}
+}
+
+func (p *pendingRows) removeFromIndex(index map[interface{}][]*DBRow, key core.UniqueKey, row *DBRow, vals core.Values) {
+	keyVals, ok := p.table.keyValues(key, vals)
+	if !ok {
+		return
+	}
+	h := core.HashKey(keyVals)
+	rows := index[h]
+	for k, r := range rows {
+		if r == row {
+			index[h] = append(rows[:k:k], rows[k+1:]...)
+			break
+		}
+	}
+	if len(index[h]) == 0 {
+		delete(index, h)
+	}
+}
+
+// keyValues returns the values of the columns of the key.
+// It reports false if any of them is NULL, which is never indexed.
+func (t *DBTable) keyValues(key core.UniqueKey, vals core.Values) (core.Values, bool) {
+	idxs := t.keyIndexes(key)
+	keyVals := make(core.Values, 0, len(idxs))
+	for _, idx := range idxs {
+		if isNullValue(vals[idx]) {
+			return nil, false
+		}
+		keyVals = append(keyVals, vals[idx])
+	}
+
+	return keyVals, true
+}
+
+func isNullValue(v core.Value) bool {
+	return v == nil || v == core.Null
+}
+
+func (t *DBTable) keyIndexes(key core.UniqueKey) []int {
+	idxs := make([]int, 0, len(key.ColNames))
+	for _, name := range key.ColNames {
+		for k, colName := range t.ColNames {
+			if colName.Name == name.Name {
+				idxs = append(idxs, k)
+			}
+		}
+	}
+
+	return idxs
+}
+
+// conflictValues computes new values of the existing row by DO UPDATE SET.
+// The second return value is false if the row doesn't satisfy the WHERE condition.
+func (p *pendingRows) conflictValues(existing, proposed *DBRow, onConflict *OnConflict) (core.Values, bool, error) {
+	colNames := p.table.ColNames
+	if onConflict.Alias != "" {
+		colNames = colNames.Copy()
+		for k := range colNames {
+			colNames[k].TableName = onConflict.Alias
+		}
+	}
+	row := &conflictRow{
+		existing: &DBRow{ColNames: colNames, Values: p.valuesOf(existing)},
+		excluded: proposed,
+	}
+	if onConflict.Condition != nil {
+		v, err := onConflict.Condition(row)
+		if err != nil {
+			return nil, false, err
+		}
+		if v != core.True {
+			return nil, false, nil
+		}
+	}
+
+	vals, err := p.table.assignValues(row, onConflict.ColNames, onConflict.AssignValFns)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return vals, true, nil
+}
+
+// conflictRow is a row evaluated in ON CONFLICT DO UPDATE.
+// Columns qualified by excluded refer to the row proposed for insertion.
+type conflictRow struct {
+	existing *DBRow
+	excluded *DBRow
+}
+
+// GetValueByColName gets value from the existing row or the excluded row
+func (r *conflictRow) GetValueByColName(name core.ColumnName) (core.Value, error) {
+	if name.TableName == ExcludedTableName {
+		return r.excluded.GetValueByColName(core.ColumnName{Name: name.Name})
+	}
+
+	return r.existing.GetValueByColName(name)
+}
+
+// GetValues gets values of the existing row
+func (r *conflictRow) GetValues() core.Values {
+	return r.existing.GetValues()
+}
+
+// GetColNames gets column names of the existing row
+func (r *conflictRow) GetColNames() core.ColumnNames {
+	return r.existing.GetColNames()
+}
+
+// UpdateValue updates value of the existing row
+func (r *conflictRow) UpdateValue(name core.ColumnName, val core.Value) {
+	r.existing.UpdateValue(name, val)
+}
@@ -0,0 +1,327 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/goropikari/psqlittle/core"
+)
+
+// JoinKeys is pairs of expressions which are compared by equality in an equi-join.
+// Left[i] is evaluated on a row of the left table and Right[i] on a row of the right table.
+type JoinKeys struct {
+	Left  []func(Row) (core.Value, error)
+	Right []func(Row) (core.Value, error)
+}
+
+// EquiJoin joins ltb with rtb. Rows are joined when all keys are equal and condFn is true.
+// Merge join is chosen when both tables are already sorted by the keys because it needs
+// neither sorting nor a hash table. Otherwise hash join is chosen.
+func EquiJoin(ltb, rtb Table, joinType JoinType, keys JoinKeys, condFn func(Row) (core.Value, error)) (Table, error) {
+	lsorted, err := sortedByKeys(ltb.GetRows(), keys.Left)
+	if err != nil {
+		return nil, err
+	}
+	rsorted, err := sortedByKeys(rtb.GetRows(), keys.Right)
+	if err != nil {
+		return nil, err
+	}
+	if lsorted && rsorted {
+		return ltb.MergeJoin(rtb, joinType, keys, condFn)
+	}
+
+	return ltb.HashJoin(rtb, joinType, keys, condFn)
+}
+
+// HashJoin joins the table with rtb by building a hash table of rtb on the keys.
+// The order of the result is the same as Join.
+func (t *DBTable) HashJoin(rtb Table, joinType JoinType, keys JoinKeys, condFn func(Row) (core.Value, error)) (Table, error) {
+	rkeys, err := evalJoinKeys(rtb.GetRows(), keys.Right)
+	if err != nil {
+		return nil, err
+	}
+	buckets := make(map[interface{}][]int)
+	for k, key := range rkeys {
+		if key != nil {
+			h := core.HashKey(key)
+			buckets[h] = append(buckets[h], k)
+		}
+	}
+
+	res := newJoinResult(t, rtb, joinType, condFn)
+	for _, lrow := range t.GetRows() {
+		var partners []int
+		if len(buckets) > 0 {
+			key, err := evalJoinKey(lrow, keys.Left)
+			if err != nil {
+				return nil, err
+			}
+			if key != nil {
+				partners = buckets[core.HashKey(key)]
+			}
+		}
+		if err := res.join(lrow, partners); err != nil {
+			return nil, err
+		}
+	}
+
+	return res.table(), nil
+}
+
+// MergeJoin joins the table with rtb by sorting both tables on the keys and merging them.
+// The order of the result is the same as Join.
+func (t *DBTable) MergeJoin(rtb Table, joinType JoinType, keys JoinKeys, condFn func(Row) (core.Value, error)) (Table, error) {
+	lrows := t.GetRows()
+	lkeys, err := evalJoinKeys(lrows, keys.Left)
+	if err != nil {
+		return nil, err
+	}
+	rkeys, err := evalJoinKeys(rtb.GetRows(), keys.Right)
+	if err != nil {
+		return nil, err
+	}
+	lorder := sortKeyIndexes(lkeys)
+	rorder := sortKeyIndexes(rkeys)
+
+	partners := make([][]int, len(lrows))
+	i, j := 0, 0
+	for i < len(lorder) && j < len(rorder) {
+		c := compareJoinKeys(lkeys[lorder[i]], rkeys[rorder[j]])
+		if c < 0 {
+			i++
+			continue
+		}
+		if c > 0 {
+			j++
+			continue
+		}
+
+		iEnd := i + 1
+		for iEnd < len(lorder) && compareJoinKeys(lkeys[lorder[i]], lkeys[lorder[iEnd]]) == 0 {
+			iEnd++
+		}
+		jEnd := j + 1
+		for jEnd < len(rorder) && compareJoinKeys(rkeys[rorder[j]], rkeys[rorder[jEnd]]) == 0 {
+			jEnd++
+		}
+		for _, li := range lorder[i:iEnd] {
+			partners[li] = rorder[j:jEnd]
+		}
+		i, j = iEnd, jEnd
+	}
+
+	res := newJoinResult(t, rtb, joinType, condFn)
+	for k, lrow := range lrows {
+		if err := res.join(lrow, partners[k]); err != nil {
+			return nil, err
+		}
+	}
+
+	return res.table(), nil
+}
+
+// joinResult accumulates joined rows. Rows which have no partner are padded with NULL in outer joins.
+type joinResult struct {
+	ltb      Table
+	rtb      Table
+	joinType JoinType
+	condFn   func(Row) (core.Value, error)
+	rrows    []Row
+	rmatched []bool
+	rows     []*DBRow
+}
+
+func newJoinResult(ltb, rtb Table, joinType JoinType, condFn func(Row) (core.Value, error)) *joinResult {
+	rrows := rtb.GetRows()
+	return &joinResult{
+		ltb:      ltb,
+		rtb:      rtb,
+		joinType: joinType,
+		condFn:   condFn,
+		rrows:    rrows,
+		rmatched: make([]bool, len(rrows)),
+		rows:     make([]*DBRow, 0),
+	}
+}
+
+// join joins lrow with the rows of the right table at partners
+func (j *joinResult) join(lrow Row, partners []int) error {
+	matched := false
+	for _, k := range partners {
+		row := uniteRow(lrow, j.rrows[k]).(*DBRow)
+		v, err := j.condFn(row)
+		if err != nil {
+			return err
+		}
+		if v == core.True {
+			j.rows = append(j.rows, row)
+			matched = true
+			j.rmatched[k] = true
+		}
+	}
+	if !matched && (j.joinType == LeftJoin || j.joinType == FullJoin) {
+		j.rows = append(j.rows, uniteRow(lrow, nullRow(j.rtb.GetColNames())).(*DBRow))
+	}
+
+	return nil
+}
+
+func (j *joinResult) table() *DBTable {
+	if j.joinType == RightJoin || j.joinType == FullJoin {
+		for k, rrow := range j.rrows {
+			if !j.rmatched[k] {
+				j.rows = append(j.rows, uniteRow(nullRow(j.ltb.GetColNames()), rrow).(*DBRow))
+			}
+		}
+	}
+
+	return &DBTable{
+		ColNames: uniteColNames(j.ltb.GetColNames(), j.rtb.GetColNames()),
+		Cols:     uniteCols(j.ltb.GetCols(), j.rtb.GetCols()),
+		Rows:     j.rows,
+	}
+}
+
+// evalJoinKeys evaluates keys for each row
+func evalJoinKeys(rows []Row, keyFns []func(Row) (core.Value, error)) ([]core.Values, error) {
+	keys := make([]core.Values, 0, len(rows))
+	for _, row := range rows {
+		key, err := evalJoinKey(row, keyFns)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// evalJoinKey evaluates keys on the row. It returns nil if any of keys is NULL
+// because NULL is never equal to anything.
+func evalJoinKey(row Row, keyFns []func(Row) (core.Value, error)) (core.Values, error) {
+	key := make(core.Values, 0, len(keyFns))
+	for _, fn := range keyFns {
+		v, err := fn(row)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil || v == core.Null {
+			return nil, nil
+		}
+		if f, ok := v.(float64); ok && math.IsNaN(f) {
+			return nil, nil
+		}
+		key = append(key, v)
+	}
+
+	return key, nil
+}
+
+// sortKeyIndexes returns indexes of keys sorted by the keys. NULL keys are excluded.
+func sortKeyIndexes(keys []core.Values) []int {
+	idxs := make([]int, 0, len(keys))
+	for k, key := range keys {
+		if key != nil {
+			idxs = append(idxs, k)
+		}
+	}
+	sort.SliceStable(idxs, func(i, j int) bool {
+		return compareJoinKeys(keys[idxs[i]], keys[idxs[j]]) < 0
+	})
+
+	return idxs
+}
+
+func sortedByKeys(rows []Row, keyFns []func(Row) (core.Value, error)) (bool, error) {
+	keys, err := evalJoinKeys(rows, keyFns)
+	if err != nil {
+		return false, err
+	}
+	var prev core.Values
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+		if prev != nil && compareJoinKeys(prev, key) > 0 {
+			return false, nil
+		}
+		prev = key
+	}
+
+	return true, nil
+}
+
+func compareJoinKeys(x, y core.Values) int {
+	for k := range x {
+		if c := compareKeyValue(x[k], y[k]); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// compareKeyValue orders values by their types first so that values compared as 0
+// are exactly the values which are equal by = operator.
+func compareKeyValue(x, y core.Value) int {
+	if rx, ry := keyTypeRank(x), keyTypeRank(y); rx != ry {
+		return rx - ry
+	}
+
+	switch xv := x.(type) {
+	case core.BoolType:
+		return int(xv) - int(y.(core.BoolType))
+	case int:
+		yv := y.(int)
+		if xv < yv {
+			return -1
+		}
+		if xv > yv {
+			return 1
+		}
+		return 0
+	case float64:
+		yv := y.(float64)
+		if xv < yv {
+			return -1
+		}
+		if xv > yv {
+			return 1
+		}
+		return 0
+	case string:
+		yv := y.(string)
+		if xv < yv {
+			return -1
+		}
+		if xv > yv {
+			return 1
+		}
+		return 0
+	}
+	if x == y {
+		return 0
+	}
+	xs, ys := fmt.Sprintf("%T %v", x, x), fmt.Sprintf("%T %v", y, y)
+	if xs < ys {
+		return -1
+	}
+
+	return 1
+}
+
+func keyTypeRank(v core.Value) int {
+	switch v.(type) {
+	case core.BoolType:
+		return 0
+	case int:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+
+	return 4
+}
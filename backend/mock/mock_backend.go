@@ -35,6 +35,18 @@ func (m *MockDB) EXPECT() *MockDBMockRecorder {
 	return m.recorder
 }
 
+// AddNotice mocks base method.
+func (m *MockDB) AddNotice(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddNotice", arg0)
+}
+
+// AddNotice indicates an expected call of AddNotice.
+func (mr *MockDBMockRecorder) AddNotice(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNotice", reflect.TypeOf((*MockDB)(nil).AddNotice), arg0)
+}
+
 // CreateTable mocks base method.
 func (m *MockDB) CreateTable(arg0 string, arg1 core.Cols) error {
 	m.ctrl.T.Helper()
@@ -49,6 +61,34 @@ func (mr *MockDBMockRecorder) CreateTable(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTable", reflect.TypeOf((*MockDB)(nil).CreateTable), arg0, arg1)
 }
 
+// CreateTempTable mocks base method.
+func (m *MockDB) CreateTempTable(arg0 string, arg1 core.Cols, arg2 core.OnCommitAction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTempTable", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateTempTable indicates an expected call of CreateTempTable.
+func (mr *MockDBMockRecorder) CreateTempTable(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTempTable", reflect.TypeOf((*MockDB)(nil).CreateTempTable), arg0, arg1, arg2)
+}
+
+// CreateUnloggedTable mocks base method.
+func (m *MockDB) CreateUnloggedTable(arg0 string, arg1 core.Cols) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUnloggedTable", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUnloggedTable indicates an expected call of CreateUnloggedTable.
+func (mr *MockDBMockRecorder) CreateUnloggedTable(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUnloggedTable", reflect.TypeOf((*MockDB)(nil).CreateUnloggedTable), arg0, arg1)
+}
+
 // DropTable mocks base method.
 func (m *MockDB) DropTable(arg0 string) error {
 	m.ctrl.T.Helper()
@@ -131,18 +171,18 @@ func (mr *MockTableMockRecorder) CrossJoin(arg0 interface{}) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockTable) Delete(arg0 func(backend.Row) (core.Value, error)) (backend.Table, error) {
+func (m *MockTable) Delete(arg0 backend.Table, arg1 func(backend.Row) (core.Value, error), arg2 backend.ReturningFunc) (backend.Table, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
 	ret0, _ := ret[0].(backend.Table)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockTableMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+func (mr *MockTableMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTable)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTable)(nil).Delete), arg0, arg1, arg2)
 }
 
 // GetColNames mocks base method.
@@ -187,6 +227,20 @@ func (mr *MockTableMockRecorder) GetName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetName", reflect.TypeOf((*MockTable)(nil).GetName))
 }
 
+// GetPersistence mocks base method.
+func (m *MockTable) GetPersistence() core.Persistence {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPersistence")
+	ret0, _ := ret[0].(core.Persistence)
+	return ret0
+}
+
+// GetPersistence indicates an expected call of GetPersistence.
+func (mr *MockTableMockRecorder) GetPersistence() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPersistence", reflect.TypeOf((*MockTable)(nil).GetPersistence))
+}
+
 // GetRows mocks base method.
 func (m *MockTable) GetRows() []backend.Row {
 	m.ctrl.T.Helper()
@@ -201,12 +255,42 @@ func (mr *MockTableMockRecorder) GetRows() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRows", reflect.TypeOf((*MockTable)(nil).GetRows))
 }
 
+// GetUniqueKeys mocks base method.
+func (m *MockTable) GetUniqueKeys() []core.UniqueKey {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUniqueKeys")
+	ret0, _ := ret[0].([]core.UniqueKey)
+	return ret0
+}
+
+// GetUniqueKeys indicates an expected call of GetUniqueKeys.
+func (mr *MockTableMockRecorder) GetUniqueKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUniqueKeys", reflect.TypeOf((*MockTable)(nil).GetUniqueKeys))
+}
+
+// HashJoin mocks base method.
+func (m *MockTable) HashJoin(arg0 backend.Table, arg1 backend.JoinType, arg2 backend.JoinKeys, arg3 func(backend.Row) (core.Value, error)) (backend.Table, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HashJoin", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(backend.Table)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HashJoin indicates an expected call of HashJoin.
+func (mr *MockTableMockRecorder) HashJoin(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HashJoin", reflect.TypeOf((*MockTable)(nil).HashJoin), arg0, arg1, arg2, arg3)
+}
+
 // InsertValues mocks base method.
-func (m *MockTable) InsertValues(arg0 core.ColumnNames, arg1 core.ValuesList) error {
+func (m *MockTable) InsertValues(arg0 core.ColumnNames, arg1 core.ValuesList) (backend.Table, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "InsertValues", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(backend.Table)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // InsertValues indicates an expected call of InsertValues.
@@ -215,6 +299,36 @@ func (mr *MockTableMockRecorder) InsertValues(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertValues", reflect.TypeOf((*MockTable)(nil).InsertValues), arg0, arg1)
 }
 
+// Join mocks base method.
+func (m *MockTable) Join(arg0 backend.Table, arg1 backend.JoinType, arg2 func(backend.Row) (core.Value, error)) (backend.Table, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Join", arg0, arg1, arg2)
+	ret0, _ := ret[0].(backend.Table)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Join indicates an expected call of Join.
+func (mr *MockTableMockRecorder) Join(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Join", reflect.TypeOf((*MockTable)(nil).Join), arg0, arg1, arg2)
+}
+
+// LateralJoin mocks base method.
+func (m *MockTable) LateralJoin(arg0 func(backend.Row) (backend.Table, error), arg1 backend.JoinType, arg2 func(backend.Row) (core.Value, error)) (backend.Table, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LateralJoin", arg0, arg1, arg2)
+	ret0, _ := ret[0].(backend.Table)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LateralJoin indicates an expected call of LateralJoin.
+func (mr *MockTableMockRecorder) LateralJoin(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LateralJoin", reflect.TypeOf((*MockTable)(nil).LateralJoin), arg0, arg1, arg2)
+}
+
 // Limit mocks base method.
 func (m *MockTable) Limit(arg0 int) (backend.Table, error) {
 	m.ctrl.T.Helper()
@@ -230,8 +344,38 @@ func (mr *MockTableMockRecorder) Limit(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Limit", reflect.TypeOf((*MockTable)(nil).Limit), arg0)
 }
 
+// MergeJoin mocks base method.
+func (m *MockTable) MergeJoin(arg0 backend.Table, arg1 backend.JoinType, arg2 backend.JoinKeys, arg3 func(backend.Row) (core.Value, error)) (backend.Table, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeJoin", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(backend.Table)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeJoin indicates an expected call of MergeJoin.
+func (mr *MockTableMockRecorder) MergeJoin(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeJoin", reflect.TypeOf((*MockTable)(nil).MergeJoin), arg0, arg1, arg2, arg3)
+}
+
+// Offset mocks base method.
+func (m *MockTable) Offset(arg0 int) (backend.Table, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Offset", arg0)
+	ret0, _ := ret[0].(backend.Table)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Offset indicates an expected call of Offset.
+func (mr *MockTableMockRecorder) Offset(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Offset", reflect.TypeOf((*MockTable)(nil).Offset), arg0)
+}
+
 // OrderBy mocks base method.
-func (m *MockTable) OrderBy(arg0 core.ColumnNames, arg1 []int) (backend.Table, error) {
+func (m *MockTable) OrderBy(arg0 []func(backend.Row) (core.Value, error), arg1 func(core.Values, core.Values) (int, error)) (backend.Table, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "OrderBy", arg0, arg1)
 	ret0, _ := ret[0].(backend.Table)
@@ -272,19 +416,72 @@ func (mr *MockTableMockRecorder) RenameTableName(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameTableName", reflect.TypeOf((*MockTable)(nil).RenameTableName), arg0)
 }
 
+// SetCols mocks base method.
+func (m *MockTable) SetCols(arg0 core.Cols) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCols", arg0)
+}
+
+// SetCols indicates an expected call of SetCols.
+func (mr *MockTableMockRecorder) SetCols(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCols", reflect.TypeOf((*MockTable)(nil).SetCols), arg0)
+}
+
+// SetUniqueKeys mocks base method.
+func (m *MockTable) SetUniqueKeys(arg0 []core.UniqueKey) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetUniqueKeys", arg0)
+}
+
+// SetUniqueKeys indicates an expected call of SetUniqueKeys.
+func (mr *MockTableMockRecorder) SetUniqueKeys(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUniqueKeys", reflect.TypeOf((*MockTable)(nil).SetUniqueKeys), arg0)
+}
+
+// Truncate mocks base method.
+func (m *MockTable) Truncate(arg0 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Truncate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Truncate indicates an expected call of Truncate.
+func (mr *MockTableMockRecorder) Truncate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Truncate", reflect.TypeOf((*MockTable)(nil).Truncate), arg0)
+}
+
 // Update mocks base method.
-func (m *MockTable) Update(arg0 core.ColumnNames, arg1 func(backend.Row) (core.Value, error), arg2 []func(backend.Row) (core.Value, error)) (backend.Table, error) {
+func (m *MockTable) Update(arg0 backend.Table, arg1 core.ColumnNames, arg2 func(backend.Row) (core.Value, error), arg3 []func(backend.Row) (core.Value, error), arg4 backend.ReturningFunc) (backend.Table, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(backend.Table)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockTableMockRecorder) Update(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockTableMockRecorder) Update(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTable)(nil).Update), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Upsert mocks base method.
+func (m *MockTable) Upsert(arg0 core.ColumnNames, arg1 core.ValuesList, arg2 *backend.OnConflict, arg3 backend.ReturningFunc) (backend.Table, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upsert", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(backend.Table)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upsert indicates an expected call of Upsert.
+func (mr *MockTableMockRecorder) Upsert(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTable)(nil).Update), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upsert", reflect.TypeOf((*MockTable)(nil).Upsert), arg0, arg1, arg2, arg3)
 }
 
 // Where mocks base method.
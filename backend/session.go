@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/goropikari/psqlittle/core"
+)
+
+// Session is a view of Database for one client connection.
+// Temporary tables belong to the session and are dropped when it is closed.
+type Session struct {
+	db         *Database
+	TempTables map[string]*DBTable
+	onCommit   map[string]core.OnCommitAction
+	notices    []string
+}
+
+// NewSession is constructor of Session
+func NewSession(db *Database) *Session {
+	return &Session{
+		db:         db,
+		TempTables: make(map[string]*DBTable),
+		onCommit:   make(map[string]core.OnCommitAction),
+	}
+}
+
+// GetTable gets table from the session. Temporary tables hide
+// permanent tables which have the same name.
+func (s *Session) GetTable(tableName string) (Table, error) {
+	schema, name := SplitTableName(tableName)
+	if schema == "" || schema == TempSchema {
+		if tb, ok := s.TempTables[name]; ok {
+			return tb, nil
+		}
+		if schema == TempSchema {
+			return nil, fmt.Errorf(`ERROR:  relation "%v" does not exist`, tableName)
+		}
+	}
+
+	return s.db.getTable(tableName, s.TempTables)
+}
+
+// CreateTable creates a permanent table in the database
+func (s *Session) CreateTable(tableName string, cols core.Cols) error {
+	return s.db.CreateTable(tableName, cols)
+}
+
+// CreateUnloggedTable creates an unlogged table in the database
+func (s *Session) CreateUnloggedTable(tableName string, cols core.Cols) error {
+	return s.db.CreateUnloggedTable(tableName, cols)
+}
+
+// CreateTempTable creates a temporary table which is visible only in the session
+func (s *Session) CreateTempTable(tableName string, cols core.Cols, onCommit core.OnCommitAction) error {
+	if _, ok := s.TempTables[tableName]; ok {
+		return fmt.Errorf(`ERROR:  relation %v already exist`, tableName)
+	}
+
+	tb := newDBTable(tableName, cols, core.Temporary)
+	tb.OID = s.db.newOID()
+	s.TempTables[tableName] = tb
+	s.onCommit[tableName] = onCommit
+	return nil
+}
+
+// DropTable drops table. A temporary table is dropped prior to a permanent one.
+func (s *Session) DropTable(tableName string) error {
+	schema, name := SplitTableName(tableName)
+	if schema == "" || schema == TempSchema {
+		if _, ok := s.TempTables[name]; ok {
+			delete(s.TempTables, name)
+			delete(s.onCommit, name)
+			return nil
+		}
+	}
+
+	return s.db.DropTable(tableName)
+}
+
+// AddNotice records a notice message for the client of the session
+func (s *Session) AddNotice(msg string) {
+	s.notices = append(s.notices, msg)
+}
+
+// TakeNotices returns recorded notice messages and clears them
+func (s *Session) TakeNotices() []string {
+	notices := s.notices
+	s.notices = nil
+	return notices
+}
+
+// Commit applies ON COMMIT actions of temporary tables.
+// Every statement is committed on its own, so this is called after each statement.
+func (s *Session) Commit() {
+	for name, action := range s.onCommit {
+		switch action {
+		case core.DeleteRows:
+			s.TempTables[name].Rows = make(DBRows, 0)
+		case core.Drop:
+			delete(s.TempTables, name)
+			delete(s.onCommit, name)
+		}
+	}
+}
+
+// Close drops all temporary tables of the session
+func (s *Session) Close() {
+	s.TempTables = make(map[string]*DBTable)
+	s.onCommit = make(map[string]core.OnCommitAction)
+}
@@ -16,6 +16,7 @@ import (
 
 func main() {
 	db, path := setupDB()
+	sess := backend.NewSession(db)
 	for {
 		reader := bufio.NewReader(os.Stdin)
 		fmt.Print("sql> ")
@@ -25,6 +26,7 @@ func main() {
 			continue
 		}
 		if query == ".exit;" {
+			sess.Close()
 			os.Exit(0)
 		}
 		query = strings.Trim(query, " \n")
@@ -37,14 +39,27 @@ func main() {
 			fmt.Println(err)
 			continue
 		}
-		res, err := raNode.Eval(db)
+		logged := raNode.IsLogged(sess)
+		res, err := raNode.Eval(sess)
+		sess.Commit()
+		for _, notice := range sess.TakeNotices() {
+			fmt.Println("NOTICE: ", notice)
+		}
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
+		if logged {
+			logQuery, err := raNode.LogQuery(sess, query)
+			if err != nil {
+				fmt.Println(err)
+			}
+			if logQuery != "" {
+				writeLog(path, logQuery)
+			}
+		}
 		if res == nil {
 			// DDL
-			writeLog(path, query)
 			continue
 		}
 		recs := res.GetRecords()
@@ -66,7 +81,7 @@ func writeLog(path, query string) {
 	}
 }
 
-func setupDB() (backend.DB, string) {
+func setupDB() (*backend.Database, string) {
 	path := getEnvWithDefault("DB_DATA_PATH", "data.db")
 
 	db := backend.NewDatabase()
@@ -1,6 +1,10 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strings"
+)
 
 // Not negates x
 func Not(x Value) Value {
@@ -54,16 +58,89 @@ func AND(x, y Value) Value {
 	return Null
 }
 
-func LessForSort(x, y Value, sortDir int) bool {
-	if y == Null {
-		return true
+// Compare compares non-NULL values x and y. It returns a negative number if x < y,
+// zero if x = y and a positive number if x > y.
+// Integers and floats are compared as numbers, and NaN is greater than any other number as in PostgreSQL.
+func Compare(x, y Value) (int, error) {
+	switch xv := x.(type) {
+	case int:
+		switch yv := y.(type) {
+		case int:
+			return compareInts(xv, yv), nil
+		case float64:
+			return compareFloats(float64(xv), yv), nil
+		}
+	case float64:
+		switch yv := y.(type) {
+		case int:
+			return compareFloats(xv, float64(yv)), nil
+		case float64:
+			return compareFloats(xv, yv), nil
+		}
+	case string:
+		if yv, ok := y.(string); ok {
+			return strings.Compare(xv, yv), nil
+		}
+	case BoolType:
+		if yv, ok := y.(BoolType); ok {
+			// false < true
+			return int(yv) - int(xv), nil
+		}
+	case DateType:
+		if yv, ok := y.(DateType); ok {
+			return compareInts(xv.Days, yv.Days), nil
+		}
 	}
-	if x == Null {
-		return false
+
+	return 0, fmt.Errorf("ERROR:  operator does not exist: %v < %v", TypeName(x), TypeName(y))
+}
+
+func compareInts(x, y int) int {
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+
+	return 0
+}
+
+func compareFloats(x, y float64) int {
+	xNaN, yNaN := math.IsNaN(x), math.IsNaN(y)
+	switch {
+	case xNaN && yNaN:
+		return 0
+	case xNaN:
+		return 1
+	case yNaN:
+		return -1
+	case x < y:
+		return -1
+	case x > y:
+		return 1
 	}
 
-	if sortDir < 3 {
-		return x.(int) < y.(int)
+	return 0
+}
+
+// hashKey is a comparable form of multiple values
+type hashKey struct {
+	val  Value
+	next interface{}
+}
+
+// HashKey makes a key of map from vals. The keys made from vals are equal
+// if and only if all values are equal by = operator or both NULL.
+func HashKey(vals Values) interface{} {
+	var h interface{}
+	for k := len(vals) - 1; k >= 0; k-- {
+		v := vals[k]
+		if v == nil {
+			v = Null
+		}
+		h = hashKey{val: v, next: h}
 	}
-	return x.(int) > y.(int)
+
+	return h
 }
@@ -1,5 +1,10 @@
 package core
 
+import (
+	"fmt"
+	"time"
+)
+
 // BoolType express SQL boolean including Null
 type BoolType int
 
@@ -20,12 +25,128 @@ const (
 	Wildcard WildcardType = iota
 )
 
+// DefaultType expresses DEFAULT in VALUES of INSERT statement.
+// It is replaced with the default value of the column when the row is inserted.
+type DefaultType int
+
+const (
+	Default DefaultType = iota
+)
+
 // ColType is a type of column
 type ColType int
 
 const (
 	Integer ColType = iota
 	VarChar
+	Boolean
+	Float
+	Date
+)
+
+// DateType express SQL date as the number of days since 1970-01-01
+type DateType struct {
+	Days int
+}
+
+var epoch = time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// NewDate returns the date of the given year, month and day.
+// It reports false if the day doesn't exist.
+func NewDate(year, month, day int) (DateType, bool) {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return DateType{}, false
+	}
+
+	return DateType{Days: int(t.Unix() / (24 * 60 * 60))}, true
+}
+
+// String formats the date in ISO 8601 format as PostgreSQL does by default
+func (d DateType) String() string {
+	t := epoch.AddDate(0, 0, d.Days)
+	if t.Year() <= 0 {
+		// 1 BC is year 0 in ISO 8601
+		return fmt.Sprintf("%04d-%02d-%02d BC", 1-t.Year(), t.Month(), t.Day())
+	}
+
+	return t.Format("2006-01-02")
+}
+
+// TypeOf returns the column type of the value.
+// The second return value is false if the type can't be determined such as Null.
+func TypeOf(v Value) (ColType, bool) {
+	switch v.(type) {
+	case int:
+		return Integer, true
+	case float64:
+		return Float, true
+	case string:
+		return VarChar, true
+	case BoolType:
+		if v != Null {
+			return Boolean, true
+		}
+	case DateType:
+		return Date, true
+	}
+
+	return Integer, false
+}
+
+// TypeName returns the SQL name of the type of the value, which is used in error messages
+func TypeName(v Value) string {
+	if typ, ok := TypeOf(v); ok {
+		return ColTypeName(typ)
+	}
+
+	return "unknown"
+}
+
+// ColTypeName returns the SQL name of the column type, which is used in error messages
+func ColTypeName(typ ColType) string {
+	switch typ {
+	case Integer:
+		return "integer"
+	case Float:
+		return "double precision"
+	case VarChar:
+		return "character varying"
+	case Boolean:
+		return "boolean"
+	case Date:
+		return "date"
+	}
+
+	return "unknown"
+}
+
+// Persistence is persistence of a table
+type Persistence int
+
+const (
+	// Permanent is a table whose changes are written to the durability log
+	Permanent Persistence = iota
+
+	// Unlogged is a table whose data changes skip the durability log
+	Unlogged
+
+	// Temporary is a table which lives only in a session
+	Temporary
+)
+
+// OnCommitAction is an action applied to a temporary table at commit
+type OnCommitAction int
+
+const (
+	// PreserveRows keeps the rows of a temporary table at commit
+	PreserveRows OnCommitAction = iota
+
+	// DeleteRows deletes all rows of a temporary table at commit
+	DeleteRows
+
+	// Drop drops a temporary table at commit
+	Drop
 )
 
 // ColumnName is column name
@@ -41,6 +162,12 @@ func (cn ColumnName) String() string {
 	return cn.TableName + "." + cn.Name
 }
 
+// Matches checks whether the column reference cn refers to the column other.
+// A reference without table name matches columns of any table.
+func (cn ColumnName) Matches(other ColumnName) bool {
+	return (cn.TableName == "" || cn.TableName == other.TableName) && cn.Name == other.Name
+}
+
 // Copy copies ColumnName
 func (cn ColumnName) Copy() ColumnName {
 	return ColumnName{
@@ -95,15 +222,60 @@ type Values []Value
 // ValuesList is list of Values
 type ValuesList []Values
 
+// Sequence generates serial numbers for serial and identity columns
+type Sequence struct {
+	Last int
+}
+
+// Next advances the sequence and returns the new value
+func (s *Sequence) Next() int {
+	s.Last++
+	return s.Last
+}
+
+// Restart resets the sequence to its start value
+func (s *Sequence) Restart() {
+	s.Last = 0
+}
+
 // Col is type of column
 type Col struct {
 	ColName ColumnName
 	ColType ColType
+
+	// Sequence is set when the column is a serial or identity column
+	Sequence *Sequence
+
+	// Default is set when the column has DEFAULT clause
+	Default func() (Value, error)
+
+	// DefaultExpr is the SQL text of DEFAULT clause
+	DefaultExpr string
+}
+
+// DefaultValue returns the value which is inserted when the column is omitted
+func (col Col) DefaultValue() (Value, error) {
+	if col.Sequence != nil {
+		return col.Sequence.Next(), nil
+	}
+	if col.Default != nil {
+		return col.Default()
+	}
+
+	return nil, nil
 }
 
 // Cols is list of Col
 type Cols []Col
 
+// UniqueKey is a primary key or unique constraint of a table
+type UniqueKey struct {
+	Name     string
+	ColNames ColumnNames
+	// Primary is true for the primary key, whose columns can't be NULL
+	Primary bool
+}
+
 // Equal check the equality of Col
 func (col Col) Equal(other Col) bool {
 	return col.ColName.Equal(other.ColName) && col.ColType == other.ColType
@@ -125,9 +297,9 @@ func (cols Cols) NotEqual(others Cols) bool {
 	return !cols.Equal(others)
 }
 
-// Copy copies Col.
+// Copy copies Col. Sequence is shared with the copy.
 func (col Col) Copy() Col {
-	return Col{col.ColName, col.ColType}
+	return Col{col.ColName, col.ColType, col.Sequence, col.Default, col.DefaultExpr}
 }
 
 // Copy copies Cols.
@@ -46,10 +46,12 @@ func Run() {
 	}
 }
 
-func handleConnection(c net.Conn, db backend.DB, path string) {
+func handleConnection(c net.Conn, db *backend.Database, path string) {
 
 	startup(c)
 	defer c.Close()
+	sess := backend.NewSession(db)
+	defer sess.Close()
 	for {
 		tag, query, err := readQuery(c)
 		if err != nil {
@@ -63,7 +65,11 @@ func handleConnection(c net.Conn, db backend.DB, path string) {
 			// 0x58 -> X: terminate
 			return
 		}
-		res, err := handleQuery(db, query)
+		res, logQuery, err := handleQuery(sess, query)
+		sess.Commit()
+		for _, notice := range sess.TakeNotices() {
+			c.Write(makeNoticeMsg(notice))
+		}
 		if err != nil {
 			fmt.Println(err)
 			// Ideally, error msg should be sent if errors occur
@@ -71,11 +77,13 @@ func handleConnection(c net.Conn, db backend.DB, path string) {
 			c.Write(queryReady)
 			continue
 		}
+		if logQuery != "" {
+			writeLog(path, logQuery)
+		}
 		if res == nil {
 			// Query except for SELECT
 			c.Write(acceptMsg)
 			c.Write(queryReady)
-			writeLog(path, query)
 		} else {
 			sendResult(c, res)
 		}
@@ -124,6 +132,33 @@ func makeCommandCompleteMsg(s string) []byte {
 	return payload
 }
 
+func makeNoticeMsg(s string) []byte {
+	body := make([]byte, 0)
+	for _, field := range []struct {
+		typ byte
+		val string
+	}{
+		{0x53, "NOTICE"}, // 0x53 -> S: Severity
+		{0x56, "NOTICE"}, // 0x56 -> V: Severity (non-localized)
+		{0x43, "00000"},  // 0x43 -> C: Code
+		{0x4d, s},        // 0x4d -> M: Message
+	} {
+		body = append(body, field.typ)
+		body = append(body, []byte(field.val)...)
+		body = append(body, 0x00)
+	}
+	body = append(body, 0x00)
+
+	lb := make([]byte, payloadBytesLength)
+	binary.BigEndian.PutUint32(lb, uint32(len(body)+payloadBytesLength))
+	payload := make([]byte, 0)
+	payload = append(payload, 0x4e) // 0x4e -> N: NoticeResponse
+	payload = append(payload, lb...)
+	payload = append(payload, body...)
+
+	return payload
+}
+
 func sendResult(c net.Conn, res trans.Result) {
 	cols := res.GetColumns()
 	header := makeColDesc(cols)
@@ -223,17 +258,24 @@ func makeDataRows(recs core.ValuesList) []byte {
 	return dataRows
 }
 
-func handleQuery(db backend.DB, query string) (trans.Result, error) {
+// handleQuery evaluates the query. It also returns the query which has to be written to the durability log,
+// which is empty if the query isn't logged.
+func handleQuery(db backend.DB, query string) (trans.Result, string, error) {
 	raNode, err := trans.NewPGTranslator(query).Translate()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	logged := raNode.IsLogged(db)
 	res, err := raNode.Eval(db)
+	if err != nil || !logged {
+		return res, "", err
+	}
+	logQuery, err := raNode.LogQuery(db, query)
 	if err != nil {
-		return nil, err
+		log.Println(err)
 	}
 
-	return res, nil
+	return res, logQuery, nil
 }
 
 func readQuery(c net.Conn) (byte, string, error) {
@@ -294,7 +336,7 @@ func writeLog(path, query string) {
 	}
 }
 
-func setupDB() (backend.DB, string) {
+func setupDB() (*backend.Database, string) {
 	path := dataPath
 
 	db := backend.NewDatabase()
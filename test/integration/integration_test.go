@@ -2,6 +2,7 @@ package integration_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/goropikari/psqlittle/backend"
@@ -416,3 +417,4494 @@ func prepareDB() backend.DB {
 	raNode.Eval(db)
 	return db
 }
+
+func TestTempTable(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		queries     []string
+		selectQuery string
+		expected    trans.Result
+	}{
+		{
+			name: "temp table",
+			queries: []string{
+				"create temp table foo (id int, name varchar(255))",
+				"insert into foo values (1, 'taro')",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{1, "taro"},
+				},
+			},
+		},
+		{
+			name: "temp table hides permanent table",
+			queries: []string{
+				"create temporary table hoge (id int)",
+				"insert into hoge values (1)",
+			},
+			selectQuery: "select * from hoge",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+				},
+			},
+		},
+		{
+			name: "on commit delete rows",
+			queries: []string{
+				"create temp table foo (id int) on commit delete rows",
+				"insert into foo values (1)",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{},
+			},
+		},
+		{
+			name: "on commit drop",
+			queries: []string{
+				"create temp table foo (id int) on commit drop",
+			},
+			selectQuery: "select * from foo",
+			expected:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			sess := backend.NewSession(prepareDB().(*backend.Database))
+			for _, query := range tt.queries {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(sess)
+				assert.NoError(t, err)
+				sess.Commit()
+			}
+			raNode, _ := trans.NewPGTranslator(tt.selectQuery).Translate()
+			actual, _ := raNode.Eval(sess)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestTempTableIsDroppedOnClose(t *testing.T) {
+	db := prepareDB().(*backend.Database)
+	sess := backend.NewSession(db)
+	other := backend.NewSession(db)
+
+	raNode, _ := trans.NewPGTranslator("create temp table foo (id int)").Translate()
+	_, err := raNode.Eval(sess)
+	assert.NoError(t, err)
+
+	_, err = sess.GetTable("foo")
+	assert.NoError(t, err)
+	_, err = other.GetTable("foo")
+	assert.Error(t, err)
+	_, err = db.GetTable("foo")
+	assert.Error(t, err)
+
+	sess.Close()
+	_, err = sess.GetTable("foo")
+	assert.Error(t, err)
+}
+
+func TestIsLogged(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		query    string
+		expected bool
+	}{
+		{
+			name:     "create table",
+			query:    "create table foo (id int)",
+			expected: true,
+		},
+		{
+			name:     "create unlogged table",
+			query:    "create unlogged table foo (id int)",
+			expected: true,
+		},
+		{
+			name:     "create temp table",
+			query:    "create temp table foo (id int)",
+			expected: false,
+		},
+		{
+			name:     "insert into permanent table",
+			query:    "insert into hoge values (1, 2, 'foo')",
+			expected: true,
+		},
+		{
+			name:     "insert into unlogged table",
+			query:    "insert into ulog values (1)",
+			expected: false,
+		},
+		{
+			name:     "update temp table",
+			query:    "update tmp set id = 2",
+			expected: false,
+		},
+		{
+			name:     "drop temp table",
+			query:    "drop table tmp",
+			expected: false,
+		},
+		{
+			name:     "drop unlogged table",
+			query:    "drop table ulog",
+			expected: true,
+		},
+		{
+			name:     "select",
+			query:    "select * from hoge",
+			expected: false,
+		},
+	}
+
+	sess := backend.NewSession(prepareDB().(*backend.Database))
+	for _, query := range []string{
+		"create unlogged table ulog (id int)",
+		"create temp table tmp (id int)",
+	} {
+		raNode, _ := trans.NewPGTranslator(query).Translate()
+		raNode.Eval(sess)
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, raNode.IsLogged(sess))
+		})
+	}
+}
+
+func TestReplayLog(t *testing.T) {
+	tests := []struct {
+		name        string
+		queries     []string
+		selectQuery string
+	}{
+		{
+			name: "insert from unlogged table",
+			queries: []string{
+				"create unlogged table stage (id int, name varchar(255))",
+				"insert into stage values (1, 'a'), (2, 'b')",
+				"insert into piyo select id, name from stage",
+			},
+			selectQuery: "select * from piyo",
+		},
+		{
+			name: "insert from temp table with serial column",
+			queries: []string{
+				"create table foo (id serial, name varchar(255))",
+				"create temp table tmp (name varchar(255))",
+				"insert into tmp values ('a'), ('b')",
+				"insert into foo (name) select name from tmp",
+				"insert into foo (name) values ('c')",
+			},
+			selectQuery: "select * from foo",
+		},
+		{
+			name: "insert values of various types",
+			queries: []string{
+				"create table foo (a int, b float, c varchar(255), d bool, e date)",
+				"create temp table tmp (a int, b float, c varchar(255), d bool, e date)",
+				"insert into tmp values (-1, 1, 'it''s \\', true, date '2024-02-29'), (3000000000, -0.5, null, false, null)",
+				"insert into foo select * from tmp",
+				"insert into foo (b) values ('Infinity'::float)",
+			},
+			selectQuery: "select * from foo",
+		},
+		{
+			name: "insert with on conflict from temp table",
+			queries: []string{
+				"create table foo (id int primary key, name varchar(255))",
+				"insert into foo values (1, 'a')",
+				"create temp table tmp (id int, name varchar(255))",
+				"insert into tmp values (1, 'x'), (2, 'y')",
+				"insert into foo select * from tmp on conflict do nothing",
+			},
+			selectQuery: "select * from foo",
+		},
+		{
+			name: "on conflict do update with random values",
+			queries: []string{
+				"create table foo (id int primary key, v float)",
+				"insert into foo values (1, 0), (2, 0)",
+				"insert into foo values (1, 0), (3, 0) on conflict (id) do update set v = random()",
+			},
+			selectQuery: "select * from foo",
+		},
+		{
+			name: "on conflict do update from temp table",
+			queries: []string{
+				"create table foo (id int primary key, n serial, v int)",
+				"insert into foo (id, v) values (1, 1)",
+				"create temp table tmp (v int)",
+				"insert into tmp values (42)",
+				"insert into foo (id, v) values (1, 0), (2, 0) on conflict (id) do update set v = (select v from tmp) where foo.v < (select v from tmp)",
+				"insert into foo (id, v) values (3, 3)",
+			},
+			selectQuery: "select * from foo",
+		},
+		{
+			name: "insert random values",
+			queries: []string{
+				"create table foo (id int, val float)",
+				"insert into foo values (1, random())",
+				"insert into foo select id, random() from hoge",
+			},
+			selectQuery: "select * from foo",
+		},
+		{
+			name: "update with random values",
+			queries: []string{
+				"update hoge set cid = cid * random()",
+			},
+			selectQuery: "select * from hoge",
+		},
+		{
+			name: "update from temp table",
+			queries: []string{
+				"create temp table tmp (id int, name varchar(255))",
+				"insert into tmp values (456, 'jiro')",
+				"update hoge set name = tmp.name from tmp where hoge.id = tmp.id",
+			},
+			selectQuery: "select * from hoge",
+		},
+		{
+			name: "delete using unlogged table",
+			queries: []string{
+				"create unlogged table stage (id int)",
+				"insert into stage values (123), (789)",
+				"delete from hoge using stage where hoge.id = stage.id",
+			},
+			selectQuery: "select * from hoge",
+		},
+		{
+			name: "create table as from temp table",
+			queries: []string{
+				"create temp table tmp (id int, name varchar(255), d date)",
+				"insert into tmp values (1, 'a', '2024-05-01'), (2, null, null)",
+				"create table foo as select id * 2 as x, name, d + 1 as d, 1.5 as f from tmp",
+			},
+			selectQuery: "select * from foo",
+		},
+		{
+			name: "select into with random values",
+			queries: []string{
+				"select id, random() as r into foo from hoge",
+			},
+			selectQuery: "select * from foo",
+		},
+		{
+			name: "create table as from temp table with no data",
+			queries: []string{
+				"create temp table tmp (id int, name varchar(255))",
+				"insert into tmp values (1, 'a')",
+				"create unlogged table foo as select * from tmp with no data",
+			},
+			selectQuery: "select column_name, data_type from information_schema.columns where table_name = 'foo'",
+		},
+		{
+			name: "delete all rows using unlogged table",
+			queries: []string{
+				"create unlogged table stage (id int)",
+				"insert into stage values (321)",
+				"delete from piyo where id in (select id from stage)",
+			},
+			selectQuery: "select * from piyo",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			sess := backend.NewSession(prepareDB().(*backend.Database))
+			logQueries := make([]string, 0)
+			for _, query := range tt.queries {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				logged := raNode.IsLogged(sess)
+				_, err = raNode.Eval(sess)
+				assert.NoError(t, err)
+				if logged {
+					logQuery, err := raNode.LogQuery(sess, query)
+					assert.NoError(t, err)
+					logQueries = append(logQueries, logQuery+";")
+				}
+			}
+
+			// the log is replayed in the same way as the server does
+			replayed := prepareDB()
+			for _, query := range strings.Split(strings.Join(logQueries, ""), ";") {
+				if strings.TrimSpace(query) == "" {
+					continue
+				}
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(replayed)
+				assert.NoError(t, err)
+			}
+
+			raNode, err := trans.NewPGTranslator(tt.selectQuery).Translate()
+			assert.NoError(t, err)
+			expected, err := raNode.Eval(sess)
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(replayed)
+			assert.NoError(t, err)
+
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestCatalogQuery(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+	}{
+		{
+			name:  "pg_class",
+			query: "select relname, relkind, relpersistence from pg_catalog.pg_class where relnamespace = 2200 or relpersistence = 't'",
+			expected: &trans.QueryResult{
+				Columns: []string{"relname", "relkind", "relpersistence"},
+				Records: core.ValuesList{
+					{"hoge", "r", "p"},
+					{"piyo", "r", "p"},
+					{"foo", "r", "t"},
+				},
+			},
+		},
+		{
+			name:  "pg_namespace",
+			query: "select n.nspname from pg_namespace as n where n.oid = 2200",
+			expected: &trans.QueryResult{
+				Columns: []string{"nspname"},
+				Records: core.ValuesList{
+					{"public"},
+				},
+			},
+		},
+		{
+			name:  "pg_attribute",
+			query: "select attname, atttypid, attnum from pg_catalog.pg_attribute where attrelid = 16385",
+			expected: &trans.QueryResult{
+				Columns: []string{"attname", "atttypid", "attnum"},
+				Records: core.ValuesList{
+					{"id", 23, 1},
+					{"name", 1043, 2},
+				},
+			},
+		},
+		{
+			name:  "pg_type",
+			query: "select typname from pg_type where oid = 1043",
+			expected: &trans.QueryResult{
+				Columns: []string{"typname"},
+				Records: core.ValuesList{
+					{"varchar"},
+				},
+			},
+		},
+		{
+			name:  "information_schema.tables",
+			query: "select table_schema, table_name, table_type from information_schema.tables where table_schema = 'public' or table_schema = 'pg_temp'",
+			expected: &trans.QueryResult{
+				Columns: []string{"table_schema", "table_name", "table_type"},
+				Records: core.ValuesList{
+					{"public", "hoge", "BASE TABLE"},
+					{"public", "piyo", "BASE TABLE"},
+					{"pg_temp", "foo", "LOCAL TEMPORARY"},
+				},
+			},
+		},
+		{
+			name:  "information_schema.columns",
+			query: "select column_name, ordinal_position, data_type from information_schema.columns where table_name = 'hoge'",
+			expected: &trans.QueryResult{
+				Columns: []string{"column_name", "ordinal_position", "data_type"},
+				Records: core.ValuesList{
+					{"id", 1, "integer"},
+					{"cid", 2, "integer"},
+					{"name", 3, "character varying"},
+				},
+			},
+		},
+		{
+			name:  "not null columns of primary key",
+			query: "select column_name, is_nullable from information_schema.columns where table_name = 'foo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"column_name", "is_nullable"},
+				Records: core.ValuesList{
+					{"id", "NO"},
+					{"name", "YES"},
+				},
+			},
+		},
+		{
+			name:  "attnotnull",
+			query: "select a.attname, a.attnotnull from pg_attribute a join pg_class c on a.attrelid = c.oid where c.relname = 'foo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"attname", "attnotnull"},
+				Records: core.ValuesList{
+					{"id", true},
+					{"name", false},
+				},
+			},
+		},
+		{
+			name:  "schema qualified column",
+			query: "select pg_catalog.pg_type.typname from pg_catalog.pg_type where pg_catalog.pg_type.oid = 23",
+			expected: &trans.QueryResult{
+				Columns: []string{"typname"},
+				Records: core.ValuesList{
+					{"int4"},
+				},
+			},
+		},
+	}
+
+	sess := backend.NewSession(prepareDB().(*backend.Database))
+	raNode, _ := trans.NewPGTranslator("create temp table foo (id int primary key, name varchar(255))").Translate()
+	raNode.Eval(sess)
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			raNode, _ := trans.NewPGTranslator(tt.query).Translate()
+			actual, err := raNode.Eval(sess)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCatalogIntrospection(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+	}{
+		{
+			name: "psql \\dt",
+			query: `SELECT n.nspname as "Schema",
+  c.relname as "Name",
+  CASE c.relkind WHEN 'r' THEN 'table' WHEN 'v' THEN 'view' WHEN 'm' THEN 'materialized view' WHEN 'i' THEN 'index' WHEN 'S' THEN 'sequence' WHEN 's' THEN 'special' WHEN 'f' THEN 'foreign table' WHEN 'p' THEN 'partitioned table' WHEN 'I' THEN 'partitioned index' END as "Type",
+  pg_catalog.pg_get_userbyid(c.relowner) as "Owner"
+FROM pg_catalog.pg_class c
+     LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r','p','')
+      AND n.nspname <> 'pg_catalog'
+      AND n.nspname <> 'information_schema'
+      AND n.nspname !~ '^pg_toast'
+  AND pg_catalog.pg_table_is_visible(c.oid)
+ORDER BY 1,2`,
+			expected: &trans.QueryResult{
+				Columns: []string{"Schema", "Name", "Type", "Owner"},
+				Records: core.ValuesList{
+					{"pg_temp", "piyo", "table", "postgres"},
+					{"public", "foo", "table", "postgres"},
+					{"public", "hoge", "table", "postgres"},
+				},
+			},
+		},
+		{
+			name: "psql \\d lookup",
+			query: `SELECT c.oid,
+  n.nspname,
+  c.relname
+FROM pg_catalog.pg_class c
+     LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relname OPERATOR(pg_catalog.~) '^(hoge)$' COLLATE pg_catalog.default
+  AND pg_catalog.pg_table_is_visible(c.oid)
+ORDER BY 2, 3`,
+			expected: &trans.QueryResult{
+				Columns: []string{"oid", "nspname", "relname"},
+				Records: core.ValuesList{
+					{16384, "public", "hoge"},
+				},
+			},
+		},
+		{
+			name: "psql \\d table info",
+			query: `SELECT c.relchecks, c.relkind, c.relhasindex, c.relhasrules, c.relhastriggers, c.relrowsecurity, c.relforcerowsecurity, false AS relhasoids, c.relispartition, '', c.reltablespace, CASE WHEN c.reloftype = 0 THEN '' ELSE c.reloftype::pg_catalog.regtype::pg_catalog.text END, c.relpersistence, c.relreplident, am.amname
+FROM pg_catalog.pg_class c
+ LEFT JOIN pg_catalog.pg_class tc ON (c.reltoastrelid = tc.oid)
+LEFT JOIN pg_catalog.pg_am am ON (c.relam = am.oid)
+WHERE c.oid = '16384';`,
+			expected: &trans.QueryResult{
+				Columns: []string{"relchecks", "relkind", "relhasindex", "relhasrules", "relhastriggers", "relrowsecurity", "relforcerowsecurity", "relhasoids", "relispartition", "", "reltablespace", "", "relpersistence", "relreplident", "amname"},
+				Records: core.ValuesList{
+					{0, "r", false, false, false, false, false, false, false, "", 0, "", "p", "d", "heap"},
+				},
+			},
+		},
+		{
+			name: "psql \\d columns",
+			query: `SELECT a.attname,
+  pg_catalog.format_type(a.atttypid, a.atttypmod),
+  (SELECT pg_catalog.pg_get_expr(d.adbin, d.adrelid, true)
+   FROM pg_catalog.pg_attrdef d
+   WHERE d.adrelid = a.attrelid AND d.adnum = a.attnum AND a.atthasdef),
+  a.attnotnull,
+  (SELECT c.collname FROM pg_catalog.pg_collation c, pg_catalog.pg_type t
+   WHERE c.oid = a.attcollation AND t.oid = a.atttypid AND a.attcollation <> t.typcollation) AS attcollation,
+  a.attidentity,
+  a.attgenerated
+FROM pg_catalog.pg_attribute a
+WHERE a.attrelid = '16384' AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum;`,
+			expected: &trans.QueryResult{
+				Columns: []string{"attname", "format_type", "pg_get_expr", "attnotnull", "attcollation", "attidentity", "attgenerated"},
+				Records: core.ValuesList{
+					{"id", "integer", nil, false, nil, "", ""},
+					{"cid", "integer", nil, false, nil, "", ""},
+					{"name", "character varying", nil, false, nil, "", ""},
+				},
+			},
+		},
+		{
+			name: "psql \\d columns with default",
+			query: `SELECT a.attname,
+  pg_catalog.format_type(a.atttypid, a.atttypmod),
+  (SELECT pg_catalog.pg_get_expr(d.adbin, d.adrelid, true)
+   FROM pg_catalog.pg_attrdef d
+   WHERE d.adrelid = a.attrelid AND d.adnum = a.attnum AND a.atthasdef),
+  a.attnotnull,
+  (SELECT c.collname FROM pg_catalog.pg_collation c, pg_catalog.pg_type t
+   WHERE c.oid = a.attcollation AND t.oid = a.atttypid AND a.attcollation <> t.typcollation) AS attcollation,
+  a.attidentity,
+  a.attgenerated
+FROM pg_catalog.pg_attribute a
+WHERE a.attrelid = '16387' AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum;`,
+			expected: &trans.QueryResult{
+				Columns: []string{"attname", "format_type", "pg_get_expr", "attnotnull", "attcollation", "attidentity", "attgenerated"},
+				Records: core.ValuesList{
+					{"id", "integer", nil, true, nil, "", ""},
+					{"name", "character varying", "'anonymous'", false, nil, "", ""},
+				},
+			},
+		},
+		{
+			name:  "column_default",
+			query: "select column_name, column_default from information_schema.columns where table_name = 'foo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"column_name", "column_default"},
+				Records: core.ValuesList{
+					{"id", nil},
+					{"name", "'anonymous'"},
+				},
+			},
+		},
+		{
+			name: "django table list",
+			query: `SELECT c.relname
+FROM pg_catalog.pg_class c
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('f', 'm', 'p', 'r', 'v')
+    AND n.nspname NOT IN ('pg_catalog', 'pg_toast')
+    AND pg_catalog.pg_table_is_visible(c.oid)`,
+			expected: &trans.QueryResult{
+				Columns: []string{"relname"},
+				Records: core.ValuesList{
+					{"hoge"},
+					{"piyo"},
+					{"foo"},
+				},
+			},
+		},
+		{
+			name:  "temporary table hides permanent one",
+			query: "select n.nspname, pg_table_is_visible(c.oid) from pg_class c join pg_namespace n on n.oid = c.relnamespace where c.relname = 'piyo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"nspname", "pg_table_is_visible"},
+				Records: core.ValuesList{
+					{"public", false},
+					{"pg_temp", true},
+				},
+			},
+		},
+		{
+			name:  "unknown objects",
+			query: "select pg_table_is_visible(1), pg_get_userbyid(1), format_type(1, -1), format_type(null, -1)",
+			expected: &trans.QueryResult{
+				Columns: []string{"pg_table_is_visible", "pg_get_userbyid", "format_type", "format_type"},
+				Records: core.ValuesList{
+					{nil, "unknown (OID=1)", "???", nil},
+				},
+			},
+		},
+	}
+
+	sess := backend.NewSession(prepareDB().(*backend.Database))
+	for _, query := range []string{
+		"create temp table piyo (id int)",
+		"create table foo (id int primary key, name varchar(255) default 'anonymous')",
+	} {
+		raNode, _ := trans.NewPGTranslator(query).Translate()
+		raNode.Eval(sess)
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(sess)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestTableOID(t *testing.T) {
+	db := prepareDB()
+	for _, query := range []string{
+		"create table aaa (id int)",
+		"drop table piyo",
+		"create table piyo (id int)",
+	} {
+		raNode, _ := trans.NewPGTranslator(query).Translate()
+		_, err := raNode.Eval(db)
+		assert.NoError(t, err)
+	}
+
+	raNode, _ := trans.NewPGTranslator("select oid, relname from pg_class where relnamespace = 2200").Translate()
+	actual, err := raNode.Eval(db)
+	assert.NoError(t, err)
+	assert.Equal(t, &trans.QueryResult{
+		Columns: []string{"oid", "relname"},
+		Records: core.ValuesList{
+			{16384, "hoge"},
+			{16386, "aaa"},
+			{16387, "piyo"},
+		},
+	}, actual)
+}
+
+func TestCatalogIsReadOnly(t *testing.T) {
+	db := prepareDB()
+
+	for _, query := range []string{
+		"insert into pg_catalog.pg_namespace values (1, 'foo', 10)",
+		"update pg_class set relname = 'foo'",
+		"delete from information_schema.tables",
+	} {
+		raNode, _ := trans.NewPGTranslator(query).Translate()
+		_, err := raNode.Eval(db)
+		assert.Error(t, err)
+	}
+}
+
+func TestIdempotentDDL(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		queries     []string
+		selectQuery string
+		expected    trans.Result
+		notices     []string
+	}{
+		{
+			name: "truncate multiple tables",
+			queries: []string{
+				"truncate hoge, piyo",
+			},
+			selectQuery: "select * from piyo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{},
+			},
+		},
+		{
+			name: "truncate restart identity",
+			queries: []string{
+				"create table foo (id serial, name varchar(255))",
+				"insert into foo (name) values ('taro'), ('hanako')",
+				"truncate table foo restart identity",
+				"insert into foo (name) values ('mike')",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{1, "mike"},
+				},
+			},
+		},
+		{
+			name: "truncate continue identity",
+			queries: []string{
+				"create table foo (id int generated by default as identity, name text)",
+				"insert into foo (name) values ('taro'), ('hanako')",
+				"truncate table foo",
+				"insert into foo (name) values ('mike')",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{3, "mike"},
+				},
+			},
+		},
+		{
+			name: "create table if not exists",
+			queries: []string{
+				"create table if not exists piyo (id int)",
+			},
+			selectQuery: "select * from piyo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{321, "mike1"},
+				},
+			},
+			notices: []string{`relation "piyo" already exists, skipping`},
+		},
+		{
+			name: "drop table if exists cascade",
+			queries: []string{
+				"drop table if exists foo, piyo cascade",
+			},
+			selectQuery: "select * from piyo",
+			expected:    nil,
+			notices:     []string{`table "foo" does not exist, skipping`},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			sess := backend.NewSession(prepareDB().(*backend.Database))
+			for _, query := range tt.queries {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(sess)
+				assert.NoError(t, err)
+			}
+			raNode, _ := trans.NewPGTranslator(tt.selectQuery).Translate()
+			actual, _ := raNode.Eval(sess)
+
+			assert.Equal(t, tt.expected, actual)
+			assert.Equal(t, tt.notices, sess.TakeNotices())
+		})
+	}
+}
+
+func TestNoticesWithoutSession(t *testing.T) {
+	db := prepareDB().(*backend.Database)
+	sess := backend.NewSession(db)
+
+	for _, query := range []string{
+		"create table if not exists piyo (id int)",
+		"drop table if exists foo",
+	} {
+		raNode, _ := trans.NewPGTranslator(query).Translate()
+		_, err := raNode.Eval(db)
+		assert.NoError(t, err)
+	}
+
+	// notices while replaying the log aren't delivered to any session
+	assert.Nil(t, sess.TakeNotices())
+}
+
+func TestDropTableWithoutIfExists(t *testing.T) {
+	db := prepareDB()
+
+	raNode, _ := trans.NewPGTranslator("drop table piyo, foo").Translate()
+	_, err := raNode.Eval(db)
+	assert.Error(t, err)
+
+	// piyo is not dropped because the statement failed
+	_, err = db.GetTable("piyo")
+	assert.NoError(t, err)
+}
+
+func TestCreateTableAs(t *testing.T) {
+	tests := []struct {
+		name        string
+		queries     []string
+		selectQuery string
+		expected    trans.Result
+	}{
+		{
+			name: "create table as select",
+			queries: []string{
+				"create table foo as select id, name from hoge where cid is not null",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{123, "taro"},
+					{456, "hanako"},
+				},
+			},
+		},
+		{
+			name: "create table as select with aliases and expressions",
+			queries: []string{
+				"create table foo as select id as hoge_id, cid + 1, 1.5 as ratio from hoge",
+			},
+			selectQuery: "select column_name, data_type from information_schema.columns where table_name = 'foo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"column_name", "data_type"},
+				Records: core.ValuesList{
+					{"hoge_id", "integer"},
+					{"?column?", "integer"},
+					{"ratio", "double precision"},
+				},
+			},
+		},
+		{
+			name: "create table as select with column names",
+			queries: []string{
+				"create table foo (a, b) as select * from piyo",
+			},
+			selectQuery: "select a, b from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"a", "b"},
+				Records: core.ValuesList{
+					{321, "mike1"},
+				},
+			},
+		},
+		{
+			name: "create table as with no data",
+			queries: []string{
+				"create table foo as select * from hoge with no data",
+			},
+			selectQuery: "select column_name, data_type from information_schema.columns where table_name = 'foo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"column_name", "data_type"},
+				Records: core.ValuesList{
+					{"id", "integer"},
+					{"cid", "integer"},
+					{"name", "character varying"},
+				},
+			},
+		},
+		{
+			name: "create table as expressions with no data",
+			queries: []string{
+				"create table foo as select id * 2 as x, 1.5 as f, name || '!' as s, id > 1 as b, count(*) over () as n, sum(cid) as t from hoge group by id, name with no data",
+			},
+			selectQuery: "select column_name, data_type from information_schema.columns where table_name = 'foo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"column_name", "data_type"},
+				Records: core.ValuesList{
+					{"x", "integer"},
+					{"f", "double precision"},
+					{"s", "character varying"},
+					{"b", "boolean"},
+					{"n", "integer"},
+					{"t", "integer"},
+				},
+			},
+		},
+		{
+			name: "create table as expressions of empty result",
+			queries: []string{
+				"create table foo as select id * 2 as x, 1.5 as f, avg(cid) as a from hoge where id < 0 group by id",
+			},
+			selectQuery: "select column_name, data_type from information_schema.columns where table_name = 'foo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"column_name", "data_type"},
+				Records: core.ValuesList{
+					{"x", "integer"},
+					{"f", "double precision"},
+					{"a", "double precision"},
+				},
+			},
+		},
+		{
+			name: "select into",
+			queries: []string{
+				"select name, id into foo from hoge where id = 789",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "id"},
+				Records: core.ValuesList{
+					{"mike", 789},
+				},
+			},
+		},
+		{
+			name: "select into temporary table",
+			queries: []string{
+				"select * into temp foo from piyo",
+			},
+			selectQuery: "select relpersistence from pg_class where relname = 'foo'",
+			expected: &trans.QueryResult{
+				Columns: []string{"relpersistence"},
+				Records: core.ValuesList{
+					{"t"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			sess := backend.NewSession(prepareDB().(*backend.Database))
+			for _, query := range tt.queries {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(sess)
+				assert.NoError(t, err)
+			}
+			raNode, _ := trans.NewPGTranslator(tt.selectQuery).Translate()
+			actual, err := raNode.Eval(sess)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCreateTableAsError(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{
+			name:  "table already exists",
+			query: "create table piyo as select * from hoge",
+		},
+		{
+			name:  "duplicate column names",
+			query: "create table foo as select id, id from hoge",
+		},
+		{
+			name:  "too many column names",
+			query: "create table foo (a, b, c) as select * from piyo",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			_, err = raNode.Eval(db)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestInsertQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		queries     []string
+		selectQuery string
+		expected    trans.Result
+	}{
+		{
+			name: "insert select",
+			queries: []string{
+				"insert into piyo select id, name from hoge where cid is not null",
+			},
+			selectQuery: "select * from piyo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{321, "mike1"},
+					{123, "taro"},
+					{456, "hanako"},
+				},
+			},
+		},
+		{
+			name: "insert select with column list and expressions",
+			queries: []string{
+				"insert into piyo (name, id) select name, id + 1 from hoge where id = 123",
+			},
+			selectQuery: "select * from piyo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{321, "mike1"},
+					{124, "taro"},
+				},
+			},
+		},
+		{
+			name: "insert default values",
+			queries: []string{
+				"create table foo (id serial, name varchar(255) default 'anonymous', age int)",
+				"insert into foo default values",
+				"insert into foo default values",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "age"},
+				Records: core.ValuesList{
+					{1, "anonymous", nil},
+					{2, "anonymous", nil},
+				},
+			},
+		},
+		{
+			name: "insert values with default and expressions",
+			queries: []string{
+				"create table foo (id serial, name varchar(255) default 'anonymous', age int default 20)",
+				"insert into foo values (default, 'taro', 10 + 5), (10, default, default)",
+				"insert into foo (name) values ('hanako')",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "age"},
+				Records: core.ValuesList{
+					{1, "taro", 15},
+					{10, "anonymous", 20},
+					{2, "hanako", 20},
+				},
+			},
+		},
+		{
+			name: "insert values into leading columns",
+			queries: []string{
+				"create table foo (id int, name varchar(255) default 'anonymous', age int)",
+				"insert into foo values (1)",
+				"insert into foo values (2, 'taro')",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "age"},
+				Records: core.ValuesList{
+					{1, "anonymous", nil},
+					{2, "taro", nil},
+				},
+			},
+		},
+		{
+			name: "insert select into leading columns",
+			queries: []string{
+				"insert into piyo select 1",
+			},
+			selectQuery: "select * from piyo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{321, "mike1"},
+					{1, nil},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range tt.queries {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+			raNode, _ := trans.NewPGTranslator(tt.selectQuery).Translate()
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestDefaultIsNotAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{
+			name:  "values in from clause",
+			query: "select * from (values (1, default)) as t",
+		},
+		{
+			name:  "expression in values of insert",
+			query: "insert into hoge (id) values (default + 1)",
+		},
+		{
+			name:  "select list",
+			query: "select default",
+		},
+		{
+			name:  "select list of insert",
+			query: "insert into hoge (id) select default",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			_, err = raNode.Eval(db)
+			assert.EqualError(t, err, "ERROR:  DEFAULT is not allowed in this context")
+		})
+	}
+}
+
+func TestInsertError(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{
+			name:  "more values than columns",
+			query: "insert into piyo values (1, 'a', 2)",
+			err:   "ERROR:  INSERT has more expressions than target columns",
+		},
+		{
+			name:  "more select items than columns",
+			query: "insert into piyo select 1, 'a', 2",
+			err:   "ERROR:  INSERT has more expressions than target columns",
+		},
+		{
+			name:  "more values than target columns",
+			query: "insert into piyo (name) values ('a', 1)",
+			err:   "ERROR:  INSERT has more expressions than target columns",
+		},
+		{
+			name:  "more target columns than values",
+			query: "insert into piyo (id, name) values (1)",
+			err:   "ERROR:  INSERT has more target columns than expressions",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			_, err = raNode.Eval(db)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestReturning(t *testing.T) {
+	tests := []struct {
+		name     string
+		queries  []string
+		query    string
+		expected trans.Result
+	}{
+		{
+			name: "insert returning generated id",
+			queries: []string{
+				"create table foo (id serial, name varchar(255))",
+				"insert into foo (name) values ('taro')",
+			},
+			query: "insert into foo (name) values ('hanako'), ('mike') returning id, foo.name",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{2, "hanako"},
+					{3, "mike"},
+				},
+			},
+		},
+		{
+			name:  "insert returning wildcard",
+			query: "insert into piyo values (1, 'taro') returning *",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{1, "taro"},
+				},
+			},
+		},
+		{
+			name:  "update returning expressions",
+			query: "update hoge set cid = cid + 1 where cid is not null returning id, cid * 2 as double_cid",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "double_cid"},
+				Records: core.ValuesList{
+					{123, 2002},
+					{456, 1002},
+				},
+			},
+		},
+		{
+			name:  "delete returning",
+			query: "delete from hoge where id = 789 returning *",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name"},
+				Records: core.ValuesList{
+					{789, nil, "mike"},
+				},
+			},
+		},
+		{
+			name:  "delete returning no rows",
+			query: "delete from hoge where id = 0 returning id",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{},
+			},
+		},
+		{
+			name:     "delete without returning",
+			query:    "delete from hoge where id = 789",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range tt.queries {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestReturningError(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected *trans.QueryResult
+	}{
+		{
+			name:  "insert returning",
+			query: "insert into hoge values (1, 2, 'jiro') returning nosuch",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name"},
+				Records: core.ValuesList{
+					{123, 1000, "taro"},
+					{456, 500, "hanako"},
+					{789, nil, "mike"},
+				},
+			},
+		},
+		{
+			name:  "update returning",
+			query: "update hoge set name = 'jiro' returning nosuch",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name"},
+				Records: core.ValuesList{
+					{123, 1000, "taro"},
+					{456, 500, "hanako"},
+					{789, nil, "mike"},
+				},
+			},
+		},
+		{
+			name:  "delete returning",
+			query: "delete from hoge where id = 789 returning nosuch",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name"},
+				Records: core.ValuesList{
+					{123, 1000, "taro"},
+					{456, 500, "hanako"},
+					{789, nil, "mike"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			_, err = raNode.Eval(db)
+			assert.Error(t, err)
+
+			raNode, err = trans.NewPGTranslator("select * from hoge").Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestUniqueConstraint(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []string
+		query   string
+	}{
+		{
+			name: "primary key",
+			queries: []string{
+				"create table foo (id int primary key, name varchar(255))",
+				"insert into foo values (1, 'taro')",
+			},
+			query: "insert into foo values (1, 'hanako')",
+		},
+		{
+			name: "duplicate values in one statement",
+			queries: []string{
+				"create table foo (id int unique, name varchar(255))",
+			},
+			query: "insert into foo values (1, 'taro'), (1, 'hanako')",
+		},
+		{
+			name: "multi-column unique constraint",
+			queries: []string{
+				"create table foo (a int, b int, constraint foo_ab unique (a, b))",
+				"insert into foo values (1, 1), (1, 2)",
+			},
+			query: "insert into foo values (1, 2)",
+		},
+		{
+			name: "update violates unique constraint",
+			queries: []string{
+				"create table foo (id int primary key, name varchar(255))",
+				"insert into foo values (1, 'taro'), (2, 'hanako')",
+			},
+			query: "update foo set id = 1 where id = 2",
+		},
+		{
+			name:  "multiple primary keys",
+			query: "create table foo (id int primary key, name varchar(255), primary key (name))",
+		},
+		{
+			name: "null primary key",
+			queries: []string{
+				"create table foo (id int primary key, name varchar(255))",
+			},
+			query: "insert into foo values (null, 'taro')",
+		},
+		{
+			name: "update primary key to null",
+			queries: []string{
+				"create table foo (id int primary key, name varchar(255))",
+				"insert into foo values (1, 'taro')",
+			},
+			query: "update foo set id = null",
+		},
+		{
+			name: "null in multi-column primary key",
+			queries: []string{
+				"create table foo (a int, b int, primary key (a, b))",
+			},
+			query: "insert into foo values (1, null)",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range tt.queries {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				_, err = raNode.Eval(db)
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOnConflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		queries     []string
+		selectQuery string
+		expected    trans.Result
+	}{
+		{
+			name: "nulls never conflict",
+			queries: []string{
+				"create table foo (id int unique, name varchar(255))",
+				"insert into foo values (null, 'taro'), (null, 'hanako')",
+			},
+			selectQuery: "select name from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"taro"},
+					{"hanako"},
+				},
+			},
+		},
+		{
+			name: "do nothing",
+			queries: []string{
+				"create table foo (id int primary key, name varchar(255))",
+				"insert into foo values (1, 'taro')",
+				"insert into foo values (1, 'hanako'), (2, 'mike') on conflict do nothing",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{1, "taro"},
+					{2, "mike"},
+				},
+			},
+		},
+		{
+			name: "do update with excluded",
+			queries: []string{
+				"create table foo (id int primary key, name varchar(255), cnt int)",
+				"insert into foo values (1, 'taro', 1), (2, 'hanako', 1)",
+				"insert into foo values (1, 'jiro', 5), (3, 'mike', 1) on conflict (id) do update set name = excluded.name, cnt = foo.cnt + excluded.cnt",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "cnt"},
+				Records: core.ValuesList{
+					{1, "jiro", 6},
+					{2, "hanako", 1},
+					{3, "mike", 1},
+				},
+			},
+		},
+		{
+			name: "do update with where and alias",
+			queries: []string{
+				"create table foo (id int, name varchar(255), cnt int, constraint foo_id unique (id))",
+				"insert into foo values (1, 'taro', 1), (2, 'hanako', 10)",
+				"insert into foo as f values (1, 'jiro', 5), (2, 'saburo', 5) on conflict on constraint foo_id do update set name = excluded.name where f.cnt < excluded.cnt",
+			},
+			selectQuery: "select * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "cnt"},
+				Records: core.ValuesList{
+					{1, "jiro", 1},
+					{2, "hanako", 10},
+				},
+			},
+		},
+		{
+			name: "do update returning",
+			queries: []string{
+				"create table foo (id serial primary key, name varchar(255) unique)",
+				"insert into foo (name) values ('taro')",
+			},
+			selectQuery: "insert into foo (name) values ('taro'), ('hanako') on conflict (name) do update set name = excluded.name returning id, name",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{1, "taro"},
+					{3, "hanako"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range tt.queries {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+			raNode, _ := trans.NewPGTranslator(tt.selectQuery).Translate()
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestOnConflictError(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{
+			name:  "no matching constraint",
+			query: "insert into foo values (1, 'taro') on conflict (name) do nothing",
+		},
+		{
+			name:  "affect row a second time",
+			query: "insert into foo values (1, 'taro'), (1, 'hanako') on conflict (id) do update set name = excluded.name",
+		},
+		{
+			name:  "do update without conflict target",
+			query: "insert into foo values (1, 'taro') on conflict do update set name = excluded.name",
+			err:   "ERROR:  ON CONFLICT DO UPDATE requires inference specification or constraint name",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			raNode, _ := trans.NewPGTranslator("create table foo (id int primary key, name varchar(255))").Translate()
+			_, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			raNode, err = trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				_, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUpdateFromDeleteUsing(t *testing.T) {
+	tests := []struct {
+		name        string
+		queries     []string
+		selectQuery string
+		expected    trans.Result
+	}{
+		{
+			name: "update from",
+			queries: []string{
+				"create table foo (id int, name varchar(255))",
+				"insert into foo values (123, 'TARO'), (789, 'MIKE')",
+				"update hoge set name = foo.name, cid = hoge.id + foo.id from foo where hoge.id = foo.id",
+			},
+			selectQuery: "select * from hoge",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name"},
+				Records: core.ValuesList{
+					{123, 246, "TARO"},
+					{456, 500, "hanako"},
+					{789, 1578, "MIKE"},
+				},
+			},
+		},
+		{
+			name: "update from multiple tables with alias",
+			queries: []string{
+				"create table foo (id int, pid int)",
+				"insert into foo values (456, 321)",
+				"update hoge as h set name = p.name from foo f, piyo p where h.id = f.id and f.pid = p.id",
+			},
+			selectQuery: "select * from hoge",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name"},
+				Records: core.ValuesList{
+					{123, 1000, "taro"},
+					{456, 500, "mike1"},
+					{789, nil, "mike"},
+				},
+			},
+		},
+		{
+			name: "delete using",
+			queries: []string{
+				"create table foo (id int)",
+				"insert into foo values (123), (789), (0)",
+				"delete from hoge using foo where hoge.id = foo.id",
+			},
+			selectQuery: "select * from hoge",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name"},
+				Records: core.ValuesList{
+					{456, 500, "hanako"},
+				},
+			},
+		},
+		{
+			name:        "delete using with alias returning",
+			selectQuery: "delete from hoge h using piyo p where h.name || '1' = p.name returning h.id",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{789},
+				},
+			},
+		},
+		{
+			name:        "delete using returning all columns",
+			selectQuery: "delete from hoge h using piyo p where h.name || '1' = p.name returning *",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name", "id", "name"},
+				Records: core.ValuesList{
+					{789, nil, "mike", 321, "mike1"},
+				},
+			},
+		},
+		{
+			name: "update from returning joined columns",
+			queries: []string{
+				"create table src (k int, nm varchar(255))",
+				"insert into src values (123, 'TARO')",
+			},
+			selectQuery: "update hoge set name = src.nm from src where hoge.id = src.k returning hoge.id, name, src.nm",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "nm"},
+				Records: core.ValuesList{
+					{123, "TARO", "TARO"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range tt.queries {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+			raNode, _ := trans.NewPGTranslator(tt.selectQuery).Translate()
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestJoinQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+	}{
+		{
+			name:  "inner join",
+			query: "select hoge.id, foo.name from hoge join foo on hoge.id = foo.hoge_id",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{123, "foo1"},
+					{123, "foo2"},
+				},
+			},
+		},
+		{
+			name:  "left join",
+			query: "select h.id, f.name from hoge h left outer join foo f on h.id = f.hoge_id",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{123, "foo1"},
+					{123, "foo2"},
+					{456, nil},
+					{789, nil},
+				},
+			},
+		},
+		{
+			name:  "right join",
+			query: "select h.id, f.name from hoge h right join foo f on h.id = f.hoge_id",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{123, "foo1"},
+					{123, "foo2"},
+					{nil, "foo3"},
+				},
+			},
+		},
+		{
+			name:  "full join with where",
+			query: "select h.id, f.name from hoge h full join foo f on h.id = f.hoge_id where h.id is null or h.id > 400",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{456, nil},
+					{789, nil},
+					{nil, "foo3"},
+				},
+			},
+		},
+		{
+			name:  "chained joins",
+			query: "select hoge.name, foo.name, piyo.name from hoge join foo on hoge.id = foo.hoge_id left join piyo on foo.name = piyo.name",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "name", "name"},
+				Records: core.ValuesList{
+					{"taro", "foo1", nil},
+					{"taro", "foo2", nil},
+				},
+			},
+		},
+		{
+			name:  "cross join",
+			query: "select hoge.id, piyo.id from hoge cross join piyo",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "id"},
+				Records: core.ValuesList{
+					{123, 321},
+					{456, 321},
+					{789, 321},
+				},
+			},
+		},
+		{
+			name:  "inner join without matching rows",
+			query: "select hoge.id, foo.name from hoge join foo on hoge.cid = foo.hoge_id",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table foo (hoge_id int, name varchar(255))",
+				"insert into foo values (123, 'foo1'), (123, 'foo2'), (999, 'foo3')",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestUsingNaturalLateralJoin(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "join using merges the column",
+			query: "select * from hoge h join (select 123 as id, 'x' as v) s using (id)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name", "v"},
+				Records: core.ValuesList{
+					{123, 1000, "taro", "x"},
+				},
+			},
+		},
+		{
+			name:  "natural join",
+			query: "select * from hoge natural join (select 456 as id, 'hanako' as name) s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "cid"},
+				Records: core.ValuesList{
+					{456, "hanako", 500},
+				},
+			},
+		},
+		{
+			name:  "full join using coalesces the column",
+			query: "select * from hoge h full join piyo p using (id)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "cid", "name", "name"},
+				Records: core.ValuesList{
+					{123, 1000, "taro", nil},
+					{456, 500, "hanako", nil},
+					{789, nil, "mike", nil},
+					{321, nil, nil, "mike1"},
+				},
+			},
+		},
+		{
+			name:  "lateral subquery",
+			query: "select h.id, s.x from hoge h, lateral (select h.id + 1 as x) s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "x"},
+				Records: core.ValuesList{
+					{123, 124},
+					{456, 457},
+					{789, 790},
+				},
+			},
+		},
+		{
+			name:  "left join lateral",
+			query: "select h.id, s.x from hoge h left join lateral (select h.cid as x from piyo where h.cid > 600) s on true",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "x"},
+				Records: core.ValuesList{
+					{123, 1000},
+					{456, nil},
+					{789, nil},
+				},
+			},
+		},
+		{
+			name:  "generate_series with ordinality",
+			query: "select * from generate_series(1, 3) with ordinality as g(v, n)",
+			expected: &trans.QueryResult{
+				Columns: []string{"v", "n"},
+				Records: core.ValuesList{
+					{1, 1},
+					{2, 2},
+					{3, 3},
+				},
+			},
+		},
+		{
+			name:  "generate_series referring preceding from item",
+			query: "select h.id, g from hoge h, generate_series(h.id, h.id + 1) g where h.id < 500",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "g"},
+				Records: core.ValuesList{
+					{123, 123},
+					{123, 124},
+					{456, 456},
+					{456, 457},
+				},
+			},
+		},
+		{
+			name:  "set-returning functions in select list",
+			query: "select h.id, generate_series(1, 2), generate_series(1, 3) from hoge h where h.id < 500 order by 1 desc, 3",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "generate_series", "generate_series"},
+				Records: core.ValuesList{
+					{456, 1, 1},
+					{456, 2, 2},
+					{456, nil, 3},
+					{123, 1, 1},
+					{123, 2, 2},
+					{123, nil, 3},
+				},
+			},
+		},
+		{
+			name:  "set-returning function over aggregate",
+			query: "select count(*), generate_series(1, count(*)) from hoge",
+			expected: &trans.QueryResult{
+				Columns: []string{"count", "generate_series"},
+				Records: core.ValuesList{
+					{3, 1},
+					{3, 2},
+					{3, 3},
+				},
+			},
+		},
+		{
+			name:  "set-returning function in where clause",
+			query: "select id from hoge where generate_series(1, 2) = 1",
+			err:   "ERROR:  set-returning function generate_series is not allowed in this context",
+		},
+		{
+			name:  "unknown using column",
+			query: "select id from hoge join piyo using (nope)",
+			err:   `ERROR:  column "nope" specified in USING clause does not exist in left table`,
+		},
+		{
+			name:  "zero step",
+			query: "select * from generate_series(1, 3, 0)",
+			err:   "ERROR:  step size cannot equal zero",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestEquiJoinQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+	}{
+		{
+			name:  "join large tables by equality",
+			query: "select a.id, b.name from a join b on a.id = b.id where a.id < 3",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{1, "b10000"},
+					{2, "b9999"},
+				},
+			},
+		},
+		{
+			name:  "equality in where clause",
+			query: "select a.id, b.name from a, b where b.id = a.id and a.m = 3 and a.id < 20",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{9, "b9992"},
+					{10, "b9991"},
+					{11, "b9990"},
+				},
+			},
+		},
+		{
+			name:  "self join by multiple keys",
+			query: "select a.id, c.id from a, a c where a.id = c.id and c.m = a.m and a.id + c.id < 5",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "id"},
+				Records: core.ValuesList{
+					{1, 1},
+					{2, 2},
+				},
+			},
+		},
+		{
+			name:  "left join with residual condition",
+			query: "select h.id, a.id from hoge h left join a on h.cid = a.id * 2 and a.m > 0 where h.id < 500",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "id"},
+				Records: core.ValuesList{
+					{123, 500},
+					{456, 250},
+				},
+			},
+		},
+		{
+			name:  "full join with expression keys",
+			query: "select a.id, b.id from a full join b on a.id = b.id + 9998 where a.id < 3 or b.id < 2",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "id"},
+				Records: core.ValuesList{
+					{1, nil},
+					{2, nil},
+					{9999, 1},
+				},
+			},
+		},
+	}
+
+	db := prepareDB()
+	for _, query := range []string{
+		"create table a as select g as id, g / 3 as m from generate_series(1, 10000) g",
+		"create table b as select 10001 - g as id, 'b' || g as name from generate_series(1, 10000) g",
+	} {
+		raNode, _ := trans.NewPGTranslator(query).Translate()
+		_, err := raNode.Eval(db)
+		assert.NoError(t, err)
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestAggregateQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "aggregates without group by",
+			query: "select count(*), count(cid), sum(cid), avg(cid), min(name), max(cid) from hoge",
+			expected: &trans.QueryResult{
+				Columns: []string{"count", "count", "sum", "avg", "min", "max"},
+				Records: core.ValuesList{
+					{3, 2, 1500, float64(750), "hanako", 1000},
+				},
+			},
+		},
+		{
+			name:  "aggregates of no rows",
+			query: "select count(*), sum(cid), avg(cid), max(name) from hoge where id > 1000",
+			expected: &trans.QueryResult{
+				Columns: []string{"count", "sum", "avg", "max"},
+				Records: core.ValuesList{
+					{0, nil, nil, nil},
+				},
+			},
+		},
+		{
+			name:  "group by column",
+			query: "select f.hoge_id, count(*), max(f.name) from foo f group by f.hoge_id",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "count", "max"},
+				Records: core.ValuesList{
+					{123, 2, "foo2"},
+					{999, 1, "foo3"},
+				},
+			},
+		},
+		{
+			name:  "group by expression",
+			query: "select cid is null, sum(id) * 2 from hoge group by cid is null",
+			expected: &trans.QueryResult{
+				Columns: []string{"", ""},
+				Records: core.ValuesList{
+					{false, 1158},
+					{true, 1578},
+				},
+			},
+		},
+		{
+			name:  "group by ordinal",
+			query: "select cid > 600 as big, count(*) from hoge group by 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"big", "count"},
+				Records: core.ValuesList{
+					{true, 1},
+					{false, 1},
+					{nil, 1},
+				},
+			},
+		},
+		{
+			name:  "group by joined column",
+			query: "select hoge.name, count(foo.name) from hoge left join foo on hoge.id = foo.hoge_id group by hoge.name",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "count"},
+				Records: core.ValuesList{
+					{"taro", 2},
+					{"hanako", 0},
+					{"mike", 0},
+				},
+			},
+		},
+		{
+			name:  "group by of no rows",
+			query: "select cid, count(*) from hoge where id > 1000 group by cid",
+			expected: &trans.QueryResult{
+				Columns: []string{"cid", "count"},
+				Records: core.ValuesList{},
+			},
+		},
+		{
+			name:  "ungrouped column",
+			query: "select id, name from hoge group by id",
+			err:   `ERROR:  column "hoge.name" must appear in the GROUP BY clause or be used in an aggregate function`,
+		},
+		{
+			name:  "sum of strings",
+			query: "select sum(name) from hoge",
+			err:   "ERROR:  function sum(character varying) does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table foo (hoge_id int, name varchar(255))",
+				"insert into foo values (123, 'foo1'), (123, 'foo2'), (999, 'foo3')",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestAggregateError(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{
+			name:  "aggregate in where clause",
+			query: "select name from hoge where count(*) > 1",
+			err:   "ERROR:  aggregate functions are not allowed in WHERE",
+		},
+		{
+			name:  "nested aggregate",
+			query: "select sum(count(*)) from hoge",
+			err:   "ERROR:  aggregate function calls cannot be nested",
+		},
+		{
+			name:  "group by position out of range",
+			query: "select id from hoge group by 2",
+			err:   "ERROR:  GROUP BY position 2 is not in select list",
+		},
+		{
+			name:  "order by not in distinct arguments",
+			query: "select string_agg(distinct name, ',' order by id) from hoge",
+			err:   "ERROR:  in an aggregate with DISTINCT, ORDER BY expressions must appear in argument list",
+		},
+		{
+			name:  "grouping of ungrouped column",
+			query: "select grouping(name) from hoge group by rollup (id)",
+			err:   "ERROR:  arguments to GROUPING must be grouping expressions of the associated query level",
+		},
+		{
+			name:  "grouping in where",
+			query: "select count(*) from hoge where grouping(id) = 0",
+			err:   "ERROR:  grouping operations are not allowed in WHERE",
+		},
+		{
+			name:  "order by not in distinct select list",
+			query: "select distinct name from hoge order by id",
+			err:   "ERROR:  for SELECT DISTINCT, ORDER BY expressions must appear in select list",
+		},
+		{
+			name:  "distinct on not matching order by",
+			query: "select distinct on (name) name from hoge order by id",
+			err:   "ERROR:  SELECT DISTINCT ON expressions must match initial ORDER BY expressions",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestAggregateClauses(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "having",
+			query: "select hoge_id, count(*) from foo group by hoge_id having count(*) > 1 and hoge_id > 200",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "count"},
+				Records: core.ValuesList{
+					{999, 2},
+				},
+			},
+		},
+		{
+			name:  "having without group by",
+			query: "select count(*) from foo having count(*) > 5",
+			expected: &trans.QueryResult{
+				Columns: []string{"count"},
+				Records: core.ValuesList{},
+			},
+		},
+		{
+			name:  "filter",
+			query: "select count(*) filter (where hoge_id > 200), sum(hoge_id) filter (where name <> 'foo3'), count(*) from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"count", "sum", "count"},
+				Records: core.ValuesList{
+					{2, 246, 5},
+				},
+			},
+		},
+		{
+			name:  "distinct aggregates",
+			query: "select count(distinct hoge_id), sum(distinct hoge_id), count(hoge_id) from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"count", "sum", "count"},
+				Records: core.ValuesList{
+					{2, 1122, 4},
+				},
+			},
+		},
+		{
+			name:  "string_agg and array_agg with order by",
+			query: "select string_agg(name, ',' order by name desc), array_agg(hoge_id order by hoge_id nulls first), string_agg(distinct name, '-') from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"string_agg", "array_agg", "string_agg"},
+				Records: core.ValuesList{
+					{"foo3,foo3,foo2,foo1,a b", "{NULL,123,123,999,999}", "a b-foo1-foo2-foo3"},
+				},
+			},
+		},
+		{
+			name:  "array_agg quotes elements",
+			query: "select hoge_id, array_agg(name) from foo group by hoge_id having hoge_id is null",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "array_agg"},
+				Records: core.ValuesList{
+					{nil, `{"a b"}`},
+				},
+			},
+		},
+		{
+			name:  "bool_and and bool_or",
+			query: "select bool_and(cid > 600), bool_or(cid > 600), every(cid > 100) from hoge",
+			expected: &trans.QueryResult{
+				Columns: []string{"bool_and", "bool_or", "every"},
+				Records: core.ValuesList{
+					{false, true, true},
+				},
+			},
+		},
+		{
+			name:  "ungrouped column in having",
+			query: "select name from hoge group by name having id > 1",
+			err:   `ERROR:  column "hoge.id" must appear in the GROUP BY clause or be used in an aggregate function`,
+		},
+		{
+			name:  "bool_and of integers",
+			query: "select bool_and(id) from hoge",
+			err:   "ERROR:  function bool_and(integer) does not exist",
+		},
+		{
+			name:  "group by positions of wildcard",
+			query: "select * from foo group by 1, 2 order by 1, 2",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{
+					{123, "foo1"},
+					{123, "foo2"},
+					{999, "foo3"},
+					{nil, "a b"},
+				},
+			},
+		},
+		{
+			name:  "group by position after wildcard",
+			query: "select *, hoge_id from foo group by 1, 2 order by 3",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name", "hoge_id"},
+				Records: core.ValuesList{
+					{123, "foo1", 123},
+					{123, "foo2", 123},
+					{999, "foo3", 999},
+					{nil, "a b", nil},
+				},
+			},
+		},
+		{
+			name:  "group by only a part of wildcard",
+			query: "select * from foo group by 1",
+			err:   `ERROR:  column "foo.name" must appear in the GROUP BY clause or be used in an aggregate function`,
+		},
+		{
+			name:  "group by position out of range of wildcard",
+			query: "select * from foo group by 3",
+			err:   "ERROR:  GROUP BY position 3 is not in select list",
+		},
+		{
+			name:  "group by alias",
+			query: "select hoge_id as k, count(*) from foo group by k order by k",
+			expected: &trans.QueryResult{
+				Columns: []string{"k", "count"},
+				Records: core.ValuesList{
+					{123, 2},
+					{999, 2},
+					{nil, 1},
+				},
+			},
+		},
+		{
+			name:  "group by alias of expression",
+			query: "select hoge_id / 100 as k, count(*) from foo group by k order by k",
+			expected: &trans.QueryResult{
+				Columns: []string{"k", "count"},
+				Records: core.ValuesList{
+					{1, 2},
+					{9, 2},
+					{nil, 1},
+				},
+			},
+		},
+		{
+			name:  "group by column rather than alias of the same name",
+			query: "select upper(name) as name, count(*) from foo group by name order by name",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "count"},
+				Records: core.ValuesList{
+					{"A B", 1},
+					{"FOO1", 1},
+					{"FOO2", 1},
+					{"FOO3", 2},
+				},
+			},
+		},
+		{
+			name:  "aggregate aliased as grouped column",
+			query: "select count(*) as hoge_id from foo group by hoge_id order by 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id"},
+				Records: core.ValuesList{
+					{1},
+					{2},
+					{2},
+				},
+			},
+		},
+		{
+			name:  "group by alias of aggregate",
+			query: "select count(*) as k from foo group by k",
+			err:   "ERROR:  aggregate functions are not allowed in GROUP BY",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table foo (hoge_id int, name varchar(255))",
+				"insert into foo values (123, 'foo1'), (123, 'foo2'), (999, 'foo3'), (999, 'foo3'), (null, 'a b')",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestGroupingSets(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+	}{
+		{
+			name:  "rollup",
+			query: "select region, product, sum(amount) from sales group by rollup (region, product)",
+			expected: &trans.QueryResult{
+				Columns: []string{"region", "product", "sum"},
+				Records: core.ValuesList{
+					{"east", "apple", 15},
+					{"east", "banana", 20},
+					{"west", "apple", 30},
+					{"east", nil, 35},
+					{"west", nil, 30},
+					{nil, nil, 65},
+				},
+			},
+		},
+		{
+			name:  "cube and grouping",
+			query: "select region, product, sum(amount), grouping(region, product) from sales group by cube (region, product)",
+			expected: &trans.QueryResult{
+				Columns: []string{"region", "product", "sum", "grouping"},
+				Records: core.ValuesList{
+					{"east", "apple", 15, 0},
+					{"east", "banana", 20, 0},
+					{"west", "apple", 30, 0},
+					{"east", nil, 35, 1},
+					{"west", nil, 30, 1},
+					{nil, "apple", 45, 2},
+					{nil, "banana", 20, 2},
+					{nil, nil, 65, 3},
+				},
+			},
+		},
+		{
+			name:  "grouping sets with empty set",
+			query: "select region, product, count(*) from sales group by grouping sets ((region), (product), ())",
+			expected: &trans.QueryResult{
+				Columns: []string{"region", "product", "count"},
+				Records: core.ValuesList{
+					{"east", nil, 3},
+					{"west", nil, 1},
+					{nil, "apple", 3},
+					{nil, "banana", 1},
+					{nil, nil, 4},
+				},
+			},
+		},
+		{
+			name:  "plain key and rollup",
+			query: "select region, product, count(*) from sales group by region, rollup (product)",
+			expected: &trans.QueryResult{
+				Columns: []string{"region", "product", "count"},
+				Records: core.ValuesList{
+					{"east", "apple", 2},
+					{"east", "banana", 1},
+					{"west", "apple", 1},
+					{"east", nil, 3},
+					{"west", nil, 1},
+				},
+			},
+		},
+		{
+			name:  "grand total of no rows",
+			query: "select region, count(*) from sales where amount > 100 group by rollup (region)",
+			expected: &trans.QueryResult{
+				Columns: []string{"region", "count"},
+				Records: core.ValuesList{
+					{nil, 0},
+				},
+			},
+		},
+		{
+			name:  "grouping in having",
+			query: "select sales.region, grouping(region) from sales group by rollup (sales.region) having grouping(region) = 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"region", "grouping"},
+				Records: core.ValuesList{
+					{nil, 1},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table sales (region varchar(255), product varchar(255), amount int)",
+				"insert into sales values ('east', 'apple', 10), ('east', 'banana', 20), ('west', 'apple', 30), ('east', 'apple', 5)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestDistinctQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+	}{
+		{
+			name:  "distinct",
+			query: "select distinct name from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"foo1"},
+					{"foo2"},
+					{"foo3"},
+					{"a b"},
+					{"c"},
+				},
+			},
+		},
+		{
+			name:  "distinct with order by",
+			query: "select distinct hoge_id from foo order by hoge_id",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id"},
+				Records: core.ValuesList{
+					{123},
+					{999},
+					{nil},
+				},
+			},
+		},
+		{
+			name:  "distinct wildcard",
+			query: "select distinct * from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{
+					{123, "foo1"},
+					{123, "foo2"},
+					{999, "foo3"},
+					{nil, "a b"},
+					{nil, "c"},
+				},
+			},
+		},
+		{
+			name:  "distinct on keeps the first row in order",
+			query: "select distinct on (hoge_id) hoge_id, name from foo where hoge_id is not null order by hoge_id desc",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{
+					{999, "foo3"},
+					{123, "foo1"},
+				},
+			},
+		},
+		{
+			name:  "distinct on column not in select list",
+			query: "select distinct on (hoge_id) name from foo",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"foo1"},
+					{"foo3"},
+					{"a b"},
+				},
+			},
+		},
+		{
+			name:  "distinct after grouping",
+			query: "select distinct hoge_id, count(*) from foo group by hoge_id, name",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "count"},
+				Records: core.ValuesList{
+					{123, 1},
+					{999, 2},
+					{nil, 1},
+				},
+			},
+		},
+		{
+			name:  "distinct before limit",
+			query: "select distinct hoge_id from foo limit 2",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id"},
+				Records: core.ValuesList{
+					{123},
+					{999},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table foo (hoge_id int, name varchar(255))",
+				"insert into foo values (123, 'foo1'), (123, 'foo2'), (999, 'foo3'), (999, 'foo3'), (null, 'a b'), (null, 'c')",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestOrderByQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "varchar column",
+			query: "select name from foo order by name",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"a b"},
+					{"foo1"},
+					{"foo2"},
+					{"foo3"},
+					{nil},
+				},
+			},
+		},
+		{
+			name:  "nulls come first in descending order",
+			query: "select name from foo order by name desc",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{nil},
+					{"foo3"},
+					{"foo2"},
+					{"foo1"},
+					{"a b"},
+				},
+			},
+		},
+		{
+			name:  "multiple keys with nulls first",
+			query: "select hoge_id, name from foo order by hoge_id nulls first, name desc",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{
+					{nil, "a b"},
+					{1, nil},
+					{123, "foo2"},
+					{123, "foo1"},
+					{999, "foo3"},
+				},
+			},
+		},
+		{
+			name:  "float column with nulls last",
+			query: "select name, score from foo order by score desc nulls last, hoge_id",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "score"},
+				Records: core.ValuesList{
+					{"a b", 2.5},
+					{nil, 1.5},
+					{"foo1", 1.5},
+					{"foo3", 0.5},
+					{"foo2", nil},
+				},
+			},
+		},
+		{
+			name:  "output alias",
+			query: "select name, hoge_id * 2 as x from foo where hoge_id is not null order by x desc",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "x"},
+				Records: core.ValuesList{
+					{"foo3", 1998},
+					{"foo1", 246},
+					{"foo2", 246},
+					{nil, 2},
+				},
+			},
+		},
+		{
+			name:  "output column is preferred to input column",
+			query: "select hoge_id as name from foo order by name",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{1},
+					{123},
+					{123},
+					{999},
+					{nil},
+				},
+			},
+		},
+		{
+			name:  "ordinal positions",
+			query: "select name, hoge_id from foo order by 2 desc, 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "hoge_id"},
+				Records: core.ValuesList{
+					{"a b", nil},
+					{"foo3", 999},
+					{"foo1", 123},
+					{"foo2", 123},
+					{nil, 1},
+				},
+			},
+		},
+		{
+			name:  "expression not in select list",
+			query: "select name from foo order by 0 - hoge_id nulls first",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"a b"},
+					{"foo3"},
+					{"foo1"},
+					{"foo2"},
+					{nil},
+				},
+			},
+		},
+		{
+			name:  "aggregate",
+			query: "select hoge_id, count(*) from foo group by hoge_id order by count(*) desc, hoge_id",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "count"},
+				Records: core.ValuesList{
+					{123, 2},
+					{1, 1},
+					{999, 1},
+					{nil, 1},
+				},
+			},
+		},
+		{
+			name:  "ambiguous output column",
+			query: "select hoge_id as a, name as a from foo order by a",
+			err:   `ERROR:  ORDER BY "a" is ambiguous`,
+		},
+		{
+			name:  "position out of range",
+			query: "select name from foo order by 3",
+			err:   "ERROR:  ORDER BY position 3 is not in select list",
+		},
+		{
+			name:  "position of wildcard",
+			query: "select * from foo where name is not null order by 1 desc, 2",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name", "score"},
+				Records: core.ValuesList{
+					{nil, "a b", 2.5},
+					{999, "foo3", 0.5},
+					{123, "foo1", 1.5},
+					{123, "foo2", nil},
+				},
+			},
+		},
+		{
+			name:  "position after wildcard",
+			query: "select name, *, score * 2 as double from foo where hoge_id = 123 or hoge_id = 1 order by 5 desc, 3",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "hoge_id", "name", "score", "double"},
+				Records: core.ValuesList{
+					{"foo2", 123, "foo2", nil, nil},
+					{"foo1", 123, "foo1", 1.5, 3.0},
+					{nil, 1, nil, 1.5, 3.0},
+				},
+			},
+		},
+		{
+			name:  "position of wildcard with aggregate",
+			query: "select *, count(*) from foo group by hoge_id, name, score order by 4 desc, 1 limit 2",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name", "score", "count"},
+				Records: core.ValuesList{
+					{1, nil, 1.5, 1},
+					{123, "foo1", 1.5, 1},
+				},
+			},
+		},
+		{
+			name:  "position out of range of wildcard",
+			query: "select * from foo order by 4",
+			err:   "ERROR:  ORDER BY position 4 is not in select list",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table foo (hoge_id int, name varchar(255), score float)",
+				"insert into foo values (123, 'foo1', 1.5), (123, 'foo2', null), (999, 'foo3', 0.5), (null, 'a b', 2.5), (1, null, 1.5)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestLimitOffsetQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "limit and offset",
+			query: "select * from foo order by hoge_id limit 2 offset 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{
+					{2, "b"},
+					{2, "c"},
+				},
+			},
+		},
+		{
+			name:  "limit all",
+			query: "select * from foo order by hoge_id limit all offset 3",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{
+					{3, "d"},
+					{4, "e"},
+				},
+			},
+		},
+		{
+			name:  "offset beyond rows",
+			query: "select * from foo offset 10",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{},
+			},
+		},
+		{
+			name:  "fetch first",
+			query: "select * from foo order by hoge_id offset 1 rows fetch next row only",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{
+					{2, "b"},
+				},
+			},
+		},
+		{
+			name:  "fetch first with ties",
+			query: "select * from foo order by hoge_id fetch first 2 rows with ties",
+			expected: &trans.QueryResult{
+				Columns: []string{"hoge_id", "name"},
+				Records: core.ValuesList{
+					{1, "a"},
+					{2, "b"},
+					{2, "c"},
+				},
+			},
+		},
+		{
+			name:  "expression",
+			query: "select name from foo order by hoge_id desc limit 1 + 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"e"},
+					{"d"},
+				},
+			},
+		},
+		{
+			name:  "limit without from clause",
+			query: "select 1 limit 0",
+			expected: &trans.QueryResult{
+				Columns: []string{""},
+				Records: core.ValuesList{},
+			},
+		},
+		{
+			name:  "negative limit",
+			query: "select * from foo limit -1",
+			err:   "ERROR:  LIMIT must not be negative",
+		},
+		{
+			name:  "offset of varchar",
+			query: "select * from foo offset 'a'",
+			err:   "ERROR:  argument of OFFSET must be type bigint, not type character varying",
+		},
+		{
+			name:  "limit with a column",
+			query: "select * from foo limit hoge_id",
+			err:   "ERROR:  argument of LIMIT must not contain variables",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table foo (hoge_id int, name varchar(255))",
+				"insert into foo values (1, 'a'), (2, 'b'), (2, 'c'), (3, 'd'), (4, 'e')",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestSetOperationQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "union",
+			query: "select * from a union select * from b",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{1, "x"},
+					{2, "y"},
+					{3, "z"},
+					{3, "w"},
+					{4, "v"},
+				},
+			},
+		},
+		{
+			name:  "union all with order by and limit",
+			query: "select * from a union all select * from b order by id desc, name limit 4",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{4, "v"},
+					{3, "w"},
+					{3, "z"},
+					{2, "y"},
+				},
+			},
+		},
+		{
+			name:  "intersect",
+			query: "select * from a intersect select * from b",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{2, "y"},
+				},
+			},
+		},
+		{
+			name:  "intersect all",
+			query: "select * from a intersect all select * from b",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{2, "y"},
+					{2, "y"},
+				},
+			},
+		},
+		{
+			name:  "except",
+			query: "select id from a except select id from b where id = 3",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+					{2},
+				},
+			},
+		},
+		{
+			name:  "except all",
+			query: "select id from a except all (select id from b where name = 'y' limit 1)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+					{2},
+					{3},
+				},
+			},
+		},
+		{
+			name:  "integer and float",
+			query: "select id from a union select 2.5 order by 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1.0},
+					{2.0},
+					{2.5},
+					{3.0},
+				},
+			},
+		},
+		{
+			name:  "intersect binds tighter than union",
+			query: "select 1 union select 2 intersect select 3",
+			expected: &trans.QueryResult{
+				Columns: []string{""},
+				Records: core.ValuesList{
+					{1},
+				},
+			},
+		},
+		{
+			name:  "order by and limit of each query",
+			query: "(select id from a order by id desc limit 1) union all (select id from b limit 1)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{3},
+					{2},
+				},
+			},
+		},
+		{
+			name:  "set operation in from clause",
+			query: "select * from (select id from a union select id from b) s where id > 2",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{3},
+					{4},
+				},
+			},
+		},
+		{
+			name:  "different number of columns",
+			query: "select id from a union select id, name from b",
+			err:   "ERROR:  each UNION query must have the same number of columns",
+		},
+		{
+			name:  "incompatible types",
+			query: "select id from a intersect select name from b",
+			err:   "ERROR:  INTERSECT types integer and character varying cannot be matched",
+		},
+		{
+			name:  "order by expression",
+			query: "select id from a union select id from b order by id + 1",
+			err:   "ERROR:  invalid UNION/INTERSECT/EXCEPT ORDER BY clause",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table a (id int, name varchar(255))",
+				"create table b (id int, name varchar(255))",
+				"insert into a values (1, 'x'), (2, 'y'), (2, 'y'), (3, 'z')",
+				"insert into b values (2, 'y'), (3, 'w'), (4, 'v'), (2, 'y'), (2, 'y')",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestSubqueryQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "in",
+			query: "select id from a where id in (select aid from b)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+					{2},
+				},
+			},
+		},
+		{
+			name:  "not in with null",
+			query: "select id from a where id not in (select aid from b)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{},
+			},
+		},
+		{
+			name:  "not in without null",
+			query: "select id from a where id not in (select aid from b where aid is not null)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{3},
+				},
+			},
+		},
+		{
+			name:  "correlated exists",
+			query: "select id from a where exists (select 1 from b where b.aid = a.id and b.id > 10)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+					{2},
+				},
+			},
+		},
+		{
+			name:  "correlated not exists",
+			query: "select id, name from a where not exists (select 1 from b where b.aid = a.id)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name"},
+				Records: core.ValuesList{
+					{3, "z"},
+					{nil, "n"},
+				},
+			},
+		},
+		{
+			name:  "correlated in",
+			query: "select id from a where id in (select aid from b where b.id = a.id + 9)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+				},
+			},
+		},
+		{
+			name:  "scalar subquery in select list",
+			query: "select id, (select count(*) from b where b.aid = a.id), (select max(id) from b) as m from a",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "count", "m"},
+				Records: core.ValuesList{
+					{1, 2, 13},
+					{2, 1, 13},
+					{3, 0, 13},
+					{nil, 0, 13},
+				},
+			},
+		},
+		{
+			name:  "scalar subquery without rows",
+			query: "select (select name from a where id = b.aid), exists (select 1 from a where id = b.aid) from b",
+			expected: &trans.QueryResult{
+				Columns: []string{"name", "exists"},
+				Records: core.ValuesList{
+					{"x", true},
+					{"x", true},
+					{"y", true},
+					{nil, false},
+				},
+			},
+		},
+		{
+			name:  "nested correlated subquery",
+			query: "select id from a where exists (select 1 from b where b.aid = a.id and exists (select 1 from a a2 where a2.id = b.aid and a2.name = a.name and b.id = 12))",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{2},
+				},
+			},
+		},
+		{
+			name:  "any and all",
+			query: "select id from a where id > all (select aid from b where aid is not null) or id < any (select aid from b)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+					{3},
+				},
+			},
+		},
+		{
+			name:  "subquery in having",
+			query: "select aid, count(*) from b group by aid having count(*) > (select count(*) from a where id = 2)",
+			expected: &trans.QueryResult{
+				Columns: []string{"aid", "count"},
+				Records: core.ValuesList{
+					{1, 2},
+				},
+			},
+		},
+		{
+			name:  "more than one row",
+			query: "select id from a where id = (select aid from b)",
+			err:   "ERROR:  more than one row returned by a subquery used as an expression",
+		},
+		{
+			name:  "scalar subquery with two columns",
+			query: "select (select id, aid from b where id = 10)",
+			err:   "ERROR:  subquery must return only one column",
+		},
+		{
+			name:  "in with two columns",
+			query: "select id from a where id in (select id, aid from b)",
+			err:   "ERROR:  subquery has too many columns",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table a (id int, name varchar(255))",
+				"create table b (id int, aid int)",
+				"insert into a values (1, 'x'), (2, 'y'), (3, 'z'), (null, 'n')",
+				"insert into b values (10, 1), (11, 1), (12, 2), (13, null)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestWithQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+		after    string
+		rest     trans.Result
+	}{
+		{
+			name:  "multiple ctes",
+			query: "with x as (select id from emp where id < 3), y(v) as (select id * 10 from x) select * from y order by v desc",
+			expected: &trans.QueryResult{
+				Columns: []string{"v"},
+				Records: core.ValuesList{
+					{20},
+					{10},
+				},
+			},
+		},
+		{
+			name:  "materialized hints",
+			query: "with x as materialized (select id from emp where id = 1), y as not materialized (select id from emp where id = 2) select x.id, y.id from x, y",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "id"},
+				Records: core.ValuesList{
+					{1, 2},
+				},
+			},
+		},
+		{
+			name:  "cte shadows table",
+			query: "with emp as (select 100 as id) select * from emp",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{100},
+				},
+			},
+		},
+		{
+			name:  "unreferenced cte is not evaluated",
+			query: "with x as (select 1 / 0) select 1 as one",
+			expected: &trans.QueryResult{
+				Columns: []string{"one"},
+				Records: core.ValuesList{
+					{1},
+				},
+			},
+		},
+		{
+			name:  "recursive",
+			query: "with recursive t(n) as (select 1 union all select n + 1 from t where n < 5) select sum(n) from t",
+			expected: &trans.QueryResult{
+				Columns: []string{"sum"},
+				Records: core.ValuesList{
+					{15},
+				},
+			},
+		},
+		{
+			name:  "hierarchy",
+			query: "with recursive sub(id, name, depth) as (select id, name, 0 from emp where id = 2 union all select emp.id, emp.name, sub.depth + 1 from emp, sub where emp.boss = sub.id) select * from sub",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "depth"},
+				Records: core.ValuesList{
+					{2, "b", 0},
+					{4, "d", 1},
+					{5, "e", 2},
+				},
+			},
+		},
+		{
+			name:  "union stops at cycle",
+			query: "with recursive r(src, dst) as (select src, dst from edge where src = 1 union select e.src, e.dst from edge e join r on e.src = r.dst) select * from r",
+			expected: &trans.QueryResult{
+				Columns: []string{"src", "dst"},
+				Records: core.ValuesList{
+					{1, 2},
+					{2, 3},
+					{3, 1},
+				},
+			},
+		},
+		{
+			name:  "union all over cycle",
+			query: "with recursive r(src, dst) as (select src, dst from edge where src = 1 union all select e.src, e.dst from edge e join r on e.src = r.dst) select * from r",
+			err:   `ERROR:  recursive query "r" exceeded 10000 iterations`,
+		},
+		{
+			name:  "delete returning",
+			query: "with d as (delete from emp where boss = 1 returning id) select count(*) from d",
+			expected: &trans.QueryResult{
+				Columns: []string{"count"},
+				Records: core.ValuesList{
+					{2},
+				},
+			},
+			after: "select id from emp",
+			rest: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+					{4},
+					{5},
+				},
+			},
+		},
+		{
+			name:  "query sees table before modification",
+			query: "with d as (delete from emp where id > 1 returning id) select count(*) from emp",
+			expected: &trans.QueryResult{
+				Columns: []string{"count"},
+				Records: core.ValuesList{
+					{5},
+				},
+			},
+		},
+		{
+			name:  "delete with cte",
+			query: "with x as (select id from emp where boss = 2) delete from emp where id in (select id from x) returning name",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"d"},
+				},
+			},
+		},
+		{
+			name:  "too many column names",
+			query: "with x(a, b) as (select id from emp) select * from x",
+			err:   `ERROR:  WITH query "x" has 1 columns available but 2 columns specified`,
+		},
+		{
+			name:  "duplicated name",
+			query: "with x as (select 1), x as (select 2) select 1",
+			err:   `ERROR:  WITH query name "x" specified more than once`,
+		},
+		{
+			name:  "not of the form union",
+			query: "with recursive t(n) as (select n from t) select * from t",
+			err:   `ERROR:  recursive query "t" does not have the form non-recursive-term UNION [ALL] recursive-term`,
+		},
+		{
+			name:  "type of recursive term",
+			query: "with recursive t(n) as (select 1 union all select n + 0.5 from t where n < 3) select * from t",
+			err:   `ERROR:  recursive query "t" column 1 has type integer in non-recursive term but type double precision overall`,
+		},
+		{
+			name:  "aggregate in recursive term",
+			query: "with recursive t(n) as (select 1 union all select count(*) from t) select * from t",
+			err:   "ERROR:  aggregate functions are not allowed in a recursive query's recursive term",
+		},
+		{
+			name:  "window function in recursive term",
+			query: "with recursive t(n) as (select 1 union all select row_number() over () from t) select * from t",
+			err:   "ERROR:  window functions are not allowed in a recursive query's recursive term",
+		},
+		{
+			name:  "order by in recursive term",
+			query: "with recursive t(n) as (select 1 union all (select n + 1 from t order by n)) select * from t",
+			err:   "ERROR:  ORDER BY in a recursive query is not implemented",
+		},
+		{
+			name:  "limit in recursive term",
+			query: "with recursive t(n) as (select 1 union all (select n + 1 from t limit 1)) select * from t",
+			err:   "ERROR:  LIMIT in a recursive query is not implemented",
+		},
+		{
+			name:  "offset in recursive term",
+			query: "with recursive t(n) as (select 1 union all (select n + 1 from t offset 1)) select * from t",
+			err:   "ERROR:  OFFSET in a recursive query is not implemented",
+		},
+		{
+			name:  "data-modifying statement in subquery",
+			query: "select * from emp where id in (with d as (delete from emp returning id) select id from d)",
+			err:   "ERROR:  WITH clause containing a data-modifying statement must be at the top level",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table emp (id int, name varchar(255), boss int)",
+				"insert into emp values (1, 'a', null), (2, 'b', 1), (3, 'c', 1), (4, 'd', 2), (5, 'e', 4)",
+				"create table edge (src int, dst int)",
+				"insert into edge values (1, 2), (2, 3), (3, 1)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+
+			if tt.after != "" {
+				raNode, _ := trans.NewPGTranslator(tt.after).Translate()
+				actual, err := raNode.Eval(db)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.rest, actual)
+			}
+		})
+	}
+}
+
+func TestWindowFunctionQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "row_number by partition",
+			query: "select id, row_number() over (partition by grp order by v) from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "row_number"},
+				Records: core.ValuesList{
+					{1, 1},
+					{2, 2},
+					{3, 1},
+					{5, 2},
+					{4, 3},
+					{6, 1},
+				},
+			},
+		},
+		{
+			name:  "ranking",
+			query: "select id, rank() over w, dense_rank() over w, percent_rank() over w, cume_dist() over w from s window w as (order by v) order by id",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "rank", "dense_rank", "percent_rank", "cume_dist"},
+				Records: core.ValuesList{
+					{1, 2, 2, 0.2, 2.0 / 6},
+					{2, 3, 3, 0.4, 4.0 / 6},
+					{3, 3, 3, 0.4, 4.0 / 6},
+					{4, 6, 5, 1.0, 1.0},
+					{5, 5, 4, 0.8, 5.0 / 6},
+					{6, 1, 1, 0.0, 1.0 / 6},
+				},
+			},
+		},
+		{
+			name:  "ntile, lag and lead",
+			query: "select id, ntile(4) over (order by id), lag(v) over (order by id), lead(v, 2, -1) over (order by id) from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "ntile", "lag", "lead"},
+				Records: core.ValuesList{
+					{1, 1, nil, 20},
+					{2, 1, 10, nil},
+					{3, 2, 20, 30},
+					{4, 2, 20, 5},
+					{5, 3, nil, -1},
+					{6, 4, 30, -1},
+				},
+			},
+		},
+		{
+			name:  "values of rows frame",
+			query: "select id, first_value(v) over w, last_value(v) over w, nth_value(v, 2) over w from s window w as (order by id rows between 1 preceding and 1 following)",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "first_value", "last_value", "nth_value"},
+				Records: core.ValuesList{
+					{1, 10, 20, 20},
+					{2, 10, 20, 20},
+					{3, 20, nil, 20},
+					{4, 20, 30, nil},
+					{5, nil, 5, 30},
+					{6, 30, 5, 5},
+				},
+			},
+		},
+		{
+			name:  "aggregates",
+			query: "select id, sum(v) over (order by id), count(*) over (partition by grp), sum(v) filter (where v > 10) over (partition by grp) from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "sum", "count", "sum"},
+				Records: core.ValuesList{
+					{1, 10, 2, 20},
+					{2, 30, 2, 20},
+					{3, 50, 3, 50},
+					{4, 50, 3, 50},
+					{5, 80, 3, 50},
+					{6, 85, 1, nil},
+				},
+			},
+		},
+		{
+			name:  "range frame with offset",
+			query: "select id, sum(v) over (order by v desc range between 10 preceding and 5 following) from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "sum"},
+				Records: core.ValuesList{
+					{4, nil},
+					{5, 30},
+					{2, 70},
+					{3, 70},
+					{1, 55},
+					{6, 15},
+				},
+			},
+		},
+		{
+			name:  "groups frame with exclusion",
+			query: "select id, count(*) over (order by v groups between 1 preceding and 1 following exclude current row) from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "count"},
+				Records: core.ValuesList{
+					{6, 1},
+					{1, 3},
+					{2, 3},
+					{3, 3},
+					{5, 3},
+					{4, 1},
+				},
+			},
+		},
+		{
+			name:  "window over groups",
+			query: "select grp, sum(v), rank() over (order by sum(v) desc) from s group by grp",
+			expected: &trans.QueryResult{
+				Columns: []string{"grp", "sum", "rank"},
+				Records: core.ValuesList{
+					{"b", 50, 1},
+					{"a", 30, 2},
+					{"c", 5, 3},
+				},
+			},
+		},
+		{
+			name:  "window based on named window",
+			query: "select id, row_number() over (w order by id desc) from s window w as (partition by grp) order by id",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "row_number"},
+				Records: core.ValuesList{
+					{1, 2},
+					{2, 1},
+					{3, 3},
+					{4, 2},
+					{5, 1},
+					{6, 1},
+				},
+			},
+		},
+		{
+			name:  "wildcard",
+			query: "select *, count(*) over () from s where id < 3",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "grp", "v", "count"},
+				Records: core.ValuesList{
+					{1, "a", 10, 2},
+					{2, "a", 20, 2},
+				},
+			},
+		},
+		{
+			name:  "in where",
+			query: "select id from s where row_number() over () > 1",
+			err:   "ERROR:  window functions are not allowed in WHERE",
+		},
+		{
+			name:  "without over",
+			query: "select row_number() from s",
+			err:   "ERROR:  window function row_number requires an OVER clause",
+		},
+		{
+			name:  "in aggregate",
+			query: "select sum(row_number() over ()) from s",
+			err:   "ERROR:  aggregate function calls cannot contain window function calls",
+		},
+		{
+			name:  "undefined window",
+			query: "select row_number() over w from s",
+			err:   `ERROR:  window "w" does not exist`,
+		},
+		{
+			name:  "override partition",
+			query: "select row_number() over (w partition by id) from s window w as (partition by grp)",
+			err:   `ERROR:  cannot override PARTITION BY clause of window "w"`,
+		},
+		{
+			name:  "range offset without order by",
+			query: "select count(*) over (range 1 preceding) from s",
+			err:   "ERROR:  RANGE with offset PRECEDING/FOLLOWING requires exactly one ORDER BY column",
+		},
+		{
+			name:  "negative offset",
+			query: "select count(*) over (rows -1 preceding) from s",
+			err:   "ERROR:  frame starting offset must not be negative",
+		},
+		{
+			name:  "ntile of zero",
+			query: "select ntile(0) over () from s",
+			err:   "ERROR:  argument of ntile must be greater than zero",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table s (id int, grp varchar(255), v int)",
+				"insert into s values (1, 'a', 10), (2, 'a', 20), (3, 'b', 20), (4, 'b', null), (5, 'b', 30), (6, 'c', 5)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestFunctionQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "string functions",
+			query: "select lower(name), upper(name), length(name), replace(name, 'b', 'B') from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"lower", "upper", "length", "replace"},
+				Records: core.ValuesList{
+					{"alice", "ALICE", 5, "Alice"},
+					{"bob", "BOB", 3, "BoB"},
+					{nil, nil, nil, nil},
+				},
+			},
+		},
+		{
+			name:  "substring, position and trim",
+			query: "select substring('hello' from 2 for 3), substring('hello', 3), position('l' in 'hello'), trim('  a  '), trim(leading 'x' from 'xxaxx')",
+			expected: &trans.QueryResult{
+				Columns: []string{"substring", "substring", "position", "btrim", "ltrim"},
+				Records: core.ValuesList{
+					{"ell", "llo", 3, "a", "axx"},
+				},
+			},
+		},
+		{
+			name:  "split_part, concat and format",
+			query: "select split_part('a,b,c', ',', 2), concat('a', 1, null, true), format('%s=%L, %1$I', 'My Col', null)",
+			expected: &trans.QueryResult{
+				Columns: []string{"split_part", "concat", "format"},
+				Records: core.ValuesList{
+					{"b", "a1t", `My Col=NULL, "My Col"`},
+				},
+			},
+		},
+		{
+			name:  "math functions",
+			query: "select abs(-3), round(2.567, 2), ceil(1.2), floor(-1.2), mod(-7, 3), power(2, 10), sqrt(16)",
+			expected: &trans.QueryResult{
+				Columns: []string{"abs", "round", "ceil", "floor", "mod", "power", "sqrt"},
+				Records: core.ValuesList{
+					{3, 2.57, 2.0, -2.0, -1, 1024.0, 4.0},
+				},
+			},
+		},
+		{
+			name:  "conditional functions",
+			query: "select coalesce(name, 'none'), nullif(id, 2), greatest(id, 2, null), least(v, 0) from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"coalesce", "nullif", "greatest", "least"},
+				Records: core.ValuesList{
+					{"Alice", 1, 2, 0},
+					{"bob", nil, 2, -2.25},
+					{"none", 3, 3, 0},
+				},
+			},
+		},
+		{
+			name:  "coalesce doesn't evaluate arguments after a non-null value",
+			query: "select coalesce(1, 1/0), coalesce(1, mod(1, 0)), coalesce(1, (select 1 union all select 2)), coalesce(null, id, 1/0) from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"coalesce", "coalesce", "coalesce", "coalesce"},
+				Records: core.ValuesList{
+					{1, 1, 1, 1},
+					{1, 1, 1, 2},
+					{1, 1, 1, 3},
+				},
+			},
+		},
+		{
+			name:  "nullif with subquery",
+			query: "select nullif(id, (select 2)) from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"nullif"},
+				Records: core.ValuesList{
+					{1},
+					{nil},
+					{3},
+				},
+			},
+		},
+		{
+			name:  "coalesce evaluates arguments until a non-null value",
+			query: "select coalesce(null, mod(1, 0), 1)",
+			err:   "ERROR:  division by zero",
+		},
+		{
+			name:  "in where clause",
+			query: "select id from s where length(name) > 3 and random() < 1.0",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+				},
+			},
+		},
+		{
+			name:  "argument types",
+			query: "select lower(id) from s",
+			err:   "ERROR:  function lower(integer) does not exist",
+		},
+		{
+			name:  "undefined function",
+			query: "select foo(1, 'a')",
+			err:   "ERROR:  function foo(integer, character varying) does not exist",
+		},
+		{
+			name:  "too few arguments for format",
+			query: "select format('%s %s', 1)",
+			err:   "ERROR:  too few arguments for format()",
+		},
+		{
+			name:  "division by zero",
+			query: "select mod(1, 0)",
+			err:   "ERROR:  division by zero",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table s (id int, name varchar(255), v float)",
+				"insert into s values (1, 'Alice', 1.5), (2, 'bob', -2.25), (3, null, null)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestPatternMatchQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "like and ilike",
+			query: "select name from s where name like 'a%' or name ilike '_OB'",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"apple"},
+					{"Bob"},
+				},
+			},
+		},
+		{
+			name:  "not like and null",
+			query: "select id, name not like '%e' from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", ""},
+				Records: core.ValuesList{
+					{1, false},
+					{2, true},
+					{3, true},
+					{4, nil},
+				},
+			},
+		},
+		{
+			name:  "like with escape",
+			query: "select '10%' like '10!%' escape '!', '100' like '10!%' escape '!', 'a_b' like 'a\\_b', 'a%b' not ilike 'A#%B' escape '#'",
+			expected: &trans.QueryResult{
+				Columns: []string{"", "", "", ""},
+				Records: core.ValuesList{
+					{true, false, true, false},
+				},
+			},
+		},
+		{
+			name:  "similar to",
+			query: "select name from s where name similar to '(a|B)%(e|b)'",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"apple"},
+					{"Bob"},
+				},
+			},
+		},
+		{
+			name:  "not similar to with escape",
+			query: "select 'a.c' similar to 'a.c', 'abc' similar to 'a.c', 'a%' not similar to 'a#%' escape '#'",
+			expected: &trans.QueryResult{
+				Columns: []string{"", "", ""},
+				Records: core.ValuesList{
+					{true, false, false},
+				},
+			},
+		},
+		{
+			name:  "regular expression operators",
+			query: "select name ~ '^[a-c]', name ~* '^B', name !~ 'p+', name !~* 'E$' from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"", "", "", ""},
+				Records: core.ValuesList{
+					{true, false, false, false},
+					{false, true, true, true},
+					{true, false, true, true},
+					{nil, nil, nil, nil},
+				},
+			},
+		},
+		{
+			name:  "regexp_replace",
+			query: "select regexp_replace('foobarbaz', 'b(..)', 'X\\1Y'), regexp_replace('foobarbaz', 'B(..)', '<\\&>', 'gi')",
+			expected: &trans.QueryResult{
+				Columns: []string{"regexp_replace", "regexp_replace"},
+				Records: core.ValuesList{
+					{"fooXarYbaz", "foo<bar><baz>"},
+				},
+			},
+		},
+		{
+			name:  "regexp_matches",
+			query: "select * from regexp_matches('foo1 bar22 baz', '([a-z]+)(\\d+)?', 'g')",
+			expected: &trans.QueryResult{
+				Columns: []string{"regexp_matches"},
+				Records: core.ValuesList{
+					{"{foo,1}"},
+					{"{bar,22}"},
+					{"{baz,NULL}"},
+				},
+			},
+		},
+		{
+			name:  "regexp_split_to_table",
+			query: "select * from regexp_split_to_table('a, b ,c', '\\s*,\\s*') with ordinality as t(part, n)",
+			expected: &trans.QueryResult{
+				Columns: []string{"part", "n"},
+				Records: core.ValuesList{
+					{"a", 1},
+					{"b", 2},
+					{"c", 3},
+				},
+			},
+		},
+		{
+			name:  "operator type",
+			query: "select id like '1' from s",
+			err:   "ERROR:  operator does not exist: integer ~~ character varying",
+		},
+		{
+			name:  "like pattern ending with escape",
+			query: "select 'a' like 'a\\'",
+			err:   "ERROR:  LIKE pattern must not end with escape character",
+		},
+		{
+			name:  "invalid regular expression",
+			query: "select 'a' ~ '('",
+			err:   "ERROR:  invalid regular expression: error parsing regexp: missing closing ): `(?s)(`",
+		},
+		{
+			name:  "regexp_matches in select list",
+			query: "select regexp_matches('a1b2', '\\d', 'g')",
+			expected: &trans.QueryResult{
+				Columns: []string{"regexp_matches"},
+				Records: core.ValuesList{
+					{"{1}"},
+					{"{2}"},
+				},
+			},
+		},
+		{
+			name:  "regexp_matches for each row",
+			query: "select id, regexp_matches(name, '([a-z])(r+)') from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "regexp_matches"},
+				Records: core.ValuesList{
+					{3, "{e,rr}"},
+				},
+			},
+		},
+		{
+			name:  "nested set-returning functions",
+			query: "select regexp_matches(regexp_split_to_table('a,b', ','), 'a')",
+			err:   "ERROR:  set-returning function calls cannot be nested",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table s (id int, name varchar(255))",
+				"insert into s values (1, 'apple'), (2, 'Bob'), (3, 'cherry'), (4, null)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestPredicateQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "between",
+			query: "select id from s where v between 2 and 3.5",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{2},
+					{3},
+				},
+			},
+		},
+		{
+			name:  "not between and between symmetric",
+			query: "select id, v not between 2 and 3, v between symmetric 3 and 1 from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "", ""},
+				Records: core.ValuesList{
+					{1, true, true},
+					{2, false, true},
+					{3, false, true},
+					{4, nil, nil},
+				},
+			},
+		},
+		{
+			name:  "between strings",
+			query: "select name from s where name not between symmetric 'c' and 'a'",
+			expected: &trans.QueryResult{
+				Columns: []string{"name"},
+				Records: core.ValuesList{
+					{"carol"},
+					{"dave"},
+				},
+			},
+		},
+		{
+			name:  "in list",
+			query: "select id, v in (1, 3, null), name not in ('alice', 'bob') from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "", ""},
+				Records: core.ValuesList{
+					{1, true, false},
+					{2, nil, false},
+					{3, true, true},
+					{4, nil, true},
+				},
+			},
+		},
+		{
+			name:  "in list in where clause",
+			query: "select id from s where id in (4, 2) and name not in ('bob')",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{4},
+				},
+			},
+		},
+		{
+			name:  "is distinct from",
+			query: "select a.id, b.id from s a, s b where a.v is not distinct from b.v and a.id < b.id or a.v is distinct from 1 and b.v is null",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "id"},
+				Records: core.ValuesList{
+					{2, 4},
+					{3, 4},
+					{4, 4},
+				},
+			},
+		},
+		{
+			name:  "boolean tests",
+			query: "select id, v > 1 is true, v > 1 is not true, v > 1 is false, v > 1 is not false, v > 1 is unknown, v > 1 is not unknown from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "", "", "", "", "", ""},
+				Records: core.ValuesList{
+					{1, false, true, true, false, false, true},
+					{2, true, false, false, true, false, true},
+					{3, true, false, false, true, false, true},
+					{4, false, true, false, true, true, false},
+				},
+			},
+		},
+		{
+			name:  "not",
+			query: "select id from s where not (v > 1) or not v is not null",
+			expected: &trans.QueryResult{
+				Columns: []string{"id"},
+				Records: core.ValuesList{
+					{1},
+					{4},
+				},
+			},
+		},
+		{
+			name:  "boolean test of non-boolean",
+			query: "select id is true from s",
+			err:   "ERROR:  argument of IS TRUE must be type boolean, not type integer",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table s (id int, name varchar(255), v int)",
+				"insert into s values (1, 'alice', 1), (2, 'bob', 2), (3, 'carol', 3), (4, 'dave', null)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCastQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name:  "casts of literals",
+			query: "select '12'::int + 1, cast('2.5' as float) * 2, 3::text || 'x', '  t '::boolean, 'off'::bool, 1::boolean, true::int, false::text",
+			expected: &trans.QueryResult{
+				Columns: []string{"", "", "", "bool", "bool", "bool", "int4", "text"},
+				Records: core.ValuesList{
+					{13, 5.0, "3x", true, false, true, 1, "false"},
+				},
+			},
+		},
+		{
+			name:  "casts of columns",
+			query: "select id::text, name::int, cast(v as integer), v::text, d::varchar from s",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "name", "v", "v", "d"},
+				Records: core.ValuesList{
+					{"1", 12, 2, "1.75", "2024-01-31"},
+					{"2", -7, -2, "-2.25", "2023-12-01"},
+					{"3", nil, nil, nil, nil},
+				},
+			},
+		},
+		{
+			name:  "typed literals and date arithmetic",
+			query: "select date '2024-02-29', (date '2024-02-29' + 1)::text, date '2024-03-01' - date '2024-02-01', int4 '42', '2024/1/5'::date > d from s where id = 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"date", "text", "", "int4", ""},
+				Records: core.ValuesList{
+					{core.DateType{Days: 19782}, "2024-03-01", 29, 42, false},
+				},
+			},
+		},
+		{
+			name:  "type modifiers",
+			query: "select 3.14159::numeric(5, 2), 'abcdef'::varchar(3), 'ab'::char(4), 1.1::real",
+			expected: &trans.QueryResult{
+				Columns: []string{"numeric", "varchar", "bpchar", "float4"},
+				Records: core.ValuesList{
+					{3.14, "abc", "ab  ", 1.100000023841858},
+				},
+			},
+		},
+		{
+			name:  "compare and sort dates",
+			query: "select id, d from s where d between date '2023-01-01' and '2024-12-31'::date or d is null order by d desc",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "d"},
+				Records: core.ValuesList{
+					{3, nil},
+					{1, core.DateType{Days: 19753}},
+					{2, core.DateType{Days: 19692}},
+				},
+			},
+		},
+		{
+			name:  "dates with integers and string literals",
+			query: "select 1 + d, d = '2024-01-31', '2024-01-01' < d, d <> '2024/1/31', d - 31 = '2023-12-31' from s where id = 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"", "", "", "", ""},
+				Records: core.ValuesList{
+					{core.DateType{Days: 19754}, true, true, false, true},
+				},
+			},
+		},
+		{
+			name:  "string literals take types of other operands",
+			query: "select 1 = '1', '2.5' > v, id <> '2', '1' = 1.0, 't' = true, id is distinct from '1' from s where id = 1",
+			expected: &trans.QueryResult{
+				Columns: []string{"", "", "", "", "", ""},
+				Records: core.ValuesList{
+					{true, true, true, true, true, false},
+				},
+			},
+		},
+		{
+			name:  "invalid integer",
+			query: "select 'abc'::int",
+			err:   `ERROR:  invalid input syntax for type integer: "abc"`,
+		},
+		{
+			name:  "invalid boolean",
+			query: "select 'yes please'::bool",
+			err:   `ERROR:  invalid input syntax for type boolean: "yes please"`,
+		},
+		{
+			name:  "invalid date",
+			query: "select date '2023-02-29'",
+			err:   `ERROR:  date/time field value out of range: "2023-02-29"`,
+		},
+		{
+			name:  "cannot cast",
+			query: "select v::date from s",
+			err:   "ERROR:  cannot cast type double precision to date",
+		},
+		{
+			name:  "integer out of range",
+			query: "select 70000::smallint",
+			err:   "ERROR:  smallint out of range",
+		},
+		{
+			name:  "numeric field overflow",
+			query: "select 123.4::numeric(3, 1)",
+			err:   "ERROR:  numeric field overflow",
+		},
+		{
+			name:  "undefined type",
+			query: "select 1::json",
+			err:   `ERROR:  type "json" does not exist`,
+		},
+		{
+			name:  "date operator",
+			query: "select d + d from s",
+			err:   "ERROR:  operator does not exist: date + date",
+		},
+		{
+			name:  "float plus date",
+			query: "select 1.5 + d from s",
+			err:   "ERROR:  operator does not exist: double precision + date",
+		},
+		{
+			name:  "invalid date literal",
+			query: "select * from s where d = 'abc'",
+			err:   `ERROR:  invalid input syntax for type date: "abc"`,
+		},
+		{
+			name:  "invalid integer literal",
+			query: "select * from s where id = 'abc'",
+			err:   `ERROR:  invalid input syntax for type integer: "abc"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range []string{
+				"create table s (id int, name varchar(255), v float, d date)",
+				"insert into s values (1, '12', 1.75, date '2024-01-31'), (2, ' -7 ', -2.25, '2023-12-01'::date), (3, null, null, null)",
+			} {
+				raNode, _ := trans.NewPGTranslator(query).Translate()
+				_, err := raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			var actual trans.Result
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			if err == nil {
+				actual, err = raNode.Eval(db)
+			}
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestAssignmentCast(t *testing.T) {
+	tests := []struct {
+		name     string
+		queries  []string
+		query    string
+		expected trans.Result
+		err      string
+	}{
+		{
+			name: "insert string literals into date and integer columns",
+			queries: []string{
+				"insert into dt (id, d) values ('7', '2024-05-01')",
+			},
+			query: "select id + 1, d + 1, d = date '2024-05-01', d < date '2024-06-01' from dt",
+			expected: &trans.QueryResult{
+				Columns: []string{"", "", "", ""},
+				Records: core.ValuesList{
+					{8, core.DateType{Days: 19845}, true, true},
+				},
+			},
+		},
+		{
+			name: "update with string literals",
+			queries: []string{
+				"insert into dt (id, d) values (1, '2024-05-01')",
+				"update dt set id = '2', d = '2024-06-01'",
+			},
+			query: "select id * 10, d - date '2024-05-01' from dt",
+			expected: &trans.QueryResult{
+				Columns: []string{"", ""},
+				Records: core.ValuesList{
+					{20, 31},
+				},
+			},
+		},
+		{
+			name: "assignment casts between types",
+			queries: []string{
+				"insert into dt (id, b, f, name) values (1.6, 'yes', '2.5', 3)",
+			},
+			query: "select id, b, f, name from dt",
+			expected: &trans.QueryResult{
+				Columns: []string{"id", "b", "f", "name"},
+				Records: core.ValuesList{
+					{2, true, 2.5, "3"},
+				},
+			},
+		},
+		{
+			name:  "invalid date",
+			query: "insert into dt (id, d) values (1, 'not a date')",
+			err:   `ERROR:  invalid input syntax for type date: "not a date"`,
+		},
+		{
+			name:  "invalid integer",
+			query: "insert into dt (id) values ('x')",
+			err:   `ERROR:  invalid input syntax for type integer: "x"`,
+		},
+		{
+			name: "update with invalid date",
+			queries: []string{
+				"insert into dt (id, d) values (1, '2024-05-01')",
+			},
+			query: "update dt set d = 'tomorrow'",
+			err:   `ERROR:  invalid input syntax for type date: "tomorrow"`,
+		},
+		{
+			name:  "type without assignment cast",
+			query: "insert into dt (d) values (1)",
+			err:   `ERROR:  column "d" is of type date but expression is of type integer`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			db := prepareDB()
+			for _, query := range append([]string{"create table dt (id int, d date, b bool, f float, name varchar(255))"}, tt.queries...) {
+				raNode, err := trans.NewPGTranslator(query).Translate()
+				assert.NoError(t, err)
+				_, err = raNode.Eval(db)
+				assert.NoError(t, err)
+			}
+
+			raNode, err := trans.NewPGTranslator(tt.query).Translate()
+			assert.NoError(t, err)
+			actual, err := raNode.Eval(db)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,1134 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+	pg_query "github.com/pganalyze/pg_query_go/v2"
+)
+
+// AggregateNode is a node of GROUP BY and aggregate functions.
+// The result table has a row for each group. Its columns are the grouping keys
+// followed by the results of the aggregate functions and GROUPING operations.
+type AggregateNode struct {
+	GroupKeys []ExpressionNode
+	// GroupingSets are sets of indexes of GroupKeys. Rows are grouped by each set
+	// and the keys which are not in the set are NULL in the result.
+	// If it's nil, rows are grouped by all keys.
+	GroupingSets [][]int
+	Aggregates   []*AggCallNode
+	// Groupings are indexes of GroupKeys which are arguments of each GROUPING operation
+	Groupings [][]int
+	// Outputs are expressions evaluated on the result such as the select list.
+	// They must refer only to grouping keys and aggregate functions.
+	Outputs []ExpressionNode
+	RANode  RelationalAlgebraNode
+}
+
+// Eval evaluates AggregateNode
+func (a *AggregateNode) Eval(db backend.DB) (backend.Table, error) {
+	tb, err := a.RANode.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if tb == nil {
+		// select without from clause aggregates a row which has no columns
+		tb = backend.NewTable("", core.Cols{}, core.ValuesList{{}})
+	}
+
+	srcNames := tb.GetColNames()
+	if a, err = a.resolveKeys(srcNames); err != nil {
+		return nil, err
+	}
+	keyNames := a.keyColNames(srcNames)
+	if err := a.checkGrouped(srcNames, keyNames); err != nil {
+		return nil, err
+	}
+
+	keyFns := make([]func(backend.Row) (core.Value, error), 0, len(a.GroupKeys))
+	for _, key := range a.GroupKeys {
+		keyFns = append(keyFns, scoped(db, key.Eval()))
+	}
+	inputs := make([]*aggInput, 0, len(a.Aggregates))
+	for _, agg := range a.Aggregates {
+		inputs = append(inputs, newAggInput(db, agg))
+	}
+
+	sets := a.groupingSets()
+	groups := make([]map[interface{}]*group, len(sets))
+	orders := make([][]*group, len(sets))
+	for k := range sets {
+		groups[k] = make(map[interface{}]*group)
+	}
+	for _, row := range tb.GetRows() {
+		keyVals := make(core.Values, 0, len(keyFns))
+		for _, fn := range keyFns {
+			v, err := fn(row)
+			if err != nil {
+				return nil, err
+			}
+			keyVals = append(keyVals, v)
+		}
+
+		for k, set := range sets {
+			// keys which are not in the grouping set are NULL
+			setVals := make(core.Values, len(keyVals))
+			for _, idx := range set {
+				setVals[idx] = keyVals[idx]
+			}
+
+			h := core.HashKey(setVals)
+			g, ok := groups[k][h]
+			if !ok {
+				g = a.newGroup(setVals, set)
+				groups[k][h] = g
+				orders[k] = append(orders[k], g)
+			}
+			if err := g.step(row, inputs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	valsList := make(core.ValuesList, 0)
+	for k, set := range sets {
+		if len(set) == 0 && len(orders[k]) == 0 {
+			// the empty grouping set such as aggregate functions without GROUP BY
+			// returns a row even if there is no input row
+			orders[k] = append(orders[k], a.newGroup(make(core.Values, len(keyFns)), set))
+		}
+		for _, g := range orders[k] {
+			vals, err := g.values(keyNames, a.Groupings)
+			if err != nil {
+				return nil, err
+			}
+			valsList = append(valsList, vals)
+		}
+	}
+
+	return backend.NewTable("", a.resultCols(tb.GetCols(), keyNames, valsList), valsList), nil
+}
+
+// resolveKeys returns AggregateNode whose grouping keys of positions after * and aliases are resolved with the source columns
+func (a *AggregateNode) resolveKeys(srcNames core.ColumnNames) (*AggregateNode, error) {
+	keys := make([]ExpressionNode, 0, len(a.GroupKeys))
+	for _, key := range a.GroupKeys {
+		if pos, ok := key.(*TargetPosNode); ok {
+			expr, err := pos.resolve(srcNames)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkGroupKey(expr); err != nil {
+				return nil, err
+			}
+			key = expr
+		}
+		if alias, ok := key.(*GroupAliasNode); ok {
+			key = alias.resolve(srcNames)
+			if err := checkGroupKey(key); err != nil {
+				return nil, err
+			}
+		}
+		keys = append(keys, key)
+	}
+	resolved := *a
+	resolved.GroupKeys = keys
+
+	// items grouped by an alias are checked as the key if it refers to them
+	outputs := make([]ExpressionNode, 0, len(a.Outputs))
+	for _, expr := range a.Outputs {
+		outputs = append(outputs, transformExpr(expr, func(e ExpressionNode) (ExpressionNode, bool) {
+			n, ok := e.(*GroupedAliasNode)
+			if !ok {
+				return nil, false
+			}
+			if alias := a.GroupKeys[n.Key].(*GroupAliasNode); haveColumn(alias.Name, srcNames) {
+				return n.Expr, true
+			}
+			return &ColRefNode{ColName: groupKeyColName(n.Key)}, true
+		}))
+	}
+	resolved.Outputs = outputs
+
+	return &resolved, nil
+}
+
+// groupingSets returns GroupingSets. It returns the set of all keys if GroupingSets is nil.
+func (a *AggregateNode) groupingSets() [][]int {
+	if a.GroupingSets != nil {
+		return a.GroupingSets
+	}
+
+	set := make([]int, 0, len(a.GroupKeys))
+	for k := range a.GroupKeys {
+		set = append(set, k)
+	}
+
+	return [][]int{set}
+}
+
+// keyColNames returns names of the result columns of grouping keys.
+// A key which refers to a column takes the name of the column so that it can be referred as before grouping.
+// The name is empty if the key refers to the same column as a preceding key.
+func (a *AggregateNode) keyColNames(srcNames core.ColumnNames) core.ColumnNames {
+	names := make(core.ColumnNames, 0, len(a.GroupKeys))
+	for k, key := range a.GroupKeys {
+		name := groupKeyColName(k)
+		if ref, ok := colRefName(key); ok {
+			for _, src := range srcNames {
+				if ref.Matches(src) {
+					name = src
+					break
+				}
+			}
+		}
+		if haveColumnExactly(name, names) {
+			name = core.ColumnName{}
+		}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func haveColumnExactly(c core.ColumnName, cs core.ColumnNames) bool {
+	for _, col := range cs {
+		if c.Equal(col) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkGrouped checks that outputs refer to columns of the source table only through grouping keys
+func (a *AggregateNode) checkGrouped(srcNames, keyNames core.ColumnNames) error {
+	for _, expr := range a.Outputs {
+		if _, ok := expr.(ColWildcardNode); ok {
+			for _, name := range srcNames {
+				if !haveColumnExactly(name, keyNames) {
+					return groupingError(name)
+				}
+			}
+			continue
+		}
+
+		refs, ok := columnRefs(expr)
+		if !ok {
+			continue
+		}
+		for _, ref := range refs {
+			if haveColumn(ref, keyNames) {
+				continue
+			}
+			for _, src := range srcNames {
+				if ref.Matches(src) {
+					return groupingError(src)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func groupingError(name core.ColumnName) error {
+	return fmt.Errorf(`ERROR:  column "%v" must appear in the GROUP BY clause or be used in an aggregate function`, name)
+}
+
+// resultCols makes columns of the result table.
+// Types are derived from the grouping keys and the aggregate functions, or inferred from the values
+// if they can't be derived.
+func (a *AggregateNode) resultCols(srcCols core.Cols, keyNames core.ColumnNames, valsList core.ValuesList) core.Cols {
+	cols := make(core.Cols, 0, len(keyNames)+len(a.Aggregates))
+	for k, name := range keyNames {
+		if (name == core.ColumnName{}) {
+			continue
+		}
+		typ, ok := exprType(a.GroupKeys[k], srcCols)
+		if !ok {
+			typ = inferValuesType(valsList, len(cols), core.VarChar)
+		}
+		cols = append(cols, core.Col{ColName: name, ColType: typ})
+	}
+	for k, agg := range a.Aggregates {
+		typ, ok := exprType(agg, srcCols)
+		if !ok {
+			typ = inferValuesType(valsList, len(cols), core.Integer)
+		}
+		cols = append(cols, core.Col{ColName: aggColName(k), ColType: typ})
+	}
+	for k := range a.Groupings {
+		cols = append(cols, core.Col{ColName: groupingColName(k), ColType: core.Integer})
+	}
+
+	return cols
+}
+
+// inferValuesType infers the type of k-th column from its first non-null value
+func inferValuesType(valsList core.ValuesList, k int, fallback core.ColType) core.ColType {
+	for _, vals := range valsList {
+		if typ, ok := core.TypeOf(vals[k]); ok {
+			return typ
+		}
+	}
+
+	return fallback
+}
+
+func groupKeyColName(k int) core.ColumnName {
+	return core.ColumnName{Name: fmt.Sprintf("?group%d?", k+1)}
+}
+
+func aggColName(k int) core.ColumnName {
+	return core.ColumnName{Name: fmt.Sprintf("?agg%d?", k+1)}
+}
+
+func groupingColName(k int) core.ColumnName {
+	return core.ColumnName{Name: fmt.Sprintf("?grouping%d?", k+1)}
+}
+
+// group is a group of rows which have the same grouping keys
+type group struct {
+	keys core.Values
+	// set is the grouping set of the group
+	set  []int
+	aggs []aggregator
+}
+
+func (a *AggregateNode) newGroup(keys core.Values, set []int) *group {
+	aggs := make([]aggregator, 0, len(a.Aggregates))
+	for _, agg := range a.Aggregates {
+		var ag aggregator = aggregateFuncs[agg.FuncName].newAggregator()
+		if agg.Distinct || len(agg.OrderBy) > 0 {
+			ag = &bufferedAgg{
+				aggregator: ag,
+				nargs:      len(agg.Args),
+				distinct:   agg.Distinct,
+				orderBy:    agg.OrderBy,
+			}
+		}
+		aggs = append(aggs, ag)
+	}
+
+	return &group{keys: keys, set: set, aggs: aggs}
+}
+
+func (g *group) step(row backend.Row, inputs []*aggInput) error {
+	for k, input := range inputs {
+		args, ok, err := input.eval(row)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := g.aggs[k].step(args); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *group) values(keyNames core.ColumnNames, groupings [][]int) (core.Values, error) {
+	vals := make(core.Values, 0, len(g.keys)+len(g.aggs)+len(groupings))
+	for k, v := range g.keys {
+		if (keyNames[k] != core.ColumnName{}) {
+			vals = append(vals, v)
+		}
+	}
+	for _, agg := range g.aggs {
+		v, err := agg.result()
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+	for _, args := range groupings {
+		vals = append(vals, g.grouping(args))
+	}
+
+	return vals, nil
+}
+
+// grouping calculates GROUPING operation whose arguments are the keys at idxs
+func (g *group) grouping(idxs []int) int {
+	mask := 0
+	for _, idx := range idxs {
+		mask <<= 1
+		if !containsInt(g.set, idx) {
+			mask |= 1
+		}
+	}
+
+	return mask
+}
+
+func containsInt(xs []int, x int) bool {
+	for _, v := range xs {
+		if v == x {
+			return true
+		}
+	}
+
+	return false
+}
+
+// aggInput evaluates the input of an aggregate function for a row
+type aggInput struct {
+	args    []func(backend.Row) (core.Value, error)
+	filter  func(backend.Row) (core.Value, error)
+	orderBy []func(backend.Row) (core.Value, error)
+}
+
+func newAggInput(db backend.DB, agg *AggCallNode) *aggInput {
+	input := &aggInput{}
+	for _, arg := range agg.Args {
+		input.args = append(input.args, scoped(db, arg.Eval()))
+	}
+	if agg.Filter != nil {
+		input.filter = scoped(db, agg.Filter.Eval())
+	}
+	for _, key := range agg.OrderBy {
+		input.orderBy = append(input.orderBy, scoped(db, key.Expr.Eval()))
+	}
+
+	return input
+}
+
+// eval evaluates arguments followed by ORDER BY keys.
+// The second return value is false if the row is filtered out by FILTER clause.
+func (in *aggInput) eval(row backend.Row) (core.Values, bool, error) {
+	if in.filter != nil {
+		v, err := in.filter(row)
+		if err != nil {
+			return nil, false, err
+		}
+		if v != core.True {
+			return nil, false, nil
+		}
+	}
+
+	vals := make(core.Values, 0, len(in.args)+len(in.orderBy))
+	for _, fn := range append(in.args, in.orderBy...) {
+		v, err := fn(row)
+		if err != nil {
+			return nil, false, err
+		}
+		vals = append(vals, v)
+	}
+
+	return vals, true, nil
+}
+
+// constructAggregateNode makes AggregateNode when the query has GROUP BY clause, HAVING clause or aggregate functions.
+// Grouping keys and aggregate function calls in targets and HAVING are replaced with references to
+// the columns of AggregateNode. HAVING is evaluated as WhereNode on AggregateNode.
+func constructAggregateNode(groupClause []*pg_query.Node, targetColNames core.ColumnNames, targets []ExpressionNode, having ExpressionNode, table RelationalAlgebraNode) (RelationalAlgebraNode, []ExpressionNode, error) {
+	keys, sets, err := interpretGroupClause(groupClause, targetColNames, targets)
+	if err != nil {
+		return nil, nil, err
+	}
+	hasAgg := false
+	for _, target := range targets {
+		if findExpr(target, isAggCall) || findExpr(target, isGroupingFunc) {
+			hasAgg = true
+		}
+	}
+	if len(keys) == 0 && !hasAgg && having == nil {
+		return table, targets, nil
+	}
+
+	agg := &AggregateNode{
+		GroupKeys:    keys,
+		GroupingSets: sets,
+		RANode:       table,
+	}
+	newTargets := make([]ExpressionNode, 0, len(targets))
+	for _, target := range targets {
+		expr, err := agg.replaceGrouped(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		newTargets = append(newTargets, expr)
+	}
+	agg.Outputs = newTargets
+	if having == nil {
+		return agg, newTargets, nil
+	}
+
+	cond, err := agg.replaceGrouped(having)
+	if err != nil {
+		return nil, nil, err
+	}
+	agg.Outputs = append(agg.Outputs, cond)
+
+	return &WhereNode{Condition: cond, Table: agg}, newTargets, nil
+}
+
+// interpretGroupClause returns grouping keys and grouping sets which are indexes of the keys.
+// The grouping sets are nil if GROUP BY clause has neither GROUPING SETS, ROLLUP nor CUBE.
+// targetColNames are the names of the select list, which are the first items of targets.
+func interpretGroupClause(groupClause []*pg_query.Node, targetColNames core.ColumnNames, targets []ExpressionNode) ([]ExpressionNode, [][]int, error) {
+	hasGroupingSet := false
+	exprSets := [][]ExpressionNode{{}}
+	for _, node := range groupClause {
+		var itemSets [][]ExpressionNode
+		if gs := node.GetGroupingSet(); gs != nil {
+			hasGroupingSet = true
+			sets, err := expandGroupingSet(gs)
+			if err != nil {
+				return nil, nil, err
+			}
+			itemSets = sets
+		} else {
+			key := constructExprNode(node)
+			if pos, ok := key.(IntegerNode); ok {
+				// GROUP BY 1 refers to the first item of the select list
+				switch {
+				case followsWildcard(pos.Val, targets):
+					// it's resolved when AggregateNode is evaluated
+					key = &TargetPosNode{Pos: pos.Val, Targets: targets, Clause: "GROUP BY"}
+				case pos.Val < 1 || pos.Val > len(targets):
+					return nil, nil, fmt.Errorf("ERROR:  GROUP BY position %d is not in select list", pos.Val)
+				default:
+					key = targets[pos.Val-1]
+				}
+			} else if alias, err := groupAlias(key, targetColNames, targets); err != nil {
+				return nil, nil, err
+			} else if alias != nil {
+				key = alias
+			}
+			if err := checkGroupKey(key); err != nil {
+				return nil, nil, err
+			}
+			itemSets = [][]ExpressionNode{{key}}
+		}
+
+		// multiple items of GROUP BY make the cross product of their grouping sets
+		product := make([][]ExpressionNode, 0, len(exprSets)*len(itemSets))
+		for _, set := range exprSets {
+			for _, itemSet := range itemSets {
+				product = append(product, append(append([]ExpressionNode{}, set...), itemSet...))
+			}
+		}
+		exprSets = product
+	}
+
+	keys := make([]ExpressionNode, 0)
+	for _, set := range exprSets {
+		for _, expr := range set {
+			if !containsExpr(keys, expr) {
+				keys = append(keys, expr)
+			}
+		}
+	}
+	if !hasGroupingSet {
+		return keys, nil, nil
+	}
+
+	sets := make([][]int, 0, len(exprSets))
+	for _, exprSet := range exprSets {
+		set := make([]int, 0, len(exprSet))
+		for _, expr := range exprSet {
+			for k, key := range keys {
+				if exprEqual(expr, key) && !containsInt(set, k) {
+					set = append(set, k)
+				}
+			}
+		}
+		sets = append(sets, set)
+	}
+
+	return keys, sets, nil
+}
+
+// groupAlias returns GroupAliasNode if key is a name of an item of the select list
+// which isn't the column of the same name. It returns nil otherwise.
+func groupAlias(key ExpressionNode, targetColNames core.ColumnNames, targets []ExpressionNode) (*GroupAliasNode, error) {
+	name, ok := colRefName(key)
+	if !ok || name.TableName != "" {
+		return nil, nil
+	}
+
+	var found ExpressionNode
+	for k, colName := range targetColNames {
+		if _, ok := targets[k].(ColWildcardNode); ok || colName.Name != name.Name {
+			continue
+		}
+		if found != nil && !exprMatches(found, targets[k]) {
+			return nil, fmt.Errorf(`ERROR:  GROUP BY "%v" is ambiguous`, name.Name)
+		}
+		found = targets[k]
+	}
+	if found == nil || exprMatches(found, key) {
+		return nil, nil
+	}
+
+	return &GroupAliasNode{Name: name, Target: found}, nil
+}
+
+// expandGroupingSet expands GROUPING SETS, ROLLUP and CUBE into a list of grouping sets.
+// For example, ROLLUP (a, b) is expanded into (a, b), (a) and ().
+func expandGroupingSet(gs *pg_query.GroupingSet) ([][]ExpressionNode, error) {
+	switch gs.GetKind() {
+	case pg_query.GroupingSetKind_GROUPING_SET_EMPTY:
+		return [][]ExpressionNode{{}}, nil
+	case pg_query.GroupingSetKind_GROUPING_SET_SETS:
+		sets := make([][]ExpressionNode, 0)
+		for _, node := range gs.GetContent() {
+			if sub := node.GetGroupingSet(); sub != nil {
+				subSets, err := expandGroupingSet(sub)
+				if err != nil {
+					return nil, err
+				}
+				sets = append(sets, subSets...)
+				continue
+			}
+			elem, err := groupingElement(node)
+			if err != nil {
+				return nil, err
+			}
+			sets = append(sets, elem)
+		}
+		return sets, nil
+	}
+
+	elems := make([][]ExpressionNode, 0, len(gs.GetContent()))
+	for _, node := range gs.GetContent() {
+		elem, err := groupingElement(node)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+
+	sets := make([][]ExpressionNode, 0)
+	switch gs.GetKind() {
+	case pg_query.GroupingSetKind_GROUPING_SET_ROLLUP:
+		for n := len(elems); n >= 0; n-- {
+			set := make([]ExpressionNode, 0)
+			for _, elem := range elems[:n] {
+				set = append(set, elem...)
+			}
+			sets = append(sets, set)
+		}
+	case pg_query.GroupingSetKind_GROUPING_SET_CUBE:
+		// The order of the sets is the same as PostgreSQL.
+		// CUBE (a, b) is expanded into (a, b), (a), (b) and ().
+		n := len(elems)
+		for mask := 1<<n - 1; mask >= 0; mask-- {
+			set := make([]ExpressionNode, 0)
+			for k, elem := range elems {
+				if mask&(1<<(n-1-k)) != 0 {
+					set = append(set, elem...)
+				}
+			}
+			sets = append(sets, set)
+		}
+	default:
+		return nil, errors.New("ERROR:  unsupported grouping set")
+	}
+
+	return sets, nil
+}
+
+// groupingElement returns expressions of an element of grouping sets.
+// A parenthesized list such as (a, b) is an element which has multiple expressions.
+func groupingElement(node *pg_query.Node) ([]ExpressionNode, error) {
+	nodes := []*pg_query.Node{node}
+	if row := node.GetRowExpr(); row != nil {
+		nodes = row.GetArgs()
+	}
+
+	exprs := make([]ExpressionNode, 0, len(nodes))
+	for _, n := range nodes {
+		expr := constructExprNode(n)
+		if err := checkGroupKey(expr); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+
+	return exprs, nil
+}
+
+func checkGroupKey(key ExpressionNode) error {
+	if findExpr(key, isAggCall) {
+		return errors.New("ERROR:  aggregate functions are not allowed in GROUP BY")
+	}
+	if findExpr(key, isGroupingFunc) {
+		return errors.New("ERROR:  grouping operations are not allowed in GROUP BY")
+	}
+	if findExpr(key, isWindowFunc) {
+		return errors.New("ERROR:  window functions are not allowed in GROUP BY")
+	}
+
+	return nil
+}
+
+// replaceGrouped replaces aggregate function calls and grouping keys in expr
+// with references to the columns of AggregateNode
+func (a *AggregateNode) replaceGrouped(expr ExpressionNode) (ExpressionNode, error) {
+	return a.replaceGroupedExpr(expr, true)
+}
+
+// replaceGroupedExpr is replaceGrouped. Items of the select list grouped by an alias are
+// replaced with GroupedAliasNode if aliases is true.
+func (a *AggregateNode) replaceGroupedExpr(expr ExpressionNode, aliases bool) (ExpressionNode, error) {
+	var err error
+	replaced := transformExpr(expr, func(e ExpressionNode) (ExpressionNode, bool) {
+		if agg, ok := e.(*AggCallNode); ok {
+			if aggErr := a.addAggregate(agg); aggErr != nil {
+				err = aggErr
+			}
+			return &ColRefNode{ColName: aggColName(len(a.Aggregates) - 1)}, true
+		}
+		if fn, ok := e.(*GroupingFuncNode); ok {
+			if groupingErr := a.addGrouping(fn); groupingErr != nil {
+				err = groupingErr
+			}
+			return &ColRefNode{ColName: groupingColName(len(a.Groupings) - 1)}, true
+		}
+		for k, key := range a.GroupKeys {
+			if alias, ok := key.(*GroupAliasNode); ok && aliases && exprEqual(e, alias.Target) {
+				// the item is evaluated as it is if the alias turns out to refer to a source column
+				item, itemErr := a.replaceGroupedExpr(e, false)
+				if itemErr != nil {
+					err = itemErr
+				}
+				return &GroupedAliasNode{Key: k, Expr: item}, true
+			}
+		}
+		if _, ok := colRefName(e); ok {
+			// a column reference is resolved by its name
+			return nil, false
+		}
+		for k, key := range a.GroupKeys {
+			if exprEqual(e, key) {
+				return &ColRefNode{ColName: groupKeyColName(k)}, true
+			}
+		}
+		return nil, false
+	})
+
+	return replaced, err
+}
+
+func (a *AggregateNode) addGrouping(fn *GroupingFuncNode) error {
+	idxs := make([]int, 0, len(fn.Args))
+	defer func() { a.Groupings = append(a.Groupings, idxs) }()
+
+	if len(fn.Args) > 31 {
+		return errors.New("ERROR:  GROUPING must have fewer than 32 arguments")
+	}
+	for _, arg := range fn.Args {
+		idx := a.groupKeyIndex(arg)
+		if idx < 0 {
+			return errors.New("ERROR:  arguments to GROUPING must be grouping expressions of the associated query level")
+		}
+		idxs = append(idxs, idx)
+	}
+
+	return nil
+}
+
+// groupKeyIndex returns the index of the grouping key which matches expr, or -1 if there is no such key.
+func (a *AggregateNode) groupKeyIndex(expr ExpressionNode) int {
+	for k, key := range a.GroupKeys {
+		if alias, ok := key.(*GroupAliasNode); ok {
+			key = &ColRefNode{ColName: alias.Name}
+		}
+		if exprMatches(expr, key) {
+			return k
+		}
+	}
+
+	return -1
+}
+
+func (a *AggregateNode) addAggregate(agg *AggCallNode) error {
+	a.Aggregates = append(a.Aggregates, agg)
+
+	fn := aggregateFuncs[agg.FuncName]
+	if agg.Star {
+		if !fn.allowStar {
+			return fmt.Errorf("ERROR:  function %v(*) does not exist", agg.FuncName)
+		}
+	} else if len(agg.Args) != fn.nargs {
+		return fmt.Errorf("ERROR:  function %v with %d arguments does not exist", agg.FuncName, len(agg.Args))
+	}
+	exprs := append([]ExpressionNode{agg.Filter}, agg.Args...)
+	for _, key := range agg.OrderBy {
+		exprs = append(exprs, key.Expr)
+	}
+	for _, expr := range exprs {
+		if findExpr(expr, isAggCall) {
+			return errors.New("ERROR:  aggregate function calls cannot be nested")
+		}
+		if findExpr(expr, isWindowFunc) {
+			return errors.New("ERROR:  aggregate function calls cannot contain window function calls")
+		}
+	}
+	if agg.Distinct {
+		for _, key := range agg.OrderBy {
+			if !containsExpr(agg.Args, key.Expr) {
+				return errors.New("ERROR:  in an aggregate with DISTINCT, ORDER BY expressions must appear in argument list")
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsExpr(exprs []ExpressionNode, expr ExpressionNode) bool {
+	for _, e := range exprs {
+		if exprEqual(e, expr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exprEqual reports whether two expressions are the same.
+// Column references are compared regardless of whether they are pointers.
+func exprEqual(x, y ExpressionNode) bool {
+	if xn, ok := colRefName(x); ok {
+		yn, ok := colRefName(y)
+		return ok && xn == yn
+	}
+
+	return reflect.DeepEqual(x, y)
+}
+
+// exprMatches reports whether x and y are the same expression.
+// Unlike exprEqual, column references match if they can refer to the same column such as a and t.a.
+func exprMatches(x, y ExpressionNode) bool {
+	if xn, ok := colRefName(x); ok {
+		yn, ok := colRefName(y)
+		return ok && (xn.Matches(yn) || yn.Matches(xn))
+	}
+
+	return exprEqual(x, y)
+}
+
+func containsMatchingExpr(exprs []ExpressionNode, expr ExpressionNode) bool {
+	for _, e := range exprs {
+		if _, ok := e.(ColWildcardNode); ok {
+			if _, ok := colRefName(expr); ok {
+				return true
+			}
+		}
+		if exprMatches(e, expr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// aggregator accumulates values of a group
+type aggregator interface {
+	step(args core.Values) error
+	result() (core.Value, error)
+}
+
+type aggregateFunc struct {
+	nargs         int
+	allowStar     bool
+	newAggregator func() aggregator
+}
+
+var aggregateFuncs = map[string]aggregateFunc{
+	"count":      {nargs: 1, allowStar: true, newAggregator: func() aggregator { return &countAgg{} }},
+	"sum":        {nargs: 1, newAggregator: func() aggregator { return &sumAgg{name: "sum"} }},
+	"avg":        {nargs: 1, newAggregator: func() aggregator { return &avgAgg{sumAgg{name: "avg"}} }},
+	"min":        {nargs: 1, newAggregator: func() aggregator { return &extremeAgg{name: "min", sign: -1} }},
+	"max":        {nargs: 1, newAggregator: func() aggregator { return &extremeAgg{name: "max", sign: 1} }},
+	"string_agg": {nargs: 2, newAggregator: func() aggregator { return &stringAgg{} }},
+	"array_agg":  {nargs: 1, newAggregator: func() aggregator { return &arrayAgg{} }},
+	"bool_and":   {nargs: 1, newAggregator: func() aggregator { return &boolAgg{name: "bool_and", and: true} }},
+	"every":      {nargs: 1, newAggregator: func() aggregator { return &boolAgg{name: "every", and: true} }},
+	"bool_or":    {nargs: 1, newAggregator: func() aggregator { return &boolAgg{name: "bool_or"} }},
+}
+
+func isNull(v core.Value) bool {
+	return v == nil || v == core.Null
+}
+
+// bufferedAgg holds inputs of an aggregate function with DISTINCT or ORDER BY
+// and gives them to the aggregate function when the result is taken.
+// Each input is arguments followed by values of ORDER BY keys.
+type bufferedAgg struct {
+	aggregator
+	nargs    int
+	distinct bool
+	orderBy  []SortKey
+	inputs   core.ValuesList
+}
+
+func (b *bufferedAgg) step(vals core.Values) error {
+	b.inputs = append(b.inputs, vals)
+
+	return nil
+}
+
+func (b *bufferedAgg) result() (core.Value, error) {
+	// DISTINCT without ORDER BY aggregates arguments in ascending order as PostgreSQL does
+	keyPos, orderBy := b.nargs, b.orderBy
+	if len(orderBy) == 0 {
+		keyPos, orderBy = 0, make([]SortKey, b.nargs)
+	}
+
+	var sortErr error
+	sort.SliceStable(b.inputs, func(i, j int) bool {
+		c, err := compareSortValues(b.inputs[i][keyPos:], b.inputs[j][keyPos:], orderBy)
+		if err != nil {
+			sortErr = err
+		}
+		return c < 0
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	seen := make(map[interface{}]bool)
+	for _, vals := range b.inputs {
+		args := vals[:b.nargs]
+		if b.distinct {
+			h := core.HashKey(args)
+			if seen[h] {
+				continue
+			}
+			seen[h] = true
+		}
+		if err := b.aggregator.step(args); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.aggregator.result()
+}
+
+// countAgg counts non-NULL values. count(*) is called without arguments and counts all rows.
+type countAgg struct {
+	count int
+}
+
+func (c *countAgg) step(args core.Values) error {
+	if len(args) == 0 || !isNull(args[0]) {
+		c.count++
+	}
+
+	return nil
+}
+
+func (c *countAgg) result() (core.Value, error) {
+	return c.count, nil
+}
+
+// sumAgg sums non-NULL numbers. The sum of integers is an integer.
+type sumAgg struct {
+	name    string
+	count   int
+	isFloat bool
+	isum    int
+	fsum    float64
+}
+
+func (s *sumAgg) step(args core.Values) error {
+	switch v := args[0].(type) {
+	case int:
+		if s.isFloat {
+			s.fsum += float64(v)
+		} else {
+			s.isum += v
+		}
+	case float64:
+		if !s.isFloat {
+			s.isFloat = true
+			s.fsum = float64(s.isum)
+		}
+		s.fsum += v
+	default:
+		if isNull(v) {
+			return nil
+		}
+		return fmt.Errorf("ERROR:  function %v(%v) does not exist", s.name, core.TypeName(v))
+	}
+	s.count++
+
+	return nil
+}
+
+func (s *sumAgg) result() (core.Value, error) {
+	if s.count == 0 {
+		return nil, nil
+	}
+	if s.isFloat {
+		return s.fsum, nil
+	}
+
+	return s.isum, nil
+}
+
+// avgAgg calculates the average of non-NULL numbers
+type avgAgg struct {
+	sumAgg
+}
+
+func (a *avgAgg) result() (core.Value, error) {
+	if a.count == 0 {
+		return nil, nil
+	}
+	if a.isFloat {
+		return a.fsum / float64(a.count), nil
+	}
+
+	return float64(a.isum) / float64(a.count), nil
+}
+
+// extremeAgg takes the minimum or maximum of non-NULL values
+type extremeAgg struct {
+	name string
+	// sign is 1 for max and -1 for min
+	sign int
+	val  core.Value
+}
+
+func (e *extremeAgg) step(args core.Values) error {
+	v := args[0]
+	if isNull(v) {
+		return nil
+	}
+	if _, ok := v.(core.BoolType); ok {
+		return fmt.Errorf("ERROR:  function %v(%v) does not exist", e.name, core.TypeName(v))
+	}
+	if e.val == nil {
+		e.val = v
+		return nil
+	}
+	c, err := core.Compare(v, e.val)
+	if err != nil {
+		return err
+	}
+	if c*e.sign > 0 {
+		e.val = v
+	}
+
+	return nil
+}
+
+func (e *extremeAgg) result() (core.Value, error) {
+	return e.val, nil
+}
+
+// stringAgg concatenates non-NULL strings. Each string except the first is preceded by the delimiter of its row.
+type stringAgg struct {
+	str  strings.Builder
+	seen bool
+}
+
+func (s *stringAgg) step(args core.Values) error {
+	for _, arg := range args {
+		if _, ok := arg.(string); !ok && !isNull(arg) {
+			return fmt.Errorf("ERROR:  function string_agg(%v, %v) does not exist", core.TypeName(args[0]), core.TypeName(args[1]))
+		}
+	}
+	v, delim := args[0], args[1]
+	if isNull(v) {
+		return nil
+	}
+	if s.seen && !isNull(delim) {
+		s.str.WriteString(delim.(string))
+	}
+	s.str.WriteString(v.(string))
+	s.seen = true
+
+	return nil
+}
+
+func (s *stringAgg) result() (core.Value, error) {
+	if !s.seen {
+		return nil, nil
+	}
+
+	return s.str.String(), nil
+}
+
+// arrayAgg collects values including NULL into an array.
+// There is no array type, so the result is the text representation of the array such as {1,NULL,3}.
+type arrayAgg struct {
+	elems []string
+}
+
+func (a *arrayAgg) step(args core.Values) error {
+	a.elems = append(a.elems, arrayElement(args[0]))
+
+	return nil
+}
+
+func (a *arrayAgg) result() (core.Value, error) {
+	if a.elems == nil {
+		return nil, nil
+	}
+
+	return "{" + strings.Join(a.elems, ",") + "}", nil
+}
+
+// arrayElement formats v as an element of the text representation of an array
+func arrayElement(v core.Value) string {
+	if isNull(v) {
+		return "NULL"
+	}
+
+	str := textOf(v)
+	s, ok := v.(string)
+	if !ok {
+		return str
+	}
+	if s != "" && !strings.EqualFold(s, "null") && !strings.ContainsAny(s, "{},\" \t\n\\") {
+		return s
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+
+	return `"` + escaped + `"`
+}
+
+// boolAgg calculates AND or OR of non-NULL booleans
+type boolAgg struct {
+	name string
+	and  bool
+	val  core.Value
+}
+
+func (b *boolAgg) step(args core.Values) error {
+	v := args[0]
+	if isNull(v) {
+		return nil
+	}
+	if _, ok := v.(core.BoolType); !ok {
+		return fmt.Errorf("ERROR:  function %v(%v) does not exist", b.name, core.TypeName(v))
+	}
+	switch {
+	case b.val == nil:
+		b.val = v
+	case b.and:
+		b.val = core.AND(b.val, v)
+	default:
+		b.val = core.OR(b.val, v)
+	}
+
+	return nil
+}
+
+func (b *boolAgg) result() (core.Value, error) {
+	return b.val, nil
+}
@@ -0,0 +1,345 @@
+package translator
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+)
+
+// TypeCastNode is expression of CAST(x AS type), x::type and a typed literal such as DATE '2024-01-01'.
+// TypeName is the internal name of the type such as int4, and TypeMods are its modifiers like varchar(10).
+type TypeCastNode struct {
+	Expr     ExpressionNode
+	TypeName string
+	TypeMods []int
+}
+
+// castType is a type which a value can be cast to
+type castType struct {
+	colType core.ColType
+
+	// name is the SQL name of the type, which is used in error messages
+	name string
+
+	// min and max are the range of an integer type
+	min, max int64
+}
+
+var castTypes = map[string]castType{
+	"int2":    {colType: core.Integer, name: "smallint", min: math.MinInt16, max: math.MaxInt16},
+	"int4":    {colType: core.Integer, name: "integer", min: math.MinInt32, max: math.MaxInt32},
+	"int8":    {colType: core.Integer, name: "bigint", min: math.MinInt64, max: math.MaxInt64},
+	"float4":  {colType: core.Float, name: "real"},
+	"float8":  {colType: core.Float, name: "double precision"},
+	"numeric": {colType: core.Float, name: "numeric"},
+	"varchar": {colType: core.VarChar, name: "character varying"},
+	"text":    {colType: core.VarChar, name: "text"},
+	"bpchar":  {colType: core.VarChar, name: "character"},
+	"bool":    {colType: core.Boolean, name: "boolean"},
+	"date":    {colType: core.Date, name: "date"},
+}
+
+// integerCastType is the type of integer columns and literals.
+// Integer columns don't keep their widths, so any value of bigint is accepted.
+var integerCastType = castType{colType: core.Integer, name: "integer", min: math.MinInt64, max: math.MaxInt64}
+
+// Eval evaluates TypeCastNode. NULL can be cast to any type.
+func (n *TypeCastNode) Eval() func(backend.Row) (core.Value, error) {
+	typ := castTypes[n.TypeName]
+	return func(row backend.Row) (core.Value, error) {
+		v, err := n.Expr.Eval()(row)
+		if err != nil {
+			return nil, err
+		}
+		v = nullIfNil(v)
+		if v == core.Null {
+			return core.Null, nil
+		}
+
+		switch typ.colType {
+		case core.Integer:
+			return castToInteger(v, typ)
+		case core.Float:
+			return castToFloat(v, typ, n.TypeMods)
+		case core.VarChar:
+			return castToText(v, n.TypeName, n.TypeMods), nil
+		case core.Boolean:
+			return castToBoolean(v, typ)
+		}
+		return castToDate(v, typ)
+	}
+}
+
+func cannotCast(v core.Value, typ castType) error {
+	return fmt.Errorf("ERROR:  cannot cast type %v to %v", core.TypeName(v), typ.name)
+}
+
+func invalidInput(s string, typ castType) error {
+	return fmt.Errorf(`ERROR:  invalid input syntax for type %v: "%v"`, typ.name, s)
+}
+
+func castToInteger(v core.Value, typ castType) (core.Value, error) {
+	switch val := v.(type) {
+	case int:
+		if int64(val) < typ.min || int64(val) > typ.max {
+			return nil, fmt.Errorf("ERROR:  %v out of range", typ.name)
+		}
+		return val, nil
+	case float64:
+		// a float is rounded half away from zero as numeric is
+		f := math.Round(val)
+		if math.IsNaN(f) || f < float64(typ.min) || f >= -float64(typ.min) {
+			return nil, fmt.Errorf("ERROR:  %v out of range", typ.name)
+		}
+		return int(f), nil
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+		if err != nil {
+			if err.(*strconv.NumError).Err == strconv.ErrRange {
+				return nil, fmt.Errorf(`ERROR:  value "%v" is out of range for type %v`, val, typ.name)
+			}
+			return nil, invalidInput(val, typ)
+		}
+		if i < typ.min || i > typ.max {
+			return nil, fmt.Errorf(`ERROR:  value "%v" is out of range for type %v`, val, typ.name)
+		}
+		return int(i), nil
+	case core.BoolType:
+		if val == core.True {
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return nil, cannotCast(v, typ)
+}
+
+func castToFloat(v core.Value, typ castType, mods []int) (core.Value, error) {
+	var f float64
+	switch val := v.(type) {
+	case int:
+		f = float64(val)
+	case float64:
+		f = val
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
+			return nil, invalidInput(val, typ)
+		}
+		if math.IsInf(parsed, 0) && !strings.Contains(strings.ToLower(val), "inf") {
+			return nil, fmt.Errorf(`ERROR:  "%v" is out of range for type %v`, val, typ.name)
+		}
+		f = parsed
+	default:
+		return nil, cannotCast(v, typ)
+	}
+
+	switch {
+	case typ.name == "real":
+		f = float64(float32(f))
+	case typ.name == "numeric" && len(mods) > 0:
+		// numeric(precision, scale) rounds the value to the scale
+		precision, scale := mods[0], 0
+		if len(mods) > 1 {
+			scale = mods[1]
+		}
+		f = math.Round(f*math.Pow10(scale)) / math.Pow10(scale)
+		if math.Abs(f) >= math.Pow10(precision-scale) {
+			return nil, fmt.Errorf("ERROR:  numeric field overflow")
+		}
+	}
+
+	return f, nil
+}
+
+// castToText converts a value to its text representation.
+// varchar(n) truncates the text to n characters and char(n) also pads it with spaces.
+func castToText(v core.Value, typeName string, mods []int) core.Value {
+	var s string
+	switch val := v.(type) {
+	case float64:
+		switch {
+		case math.IsInf(val, 1):
+			s = "Infinity"
+		case math.IsInf(val, -1):
+			s = "-Infinity"
+		case math.IsNaN(val):
+			s = "NaN"
+		default:
+			s = strconv.FormatFloat(val, 'f', -1, 64)
+		}
+	case core.BoolType:
+		s = "false"
+		if val == core.True {
+			s = "true"
+		}
+	default:
+		s = fmt.Sprintf("%v", val)
+	}
+	if len(mods) == 0 || typeName == "text" {
+		return s
+	}
+
+	rs := []rune(s)
+	if len(rs) > mods[0] {
+		rs = rs[:mods[0]]
+	}
+	if typeName == "bpchar" {
+		for len(rs) < mods[0] {
+			rs = append(rs, ' ')
+		}
+	}
+
+	return string(rs)
+}
+
+func castToBoolean(v core.Value, typ castType) (core.Value, error) {
+	switch val := v.(type) {
+	case core.BoolType:
+		return val, nil
+	case int:
+		return toSQLBool(val != 0), nil
+	case string:
+		// unique prefixes of true, false, yes and no are accepted as in PostgreSQL
+		s := strings.ToLower(strings.TrimSpace(val))
+		switch {
+		case s == "":
+		case strings.HasPrefix("true", s), strings.HasPrefix("yes", s), s == "on", s == "1":
+			return core.True, nil
+		case strings.HasPrefix("false", s), strings.HasPrefix("no", s), s == "off", s == "of", s == "0":
+			return core.False, nil
+		}
+		return nil, invalidInput(val, typ)
+	}
+
+	return nil, cannotCast(v, typ)
+}
+
+var datePattern = regexp.MustCompile(`^(\d{4,})[-/.](\d{1,2})[-/.](\d{1,2})$`)
+
+func castToDate(v core.Value, typ castType) (core.Value, error) {
+	switch val := v.(type) {
+	case core.DateType:
+		return val, nil
+	case string:
+		m := datePattern.FindStringSubmatch(strings.TrimSpace(val))
+		if m == nil {
+			return nil, invalidInput(val, typ)
+		}
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		date, ok := core.NewDate(year, month, day)
+		if !ok {
+			return nil, fmt.Errorf(`ERROR:  date/time field value out of range: "%v"`, val)
+		}
+		return date, nil
+	}
+
+	return nil, cannotCast(v, typ)
+}
+
+// assignCast converts a value assigned to the column into the column type as PostgreSQL's assignment cast.
+// A string such as an untyped literal is read as the input of the type, and a value of another type
+// is converted only if the conversion is allowed in assignment.
+func assignCast(v core.Value, col core.Col) (core.Value, error) {
+	if v == nil || v == core.Null || v == core.Default {
+		return v, nil
+	}
+	if typ, ok := core.TypeOf(v); ok && typ == col.ColType {
+		return v, nil
+	}
+
+	_, isString := v.(string)
+	switch col.ColType {
+	case core.Integer:
+		if _, ok := v.(float64); ok || isString {
+			return castToInteger(v, integerCastType)
+		}
+	case core.Float:
+		if _, ok := v.(int); ok || isString {
+			return castToFloat(v, castTypes["float8"], nil)
+		}
+	case core.VarChar:
+		return castToText(v, "varchar", nil), nil
+	case core.Boolean:
+		if isString {
+			return castToBoolean(v, castTypes["bool"])
+		}
+	case core.Date:
+		if isString {
+			return castToDate(v, castTypes["date"])
+		}
+	}
+
+	return nil, fmt.Errorf(`ERROR:  column "%v" is of type %v but expression is of type %v`,
+		col.ColName.Name, core.ColTypeName(col.ColType), core.TypeName(v))
+}
+
+// assignCastValues converts values inserted into the columns of names into the column types.
+// All columns of the table are assigned in order if names is empty.
+func assignCastValues(tb backend.Table, names core.ColumnNames, valsList core.ValuesList) (core.ValuesList, error) {
+	cols := make(core.Cols, 0, len(names))
+	for _, name := range names {
+		cols = append(cols, findCol(tb, name))
+	}
+	if len(names) == 0 {
+		cols = tb.GetCols()
+	}
+
+	casted := make(core.ValuesList, 0, len(valsList))
+	for _, vals := range valsList {
+		newVals := make(core.Values, 0, len(vals))
+		for k, v := range vals {
+			if k < len(cols) && cols[k].ColName.Name != "" {
+				var err error
+				if v, err = assignCast(v, cols[k]); err != nil {
+					return nil, err
+				}
+			}
+			newVals = append(newVals, v)
+		}
+		casted = append(casted, newVals)
+	}
+
+	return casted, nil
+}
+
+// assignCastFns wraps functions which compute values assigned to the columns of names
+// so that the values are converted into the column types.
+func assignCastFns(tb backend.Table, names core.ColumnNames, fns []func(backend.Row) (core.Value, error)) []func(backend.Row) (core.Value, error) {
+	casted := make([]func(backend.Row) (core.Value, error), 0, len(fns))
+	for k, fn := range fns {
+		fn := fn
+		col := findCol(tb, names[k])
+		if col.ColName.Name == "" {
+			casted = append(casted, fn)
+			continue
+		}
+		casted = append(casted, func(row backend.Row) (core.Value, error) {
+			v, err := fn(row)
+			if err != nil {
+				return nil, err
+			}
+			return assignCast(v, col)
+		})
+	}
+
+	return casted
+}
+
+// findCol returns the column of the table which has the name. It returns the zero Col if there is no such column.
+func findCol(tb backend.Table, name core.ColumnName) core.Col {
+	for _, col := range tb.GetCols() {
+		if col.ColName.Name == name.Name {
+			return col
+		}
+	}
+
+	return core.Col{}
+}
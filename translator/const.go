@@ -11,6 +11,29 @@ const (
 	NotEqualNull
 )
 
+// BoolTestType is boolean test type
+type BoolTestType int
+
+const (
+	// IsTrue corresponds to `IS TRUE` operation
+	IsTrue BoolTestType = iota
+
+	// IsNotTrue corresponds to `IS NOT TRUE` operation
+	IsNotTrue
+
+	// IsFalse corresponds to `IS FALSE` operation
+	IsFalse
+
+	// IsNotFalse corresponds to `IS NOT FALSE` operation
+	IsNotFalse
+
+	// IsUnknown corresponds to `IS UNKNOWN` operation
+	IsUnknown
+
+	// IsNotUnknown corresponds to `IS NOT UNKNOWN` operation
+	IsNotUnknown
+)
+
 // MathOp express SQL mathemathical operators
 type MathOp int
 
@@ -31,4 +54,40 @@ const (
 	GEQ
 	LEQ
 	CONCAT
+
+	// Like is LIKE (~~). NotLike, ILike and NotILike are NOT LIKE, ILIKE and NOT ILIKE.
+	Like
+	NotLike
+	ILike
+	NotILike
+
+	// RegexMatch is ~. RegexIMatch, NotRegexMatch and NotRegexIMatch are ~*, !~ and !~*.
+	// SIMILAR TO is also RegexMatch as its pattern is converted to a regular expression.
+	RegexMatch
+	RegexIMatch
+	NotRegexMatch
+	NotRegexIMatch
 )
+
+// mathOpSymbols are SQL symbols of MathOp, which are used in error messages
+var mathOpSymbols = map[MathOp]string{
+	EqualOp:        "=",
+	NotEqualOp:     "<>",
+	Plus:           "+",
+	Minus:          "-",
+	Multiply:       "*",
+	Divide:         "/",
+	GT:             ">",
+	LT:             "<",
+	GEQ:            ">=",
+	LEQ:            "<=",
+	CONCAT:         "||",
+	Like:           "~~",
+	NotLike:        "!~~",
+	ILike:          "~~*",
+	NotILike:       "!~~*",
+	RegexMatch:     "~",
+	RegexIMatch:    "~*",
+	NotRegexMatch:  "!~",
+	NotRegexIMatch: "!~*",
+}
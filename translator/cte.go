@@ -0,0 +1,246 @@
+package translator
+
+import (
+	"fmt"
+
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+)
+
+// maxRecursion is the maximum number of iterations of a recursive query.
+// It stops a recursive query which never terminates such as UNION ALL over a cyclic graph.
+const maxRecursion = 10000
+
+// CTEMaterialize is a hint of MATERIALIZED and NOT MATERIALIZED
+type CTEMaterialize int
+
+const (
+	// MaterializeDefault is a common table expression without the hint. It's evaluated once.
+	MaterializeDefault CTEMaterialize = iota
+
+	// MaterializeAlways is MATERIALIZED. It's evaluated once.
+	MaterializeAlways
+
+	// MaterializeNever is NOT MATERIALIZED. It's evaluated each time it's referred.
+	MaterializeNever
+)
+
+// CTE is a common table expression of WITH clause
+type CTE struct {
+	Name string
+	// ColNames renames the columns of the query
+	ColNames []string
+	Query    RelationalAlgebraNode
+	// Recursive is the recursive term of WITH RECURSIVE. Query is the non-recursive term then.
+	Recursive   RelationalAlgebraNode
+	UnionAll    bool
+	Materialize CTEMaterialize
+}
+
+// WithNode is Node of WITH clause.
+// A common table expression can be referred by the following ones and Query.
+// It's evaluated when it's referred first except for INSERT, UPDATE and DELETE
+// which are always executed once before Query.
+type WithNode struct {
+	CTEs  []*CTE
+	Query RelationalAlgebraNode
+}
+
+// Eval evaluates WithNode
+func (w *WithNode) Eval(db backend.DB) (backend.Table, error) {
+	// All statements see tables as they were before INSERT, UPDATE and DELETE of WITH clause.
+	for _, cte := range w.CTEs {
+		name, ok := modifiedTable(cte.Query)
+		if !ok {
+			continue
+		}
+		if tb, err := dbOf(db).GetTable(name); err == nil {
+			db = withCTE(db, name, &cteRef{table: tb.Copy()})
+		}
+	}
+
+	for _, cte := range w.CTEs {
+		ref := &cteRef{cte: cte, db: db}
+		db = withCTE(db, cte.Name, ref)
+		if _, ok := modifiedTable(cte.Query); ok {
+			tb, err := cte.eval(ref.db)
+			if err != nil {
+				return nil, err
+			}
+			ref.table = tb
+		}
+	}
+
+	return w.Query.Eval(db)
+}
+
+func (w *WithNode) isLogged(db backend.DB) bool {
+	for _, cte := range w.CTEs {
+		if node, ok := cte.Query.(loggedNode); ok && node.isLogged(db) {
+			return true
+		}
+	}
+	if node, ok := w.Query.(loggedNode); ok {
+		return node.isLogged(db)
+	}
+
+	return false
+}
+
+// modifiedTable returns the name of the table which is modified by ra
+func modifiedTable(ra RelationalAlgebraNode) (string, bool) {
+	switch n := ra.(type) {
+	case *InsertNode:
+		return n.TableName, true
+	case *UpdateNode:
+		return n.TableName, true
+	case *DeleteNode:
+		return n.TableName, true
+	}
+
+	return "", false
+}
+
+// cteRef is a reference to a table defined by WITH clause
+type cteRef struct {
+	cte *CTE
+	// db is the scope in which the common table expression is defined
+	db    backend.DB
+	table backend.Table
+}
+
+func (r *cteRef) getTable() (backend.Table, error) {
+	if r.table != nil {
+		return r.table, nil
+	}
+	if _, ok := modifiedTable(r.cte.Query); ok {
+		return nil, fmt.Errorf("ERROR:  WITH query \"%v\" does not have a RETURNING clause", r.cte.Name)
+	}
+
+	tb, err := r.cte.eval(r.db)
+	if err != nil {
+		return nil, err
+	}
+	if r.cte.Materialize != MaterializeNever {
+		r.table = tb
+	}
+
+	return tb, nil
+}
+
+func (c *CTE) eval(db backend.DB) (backend.Table, error) {
+	if c.Recursive != nil {
+		return c.evalRecursive(db)
+	}
+
+	tb, err := c.Query.Eval(db)
+	if err != nil || tb == nil {
+		return nil, err
+	}
+
+	return c.rename(tb.GetCols(), valuesOf(tb))
+}
+
+// evalRecursive evaluates WITH RECURSIVE.
+// The recursive term is evaluated repeatedly with the rows made by the previous iteration
+// until no row is made. With UNION, rows which have already been made are discarded,
+// so that the iteration over a cyclic graph terminates.
+func (c *CTE) evalRecursive(db backend.DB) (backend.Table, error) {
+	tb, err := c.Query.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	valsList := valuesOf(tb)
+	seen := make(map[interface{}]bool)
+	if !c.UnionAll {
+		valsList = distinctValues(valsList)
+		for _, vals := range valsList {
+			seen[core.HashKey(vals)] = true
+		}
+	}
+	working, err := c.rename(tb.GetCols(), valsList)
+	if err != nil {
+		return nil, err
+	}
+	cols := working.GetCols()
+
+	for k := 0; len(working.GetRows()) > 0; k++ {
+		if k == maxRecursion {
+			return nil, fmt.Errorf("ERROR:  recursive query \"%v\" exceeded %d iterations", c.Name, maxRecursion)
+		}
+
+		rtb, err := c.Recursive.Eval(withCTE(db, c.Name, &cteRef{table: working}))
+		if err != nil {
+			return nil, err
+		}
+		if err := c.checkRecursiveCols(working, rtb); err != nil {
+			return nil, err
+		}
+
+		newVals := make(core.ValuesList, 0)
+		for _, vals := range setOpValues(rtb, cols) {
+			if !c.UnionAll {
+				h := core.HashKey(vals)
+				if seen[h] {
+					continue
+				}
+				seen[h] = true
+			}
+			newVals = append(newVals, vals)
+		}
+		valsList = append(valsList, newVals...)
+		working = backend.NewTable(c.Name, cols, newVals)
+	}
+
+	return backend.NewTable(c.Name, cols, valsList), nil
+}
+
+// checkRecursiveCols checks that the recursive term makes rows of the same types as the non-recursive term
+func (c *CTE) checkRecursiveCols(working, rtb backend.Table) error {
+	cols, err := (&SetOpNode{Op: Union}).resultCols(working, rtb)
+	if err != nil {
+		return err
+	}
+	for k, col := range working.GetCols() {
+		if col.ColType != cols[k].ColType {
+			return fmt.Errorf("ERROR:  recursive query \"%v\" column %d has type %v in non-recursive term but type %v overall",
+				c.Name, k+1, core.ColTypeName(col.ColType), core.ColTypeName(cols[k].ColType))
+		}
+	}
+
+	return nil
+}
+
+// rename makes the table of the common table expression whose columns are renamed by ColNames
+func (c *CTE) rename(cols core.Cols, valsList core.ValuesList) (backend.Table, error) {
+	if len(c.ColNames) > len(cols) {
+		return nil, fmt.Errorf("ERROR:  WITH query \"%v\" has %d columns available but %d columns specified", c.Name, len(cols), len(c.ColNames))
+	}
+
+	newCols := make(core.Cols, 0, len(cols))
+	for k, col := range cols {
+		name := col.ColName.Name
+		if k < len(c.ColNames) {
+			name = c.ColNames[k]
+		}
+		newCols = append(newCols, core.Col{
+			ColName: core.ColumnName{TableName: c.Name, Name: name},
+			ColType: col.ColType,
+		})
+	}
+
+	return backend.NewTable(c.Name, newCols, valsList), nil
+}
+
+func valuesOf(tb backend.Table) core.ValuesList {
+	if tb == nil {
+		return nil
+	}
+
+	valsList := make(core.ValuesList, 0, len(tb.GetRows()))
+	for _, row := range tb.GetRows() {
+		valsList = append(valsList, row.GetValues())
+	}
+
+	return valsList
+}
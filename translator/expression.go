@@ -93,6 +93,163 @@ func (n ColWildcardNode) Eval() func(backend.Row) (core.Value, error) {
 	}
 }
 
+// ColPosNode is expression of the column at Pos of the row. Pos starts from 1.
+// It's used in ORDER BY of set operations whose columns may have no names.
+type ColPosNode struct {
+	Pos int
+}
+
+// Eval evaluates ColPosNode
+func (n *ColPosNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		vals := row.GetValues()
+		if n.Pos < 1 || n.Pos > len(vals) {
+			return nil, fmt.Errorf("ERROR:  ORDER BY position %d is not in select list", n.Pos)
+		}
+		return vals[n.Pos-1], nil
+	}
+}
+
+// TargetPosNode is a position of the select list in ORDER BY or GROUP BY which is at or after *.
+// It's resolved after * is expanded to the columns of the source rows.
+type TargetPosNode struct {
+	Pos     int
+	Targets []ExpressionNode
+	// Clause is ORDER BY or GROUP BY
+	Clause string
+}
+
+// locate finds the item of the select list at the position when * is expanded to names.
+// It returns the index of names if the item is a column expanded from *, otherwise the expression of the item.
+func (n *TargetPosNode) locate(names core.ColumnNames) (int, ExpressionNode, error) {
+	pos := n.Pos
+	for _, target := range n.Targets {
+		if _, ok := target.(ColWildcardNode); !ok {
+			if pos == 1 {
+				return -1, target, nil
+			}
+			pos--
+			continue
+		}
+		for k, name := range names {
+			if name.TableName == "" && internalColName.MatchString(name.Name) {
+				// columns made by AggregateNode and WindowNode aren't expanded
+				continue
+			}
+			if pos == 1 {
+				return k, nil, nil
+			}
+			pos--
+		}
+	}
+
+	return -1, nil, fmt.Errorf("ERROR:  %v position %d is not in select list", n.Clause, n.Pos)
+}
+
+// resolve returns the expression of the item of the select list at the position
+func (n *TargetPosNode) resolve(names core.ColumnNames) (ExpressionNode, error) {
+	idx, expr, err := n.locate(names)
+	if err != nil || expr != nil {
+		return expr, err
+	}
+
+	return ColRefNode{ColName: names[idx]}, nil
+}
+
+// Eval evaluates TargetPosNode
+func (n *TargetPosNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		idx, expr, err := n.locate(row.GetColNames())
+		if err != nil {
+			return nil, err
+		}
+		if expr != nil {
+			return expr.Eval()(row)
+		}
+		return row.GetValues()[idx], nil
+	}
+}
+
+// GroupAliasNode is a name in GROUP BY which is an alias of an item of the select list.
+// As in PostgreSQL, it refers to the source column of the name if there is, otherwise the item.
+// It's resolved when AggregateNode is evaluated.
+type GroupAliasNode struct {
+	Name   core.ColumnName
+	Target ExpressionNode
+}
+
+// resolve returns the expression which the name refers to when the source columns are names
+func (n *GroupAliasNode) resolve(names core.ColumnNames) ExpressionNode {
+	if haveColumn(n.Name, names) {
+		return ColRefNode{ColName: n.Name}
+	}
+
+	return n.Target
+}
+
+// Eval evaluates GroupAliasNode
+func (n *GroupAliasNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		return n.resolve(row.GetColNames()).Eval()(row)
+	}
+}
+
+// GroupedAliasNode is an item of the select list which is grouped by GroupAliasNode.
+// It takes the value of the grouping key if the key refers to the item, otherwise Expr is evaluated.
+type GroupedAliasNode struct {
+	// Key is the index of the grouping key
+	Key  int
+	Expr ExpressionNode
+}
+
+// Eval evaluates GroupedAliasNode
+func (n *GroupedAliasNode) Eval() func(backend.Row) (core.Value, error) {
+	fn := n.Expr.Eval()
+	keyName := groupKeyColName(n.Key)
+	return func(row backend.Row) (core.Value, error) {
+		for k, name := range row.GetColNames() {
+			if name.Equal(keyName) {
+				return row.GetValues()[k], nil
+			}
+		}
+		return fn(row)
+	}
+}
+
+// followsWildcard reports whether * is at or before the position of targets
+func followsWildcard(pos int, targets []ExpressionNode) bool {
+	for k := 0; k < pos && k < len(targets); k++ {
+		if _, ok := targets[k].(ColWildcardNode); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultNode is expression of DEFAULT in VALUES of INSERT statement
+type DefaultNode struct{}
+
+// Eval evaluates DefaultNode
+func (n DefaultNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(backend.Row) (core.Value, error) {
+		return core.Default, nil
+	}
+}
+
+// errorNode is expression which can't be translated. It returns err when it's evaluated
+// because constructExprNode doesn't return an error.
+type errorNode struct {
+	err error
+}
+
+// Eval evaluates errorNode
+func (n *errorNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(backend.Row) (core.Value, error) {
+		return nil, n.err
+	}
+}
+
 // NotNode is expression of Not
 type NotNode struct {
 	Expr ExpressionNode
@@ -181,6 +338,92 @@ func (n NullTestNode) Eval() func(backend.Row) (core.Value, error) {
 	}
 }
 
+// BoolTestNode is expression of `IS (NOT) TRUE`, `IS (NOT) FALSE` and `IS (NOT) UNKNOWN`
+type BoolTestNode struct {
+	TestType BoolTestType
+	Expr     ExpressionNode
+}
+
+var boolTestNames = map[BoolTestType]string{
+	IsTrue:       "IS TRUE",
+	IsNotTrue:    "IS NOT TRUE",
+	IsFalse:      "IS FALSE",
+	IsNotFalse:   "IS NOT FALSE",
+	IsUnknown:    "IS UNKNOWN",
+	IsNotUnknown: "IS NOT UNKNOWN",
+}
+
+// Eval evaluates BoolTestNode. Unlike a boolean value itself, the result is never NULL.
+func (n *BoolTestNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		val, err := n.Expr.Eval()(row)
+		if err != nil {
+			return nil, err
+		}
+		val = nullIfNil(val)
+		if _, ok := val.(core.BoolType); !ok {
+			return nil, fmt.Errorf("ERROR:  argument of %v must be type boolean, not type %v", boolTestNames[n.TestType], core.TypeName(val))
+		}
+
+		switch n.TestType {
+		case IsTrue:
+			return toSQLBool(val == core.True), nil
+		case IsNotTrue:
+			return toSQLBool(val != core.True), nil
+		case IsFalse:
+			return toSQLBool(val == core.False), nil
+		case IsNotFalse:
+			return toSQLBool(val != core.False), nil
+		case IsUnknown:
+			return toSQLBool(val == core.Null), nil
+		}
+		return toSQLBool(val != core.Null), nil
+	}
+}
+
+// DistinctFromNode is expression of `IS (NOT) DISTINCT FROM`.
+// It compares values as `=` does but treats NULL as an ordinary value.
+type DistinctFromNode struct {
+	Not   bool
+	Lexpr ExpressionNode
+	Rexpr ExpressionNode
+}
+
+// Eval evaluates DistinctFromNode
+func (n *DistinctFromNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		l, err := n.Lexpr.Eval()(row)
+		if err != nil {
+			return nil, err
+		}
+		r, err := n.Rexpr.Eval()(row)
+		if err != nil {
+			return nil, err
+		}
+		l, r = nullIfNil(l), nullIfNil(r)
+
+		var distinct bool
+		switch {
+		case l == core.Null || r == core.Null:
+			distinct = l != r
+		default:
+			if l, r, err = coerceLiterals(n.Lexpr, n.Rexpr, l, r); err != nil {
+				return nil, err
+			}
+			eq, err := BinOpNode{Op: EqualOp, Lexpr: valueNode{val: l}, Rexpr: valueNode{val: r}}.Eval()(row)
+			if err != nil {
+				return nil, err
+			}
+			distinct = eq != core.True
+		}
+		if n.Not {
+			return toSQLBool(!distinct), nil
+		}
+
+		return toSQLBool(distinct), nil
+	}
+}
+
 // CaseNode is expression of CaseNode
 type CaseNode struct {
 	CaseWhenExprs   []ExpressionNode
@@ -205,6 +448,56 @@ func (c *CaseNode) Eval() func(backend.Row) (core.Value, error) {
 	}
 }
 
+// CoalesceNode is expression of COALESCE(a, b, ...), which is the first argument that isn't NULL.
+// The arguments after it aren't evaluated as in PostgreSQL.
+type CoalesceNode struct {
+	Args []ExpressionNode
+}
+
+// Eval evaluates CoalesceNode
+func (c *CoalesceNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		for _, arg := range c.Args {
+			v, err := arg.Eval()(row)
+			if err != nil {
+				return nil, err
+			}
+			if v = nullIfNil(v); v != core.Null {
+				return v, nil
+			}
+		}
+
+		return core.Null, nil
+	}
+}
+
+// NullIfNode is expression of NULLIF(a, b), which is NULL if a = b, otherwise a
+type NullIfNode struct {
+	Lexpr ExpressionNode
+	Rexpr ExpressionNode
+}
+
+// Eval evaluates NullIfNode
+func (n *NullIfNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		l, err := n.Lexpr.Eval()(row)
+		if err != nil {
+			return nil, err
+		}
+		l = nullIfNil(l)
+		// the first argument is evaluated only once even if it's volatile
+		eq, err := BinOpNode{Op: EqualOp, Lexpr: valueNode{val: l}, Rexpr: n.Rexpr}.Eval()(row)
+		if err != nil {
+			return nil, err
+		}
+		if eq == core.True {
+			return core.Null, nil
+		}
+
+		return l, nil
+	}
+}
+
 // BinOpNode is expression of BinOpNode
 type BinOpNode struct {
 	Op    MathOp
@@ -228,6 +521,17 @@ func (e BinOpNode) Eval() func(backend.Row) (core.Value, error) {
 		if l == core.Null || r == core.Null {
 			return core.Null, nil
 		}
+		if e.Op != CONCAT && !isPatternOp(e.Op) {
+			if l, r, err = coerceLiterals(e.Lexpr, e.Rexpr, l, r); err != nil {
+				return nil, err
+			}
+		}
+
+		_, lIsDate := l.(core.DateType)
+		_, rIsDate := r.(core.DateType)
+		if (lIsDate || rIsDate) && e.Op != CONCAT {
+			return compDate(e.Op, l, r)
+		}
 
 		switch e.Op {
 		case EqualOp:
@@ -238,6 +542,8 @@ func (e BinOpNode) Eval() func(backend.Row) (core.Value, error) {
 			lStr := fmt.Sprintf("%v", l)
 			rStr := fmt.Sprintf("%v", r)
 			return lStr + rStr, nil
+		case Like, NotLike, ILike, NotILike, RegexMatch, RegexIMatch, NotRegexMatch, NotRegexIMatch:
+			return matchPattern(e.Op, l, r)
 		}
 
 		if reflect.ValueOf(l).Kind() == reflect.Int {
@@ -371,9 +677,272 @@ func compStrStr(op MathOp, l core.Value, r core.Value) core.Value {
 	return nil
 }
 
+// compDate compares dates, and adds or subtracts days to or from a date.
+// The difference of dates is the number of days.
+func compDate(op MathOp, l core.Value, r core.Value) (core.Value, error) {
+	ld, lIsDate := l.(core.DateType)
+	rd, rIsDate := r.(core.DateType)
+	ri, rIsInt := r.(int)
+	switch {
+	case lIsDate && rIsDate:
+		switch op {
+		case Minus:
+			return ld.Days - rd.Days, nil
+		case EqualOp:
+			return toSQLBool(ld == rd), nil
+		case NotEqualOp:
+			return toSQLBool(ld != rd), nil
+		case GT, LT, GEQ, LEQ:
+			return compIntInt(op, ld.Days, rd.Days), nil
+		}
+	case lIsDate && rIsInt:
+		switch op {
+		case Plus:
+			return core.DateType{Days: ld.Days + ri}, nil
+		case Minus:
+			return core.DateType{Days: ld.Days - ri}, nil
+		}
+	case rIsDate && op == Plus:
+		if li, ok := l.(int); ok {
+			return core.DateType{Days: li + rd.Days}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ERROR:  operator does not exist: %v %v %v", core.TypeName(l), mathOpSymbols[op], core.TypeName(r))
+}
+
+// coerceLiterals converts string literals operated with values of other types to the types,
+// as PostgreSQL resolves the type of an unknown-type literal such as '1' of 1 = '1'.
+func coerceLiterals(lexpr, rexpr ExpressionNode, l, r core.Value) (core.Value, core.Value, error) {
+	l, err := coerceLiteral(lexpr, l, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err = coerceLiteral(rexpr, r, l)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return l, r, nil
+}
+
+// coerceLiteral converts v to the type of other if expr is a string literal
+func coerceLiteral(expr ExpressionNode, v, other core.Value) (core.Value, error) {
+	if _, ok := expr.(StringNode); !ok {
+		return v, nil
+	}
+
+	switch other.(type) {
+	case int:
+		return castToInteger(v, integerCastType)
+	case float64:
+		return castToFloat(v, castTypes["float8"], nil)
+	case core.BoolType:
+		return castToBoolean(v, castTypes["bool"])
+	case core.DateType:
+		return castToDate(v, castTypes["date"])
+	}
+
+	return v, nil
+}
+
 func toSQLBool(b bool) core.BoolType {
 	if b {
 		return core.True
 	}
 	return core.False
 }
+
+// AggCallNode is expression of an aggregate function call such as count(*) and sum(x).
+// It is replaced with a reference to a column of AggregateNode when the query is translated.
+type AggCallNode struct {
+	FuncName string
+	Args     []ExpressionNode
+	// Star is true for count(*)
+	Star bool
+	// Distinct is true if duplicated arguments are aggregated only once
+	Distinct bool
+	// Filter is the condition of FILTER (WHERE ...)
+	Filter ExpressionNode
+	// OrderBy is the order in which arguments are aggregated
+	OrderBy []SortKey
+}
+
+// Eval evaluates AggCallNode. An aggregate function can't be evaluated for a single row,
+// so it reaches here only when it's used where aggregate functions are not allowed.
+func (a *AggCallNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(backend.Row) (core.Value, error) {
+		return nil, errors.New("ERROR:  aggregate functions are not allowed in this context")
+	}
+}
+
+// GroupingFuncNode is expression of GROUPING(...). It returns a bit mask whose bit is set
+// if the corresponding argument is not grouped in the current grouping set.
+// The last argument corresponds to the least significant bit.
+type GroupingFuncNode struct {
+	Args []ExpressionNode
+}
+
+// Eval evaluates GroupingFuncNode. It reaches here only when it's used where grouping operations are not allowed.
+func (g *GroupingFuncNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(backend.Row) (core.Value, error) {
+		return nil, errors.New("ERROR:  grouping operations are not allowed in this context")
+	}
+}
+
+// transformExpr makes a copy of expr in which sub-expressions are replaced by fn.
+// If fn returns true, the sub-expression is replaced with the returned expression
+// and its children are not visited.
+func transformExpr(expr ExpressionNode, fn func(ExpressionNode) (ExpressionNode, bool)) ExpressionNode {
+	if expr == nil {
+		return nil
+	}
+	if e, ok := fn(expr); ok {
+		return e
+	}
+
+	tr := func(e ExpressionNode) ExpressionNode {
+		return transformExpr(e, fn)
+	}
+	trList := func(es []ExpressionNode) []ExpressionNode {
+		if es == nil {
+			return nil
+		}
+		res := make([]ExpressionNode, 0, len(es))
+		for _, e := range es {
+			res = append(res, tr(e))
+		}
+		return res
+	}
+
+	switch e := expr.(type) {
+	case BinOpNode:
+		return BinOpNode{Op: e.Op, Lexpr: tr(e.Lexpr), Rexpr: tr(e.Rexpr)}
+	case *BinOpNode:
+		return &BinOpNode{Op: e.Op, Lexpr: tr(e.Lexpr), Rexpr: tr(e.Rexpr)}
+	case ANDNode:
+		return ANDNode{Lexpr: tr(e.Lexpr), Rexpr: tr(e.Rexpr)}
+	case *ANDNode:
+		return &ANDNode{Lexpr: tr(e.Lexpr), Rexpr: tr(e.Rexpr)}
+	case ORNode:
+		return ORNode{Lexpr: tr(e.Lexpr), Rexpr: tr(e.Rexpr)}
+	case *ORNode:
+		return &ORNode{Lexpr: tr(e.Lexpr), Rexpr: tr(e.Rexpr)}
+	case NotNode:
+		return NotNode{Expr: tr(e.Expr)}
+	case *NotNode:
+		return &NotNode{Expr: tr(e.Expr)}
+	case NullTestNode:
+		return NullTestNode{TestType: e.TestType, Expr: tr(e.Expr)}
+	case *NullTestNode:
+		return &NullTestNode{TestType: e.TestType, Expr: tr(e.Expr)}
+	case *BoolTestNode:
+		return &BoolTestNode{TestType: e.TestType, Expr: tr(e.Expr)}
+	case *TypeCastNode:
+		return &TypeCastNode{Expr: tr(e.Expr), TypeName: e.TypeName, TypeMods: e.TypeMods}
+	case *DistinctFromNode:
+		return &DistinctFromNode{Not: e.Not, Lexpr: tr(e.Lexpr), Rexpr: tr(e.Rexpr)}
+	case *CaseNode:
+		return &CaseNode{
+			CaseWhenExprs:   trList(e.CaseWhenExprs),
+			CaseResultExprs: trList(e.CaseResultExprs),
+			DefaultResult:   tr(e.DefaultResult),
+		}
+	case *CoalesceNode:
+		return &CoalesceNode{Args: trList(e.Args)}
+	case *NullIfNode:
+		return &NullIfNode{Lexpr: tr(e.Lexpr), Rexpr: tr(e.Rexpr)}
+	case *AggCallNode:
+		var orderBy []SortKey
+		for _, key := range e.OrderBy {
+			orderBy = append(orderBy, SortKey{Expr: tr(key.Expr), Desc: key.Desc, NullsFirst: key.NullsFirst})
+		}
+		return &AggCallNode{
+			FuncName: e.FuncName,
+			Args:     trList(e.Args),
+			Star:     e.Star,
+			Distinct: e.Distinct,
+			Filter:   tr(e.Filter),
+			OrderBy:  orderBy,
+		}
+	case *GroupingFuncNode:
+		return &GroupingFuncNode{Args: trList(e.Args)}
+	case *GroupedAliasNode:
+		return &GroupedAliasNode{Key: e.Key, Expr: tr(e.Expr)}
+	case *FuncCallNode:
+		return &FuncCallNode{FuncName: e.FuncName, Args: trList(e.Args)}
+	case *SetReturningFuncNode:
+		return &SetReturningFuncNode{FuncName: e.FuncName, Args: trList(e.Args)}
+	case *WindowFuncNode:
+		return &WindowFuncNode{
+			FuncName:   e.FuncName,
+			Args:       trList(e.Args),
+			Star:       e.Star,
+			Filter:     tr(e.Filter),
+			WindowName: e.WindowName,
+			Window:     transformWindowDef(e.Window, tr),
+		}
+	case *SubLinkNode:
+		// the subquery is a separate query, so only the test expression is visited
+		return &SubLinkNode{Type: e.Type, Testexpr: tr(e.Testexpr), Op: e.Op, Subquery: e.Subquery}
+	}
+
+	return expr
+}
+
+func transformWindowDef(def *WindowDef, tr func(ExpressionNode) ExpressionNode) *WindowDef {
+	if def == nil {
+		return nil
+	}
+
+	partitionBy := make([]ExpressionNode, 0, len(def.PartitionBy))
+	for _, expr := range def.PartitionBy {
+		partitionBy = append(partitionBy, tr(expr))
+	}
+	var orderBy []SortKey
+	for _, key := range def.OrderBy {
+		orderBy = append(orderBy, SortKey{Expr: tr(key.Expr), Desc: key.Desc, NullsFirst: key.NullsFirst})
+	}
+	var frame *WindowFrame
+	if def.Frame != nil {
+		f := *def.Frame
+		f.Start.Offset, f.End.Offset = tr(f.Start.Offset), tr(f.End.Offset)
+		frame = &f
+	}
+
+	return &WindowDef{RefName: def.RefName, PartitionBy: partitionBy, OrderBy: orderBy, Frame: frame}
+}
+
+// findExpr reports whether expr has a sub-expression which satisfies pred
+func findExpr(expr ExpressionNode, pred func(ExpressionNode) bool) bool {
+	found := false
+	transformExpr(expr, func(e ExpressionNode) (ExpressionNode, bool) {
+		if pred(e) {
+			found = true
+			return e, true
+		}
+		return nil, false
+	})
+
+	return found
+}
+
+func isAggCall(expr ExpressionNode) bool {
+	_, ok := expr.(*AggCallNode)
+	return ok
+}
+
+func isGroupingFunc(expr ExpressionNode) bool {
+	_, ok := expr.(*GroupingFuncNode)
+	return ok
+}
+
+func isWindowFunc(expr ExpressionNode) bool {
+	_, ok := expr.(*WindowFuncNode)
+	return ok
+}
+
+func isSetReturningFunc(expr ExpressionNode) bool {
+	_, ok := expr.(*SetReturningFuncNode)
+	return ok
+}
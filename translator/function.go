@@ -0,0 +1,561 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+)
+
+// FuncCallNode is expression of a scalar function call such as lower(x).
+// The function is looked up in scalarFuncs by its name and the types of the arguments when it's evaluated.
+type FuncCallNode struct {
+	FuncName string
+	Args     []ExpressionNode
+}
+
+// Eval evaluates FuncCallNode
+func (f *FuncCallNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		args := make(core.Values, 0, len(f.Args))
+		for _, arg := range f.Args {
+			v, err := arg.Eval()(row)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, nullIfNil(v))
+		}
+
+		fn, ok := lookupFunc(f.FuncName, args)
+		if !ok {
+			typeNames := make([]string, 0, len(args))
+			for _, arg := range args {
+				typeNames = append(typeNames, core.TypeName(arg))
+			}
+			return nil, fmt.Errorf("ERROR:  function %v(%v) does not exist", f.FuncName, strings.Join(typeNames, ", "))
+		}
+		if fn.volatile {
+			markVolatile(row)
+		}
+		if fn.strict {
+			for _, arg := range args {
+				if arg == core.Null {
+					return core.Null, nil
+				}
+			}
+		}
+		if fn.callDB != nil {
+			s, ok := row.(*scopedRow)
+			if !ok {
+				return nil, fmt.Errorf("ERROR:  cannot use %v() in this context", f.FuncName)
+			}
+			return fn.callDB(dbOf(s.db), args)
+		}
+
+		return fn.call(args)
+	}
+}
+
+// argType is a type of an argument of a scalar function
+type argType int
+
+const (
+	anyArg argType = iota
+	textArg
+	intArg
+	// numericArg is an integer or a floating point number
+	numericArg
+	boolArg
+)
+
+// accepts reports whether v can be given as the argument. NULL is accepted as any type.
+func (t argType) accepts(v core.Value) bool {
+	if v == core.Null {
+		return true
+	}
+
+	switch t {
+	case textArg:
+		_, ok := v.(string)
+		return ok
+	case intArg:
+		_, ok := v.(int)
+		return ok
+	case numericArg:
+		switch v.(type) {
+		case int, float64:
+			return true
+		}
+		return false
+	case boolArg:
+		_, ok := v.(core.BoolType)
+		return ok
+	}
+
+	return true
+}
+
+type scalarFunc struct {
+	args []argType
+	// variadic functions accept any number of the last argument
+	variadic bool
+	// strict functions return NULL without being called if an argument is NULL
+	strict bool
+	// volatile functions may return a different result for the same arguments
+	volatile bool
+	call     func(args core.Values) (core.Value, error)
+	// callDB is called instead of call by functions which read the database such as pg_table_is_visible
+	callDB func(db backend.DB, args core.Values) (core.Value, error)
+}
+
+func (f scalarFunc) accepts(args core.Values) bool {
+	if len(args) < len(f.args) || (!f.variadic && len(args) > len(f.args)) {
+		return false
+	}
+	for k, arg := range args {
+		t := f.args[len(f.args)-1]
+		if k < len(f.args) {
+			t = f.args[k]
+		}
+		if !t.accepts(arg) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scalarFuncs are built-in scalar functions. A function can be overloaded by the types of its arguments,
+// and the first one which accepts the arguments is called.
+var scalarFuncs = map[string][]scalarFunc{
+	"lower":               {{args: []argType{textArg}, strict: true, call: lower}},
+	"upper":               {{args: []argType{textArg}, strict: true, call: upper}},
+	"length":              {{args: []argType{textArg}, strict: true, call: length}},
+	"substring":           {{args: []argType{textArg, intArg}, strict: true, call: substring}, {args: []argType{textArg, intArg, intArg}, strict: true, call: substring}},
+	"position":            {{args: []argType{textArg, textArg}, strict: true, call: position}},
+	"btrim":               {{args: []argType{textArg}, strict: true, call: trim(strings.Trim)}, {args: []argType{textArg, textArg}, strict: true, call: trim(strings.Trim)}},
+	"ltrim":               {{args: []argType{textArg}, strict: true, call: trim(strings.TrimLeft)}, {args: []argType{textArg, textArg}, strict: true, call: trim(strings.TrimLeft)}},
+	"rtrim":               {{args: []argType{textArg}, strict: true, call: trim(strings.TrimRight)}, {args: []argType{textArg, textArg}, strict: true, call: trim(strings.TrimRight)}},
+	"replace":             {{args: []argType{textArg, textArg, textArg}, strict: true, call: replace}},
+	"split_part":          {{args: []argType{textArg, textArg, intArg}, strict: true, call: splitPart}},
+	"concat":              {{args: []argType{anyArg}, variadic: true, call: concat}},
+	"format":              {{args: []argType{textArg}, call: format}, {args: []argType{textArg, anyArg}, variadic: true, call: format}},
+	"abs":                 {{args: []argType{intArg}, strict: true, call: absInt}, {args: []argType{numericArg}, strict: true, call: mathFunc(math.Abs)}},
+	"round":               {{args: []argType{intArg}, strict: true, call: identity}, {args: []argType{numericArg}, strict: true, call: mathFunc(math.Round)}, {args: []argType{numericArg, intArg}, strict: true, call: roundScale}},
+	"ceil":                {{args: []argType{intArg}, strict: true, call: identity}, {args: []argType{numericArg}, strict: true, call: mathFunc(math.Ceil)}},
+	"ceiling":             {{args: []argType{intArg}, strict: true, call: identity}, {args: []argType{numericArg}, strict: true, call: mathFunc(math.Ceil)}},
+	"floor":               {{args: []argType{intArg}, strict: true, call: identity}, {args: []argType{numericArg}, strict: true, call: mathFunc(math.Floor)}},
+	"mod":                 {{args: []argType{intArg, intArg}, strict: true, call: modInt}, {args: []argType{numericArg, numericArg}, strict: true, call: modFloat}},
+	"power":               {{args: []argType{numericArg, numericArg}, strict: true, call: power}},
+	"pow":                 {{args: []argType{numericArg, numericArg}, strict: true, call: power}},
+	"sqrt":                {{args: []argType{numericArg}, strict: true, call: sqrt}},
+	"random":              {{volatile: true, call: random}},
+	"greatest":            {{args: []argType{anyArg}, variadic: true, call: extreme(1)}},
+	"least":               {{args: []argType{anyArg}, variadic: true, call: extreme(-1)}},
+	"like_escape":         {{args: []argType{textArg, textArg}, strict: true, call: likeEscape}},
+	"similar_to_escape":   {{args: []argType{textArg}, strict: true, call: similarToEscape}, {args: []argType{textArg, textArg}, strict: true, call: similarToEscape}},
+	"pg_table_is_visible": {{args: []argType{intArg}, strict: true, callDB: pgTableIsVisible}},
+	"pg_get_userbyid":     {{args: []argType{intArg}, strict: true, call: pgGetUserByID}},
+	"format_type":         {{args: []argType{intArg, intArg}, call: formatType}},
+	"pg_get_expr":         {{args: []argType{textArg, intArg}, strict: true, call: pgGetExpr}, {args: []argType{textArg, intArg, boolArg}, strict: true, call: pgGetExpr}},
+	"regexp_replace":      {{args: []argType{textArg, textArg, textArg}, strict: true, call: regexpReplace}, {args: []argType{textArg, textArg, textArg, textArg}, strict: true, call: regexpReplace}},
+}
+
+func lookupFunc(name string, args core.Values) (scalarFunc, bool) {
+	for _, fn := range scalarFuncs[name] {
+		if fn.accepts(args) {
+			return fn, true
+		}
+	}
+
+	return scalarFunc{}, false
+}
+
+// textOf returns the text representation of v as PostgreSQL outputs it
+func textOf(v core.Value) string {
+	switch v {
+	case core.True:
+		return "t"
+	case core.False:
+		return "f"
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+func lower(args core.Values) (core.Value, error) {
+	return strings.ToLower(args[0].(string)), nil
+}
+
+func upper(args core.Values) (core.Value, error) {
+	return strings.ToUpper(args[0].(string)), nil
+}
+
+// length is the number of characters
+func length(args core.Values) (core.Value, error) {
+	return utf8.RuneCountInString(args[0].(string)), nil
+}
+
+// substring takes count characters from the start-th character. The first character is at 1.
+// The rest of the string is taken if count is omitted.
+func substring(args core.Values) (core.Value, error) {
+	s := []rune(args[0].(string))
+	start := args[1].(int) - 1
+	end := len(s)
+	if len(args) > 2 {
+		count := args[2].(int)
+		if count < 0 {
+			return nil, errors.New("ERROR:  negative substring length not allowed")
+		}
+		if start+count < end {
+			end = start + count
+		}
+	}
+	start = clamp(start, 0, len(s))
+	end = clamp(end, start, len(s))
+
+	return string(s[start:end]), nil
+}
+
+// position is the position of the first occurrence of the substring, or zero if it's not found.
+// `position(sub IN str)` is given as position(str, sub).
+func position(args core.Values) (core.Value, error) {
+	str, sub := args[0].(string), args[1].(string)
+	idx := strings.Index(str, sub)
+	if idx < 0 {
+		return 0, nil
+	}
+
+	return utf8.RuneCountInString(str[:idx]) + 1, nil
+}
+
+// trim makes btrim, ltrim and rtrim, which remove the given characters or spaces.
+// TRIM([BOTH | LEADING | TRAILING] [chars] FROM str) is given as one of them.
+func trim(fn func(string, string) string) func(core.Values) (core.Value, error) {
+	return func(args core.Values) (core.Value, error) {
+		chars := " "
+		if len(args) > 1 {
+			chars = args[1].(string)
+		}
+		return fn(args[0].(string), chars), nil
+	}
+}
+
+func replace(args core.Values) (core.Value, error) {
+	str, from, to := args[0].(string), args[1].(string), args[2].(string)
+	if from == "" {
+		return str, nil
+	}
+
+	return strings.ReplaceAll(str, from, to), nil
+}
+
+// splitPart splits the string by the delimiter and returns the n-th field. The first field is at 1.
+func splitPart(args core.Values) (core.Value, error) {
+	str, delim, n := args[0].(string), args[1].(string), args[2].(int)
+	if n <= 0 {
+		return nil, errors.New("ERROR:  field position must be greater than zero")
+	}
+	fields := []string{str}
+	if delim != "" {
+		fields = strings.Split(str, delim)
+	}
+	if n > len(fields) {
+		return "", nil
+	}
+
+	return fields[n-1], nil
+}
+
+// concat concatenates the text representations of the arguments. NULLs are ignored.
+func concat(args core.Values) (core.Value, error) {
+	var sb strings.Builder
+	for _, arg := range args {
+		if arg != core.Null {
+			sb.WriteString(textOf(arg))
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// format formats the arguments by the format string like sprintf.
+// A format specifier is %[position$][-][width]type, and the type is one of s, I and L.
+// %s is the text representation, %I quotes an identifier and %L quotes a literal. %% is %.
+func format(args core.Values) (core.Value, error) {
+	if args[0] == core.Null {
+		return core.Null, nil
+	}
+	fmtStr := []rune(args[0].(string))
+	values := args[1:]
+
+	var sb strings.Builder
+	next := 0
+	for i := 0; i < len(fmtStr); i++ {
+		if fmtStr[i] != '%' {
+			sb.WriteRune(fmtStr[i])
+			continue
+		}
+		i++
+		if i >= len(fmtStr) {
+			return nil, errors.New("ERROR:  unterminated format() type specifier")
+		}
+		if fmtStr[i] == '%' {
+			sb.WriteRune('%')
+			continue
+		}
+
+		// %[position$][-][width]type
+		j := i
+		if n, k, ok := parseNumber(fmtStr, j); ok && k < len(fmtStr) && fmtStr[k] == '$' {
+			if n == 0 {
+				return nil, errors.New("ERROR:  format specifies argument 0, but arguments are numbered from 1")
+			}
+			next = n - 1
+			j = k + 1
+		}
+		leftAlign := j < len(fmtStr) && fmtStr[j] == '-'
+		if leftAlign {
+			j++
+		}
+		width, j, _ := parseNumber(fmtStr, j)
+		if j >= len(fmtStr) {
+			return nil, errors.New("ERROR:  unterminated format() type specifier")
+		}
+		i = j
+
+		typ := fmtStr[i]
+		if typ != 's' && typ != 'I' && typ != 'L' {
+			return nil, fmt.Errorf("ERROR:  unrecognized format() type specifier \"%c\"", typ)
+		}
+		if next >= len(values) {
+			return nil, errors.New("ERROR:  too few arguments for format()")
+		}
+		v := values[next]
+		next++
+
+		var str string
+		switch {
+		case typ == 's':
+			if v != core.Null {
+				str = textOf(v)
+			}
+		case typ == 'I':
+			if v == core.Null {
+				return nil, errors.New("ERROR:  null values cannot be formatted as an SQL identifier")
+			}
+			str = quoteIdent(textOf(v))
+		default:
+			str = "NULL"
+			if v != core.Null {
+				str = quoteLiteral(textOf(v))
+			}
+		}
+		if pad := width - utf8.RuneCountInString(str); pad > 0 && leftAlign {
+			str += strings.Repeat(" ", pad)
+		} else if pad > 0 {
+			str = strings.Repeat(" ", pad) + str
+		}
+		sb.WriteString(str)
+	}
+
+	return sb.String(), nil
+}
+
+// parseNumber parses digits from s[i:]. It returns the number and the position after the digits.
+func parseNumber(s []rune, i int) (int, int, bool) {
+	start := i
+	n := 0
+	for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+		n = n*10 + int(s[i]-'0')
+	}
+
+	return n, i, i > start
+}
+
+// quoteIdent quotes the identifier if it's necessary
+func quoteIdent(s string) string {
+	safe := s != ""
+	for k, c := range s {
+		if !(c == '_' || ('a' <= c && c <= 'z') || (k > 0 && '0' <= c && c <= '9')) {
+			safe = false
+		}
+	}
+	if safe {
+		return s
+	}
+
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes the string as a string literal. A string which has backslashes is quoted as E'...'.
+func quoteLiteral(s string) string {
+	quoted := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if strings.Contains(s, `\`) {
+		return "E" + strings.ReplaceAll(quoted, `\`, `\\`)
+	}
+
+	return quoted
+}
+
+func identity(args core.Values) (core.Value, error) {
+	return args[0], nil
+}
+
+func absInt(args core.Values) (core.Value, error) {
+	if v := args[0].(int); v < 0 {
+		return -v, nil
+	}
+
+	return args[0], nil
+}
+
+// mathFunc makes a function of a floating point number. An integer argument is converted to it.
+func mathFunc(fn func(float64) float64) func(core.Values) (core.Value, error) {
+	return func(args core.Values) (core.Value, error) {
+		return fn(toFloat(args[0])), nil
+	}
+}
+
+// roundScale rounds the number to the given number of decimal places.
+// A negative scale rounds it to the left of the decimal point.
+func roundScale(args core.Values) (core.Value, error) {
+	scale := math.Pow10(args[1].(int))
+	if v, ok := args[0].(int); ok {
+		if scale >= 1 {
+			return v, nil
+		}
+		return int(math.Round(float64(v)*scale) / scale), nil
+	}
+
+	return math.Round(args[0].(float64)*scale) / scale, nil
+}
+
+func modInt(args core.Values) (core.Value, error) {
+	if args[1].(int) == 0 {
+		return nil, errors.New("ERROR:  division by zero")
+	}
+
+	return args[0].(int) % args[1].(int), nil
+}
+
+func modFloat(args core.Values) (core.Value, error) {
+	if toFloat(args[1]) == 0 {
+		return nil, errors.New("ERROR:  division by zero")
+	}
+
+	return math.Mod(toFloat(args[0]), toFloat(args[1])), nil
+}
+
+func power(args core.Values) (core.Value, error) {
+	x, y := toFloat(args[0]), toFloat(args[1])
+	if x == 0 && y < 0 {
+		return nil, errors.New("ERROR:  zero raised to a negative power is undefined")
+	}
+	if x < 0 && y != math.Trunc(y) {
+		return nil, errors.New("ERROR:  a negative number raised to a non-integer power yields a complex result")
+	}
+
+	return math.Pow(x, y), nil
+}
+
+func sqrt(args core.Values) (core.Value, error) {
+	x := toFloat(args[0])
+	if x < 0 {
+		return nil, errors.New("ERROR:  cannot take square root of a negative number")
+	}
+
+	return math.Sqrt(x), nil
+}
+
+// random returns a random number in the range 0.0 <= x < 1.0
+func random(args core.Values) (core.Value, error) {
+	return rand.Float64(), nil
+}
+
+// extreme makes greatest and least, which ignore NULLs.
+// sign is 1 for greatest and -1 for least.
+func extreme(sign int) func(core.Values) (core.Value, error) {
+	return func(args core.Values) (core.Value, error) {
+		var res core.Value = core.Null
+		for _, arg := range args {
+			if arg == core.Null {
+				continue
+			}
+			if res == core.Null {
+				res = arg
+				continue
+			}
+			c, err := core.Compare(arg, res)
+			if err != nil {
+				return nil, err
+			}
+			if c*sign > 0 {
+				res = arg
+			}
+		}
+		return res, nil
+	}
+}
+
+// pgTableIsVisible reports whether the relation is found by its unqualified name,
+// that is, it isn't hidden by another relation in a schema which is searched earlier.
+// NULL is returned if the relation doesn't exist.
+func pgTableIsVisible(db backend.DB, args core.Values) (core.Value, error) {
+	pgClass, err := db.GetTable(backend.CatalogSchema + ".pg_class")
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range pgClass.GetRows() {
+		oid, err := row.GetValueByColName(core.ColumnName{TableName: "pg_class", Name: "oid"})
+		if err != nil {
+			return nil, err
+		}
+		if oid != args[0] {
+			continue
+		}
+		name, err := row.GetValueByColName(core.ColumnName{TableName: "pg_class", Name: "relname"})
+		if err != nil {
+			return nil, err
+		}
+		namespace, err := row.GetValueByColName(core.ColumnName{TableName: "pg_class", Name: "relnamespace"})
+		if err != nil {
+			return nil, err
+		}
+		tb, err := db.GetTable(name.(string))
+		if err != nil {
+			return core.False, nil
+		}
+		return toSQLBool(backend.TableNamespace(tb) == namespace), nil
+	}
+
+	return core.Null, nil
+}
+
+// pgGetUserByID returns the name of the role
+func pgGetUserByID(args core.Values) (core.Value, error) {
+	if args[0] == backend.OwnerOID {
+		return backend.OwnerName, nil
+	}
+
+	return fmt.Sprintf("unknown (OID=%v)", args[0]), nil
+}
+
+// pgGetExpr returns the text of the expression of pg_attrdef.adbin, which is already SQL text
+func pgGetExpr(args core.Values) (core.Value, error) {
+	return args[0], nil
+}
+
+// formatType returns the SQL name of the type. The type modifier is ignored because it's always -1.
+func formatType(args core.Values) (core.Value, error) {
+	if args[0] == core.Null {
+		return core.Null, nil
+	}
+
+	return backend.FormatType(args[0].(int)), nil
+}
@@ -0,0 +1,342 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goropikari/psqlittle/core"
+)
+
+// isPatternOp reports whether op is LIKE, ILIKE or a POSIX regular expression match operator
+func isPatternOp(op MathOp) bool {
+	switch op {
+	case Like, NotLike, ILike, NotILike, RegexMatch, RegexIMatch, NotRegexMatch, NotRegexIMatch:
+		return true
+	}
+
+	return false
+}
+
+// matchPattern evaluates LIKE, ILIKE and POSIX regular expression match operators.
+// The pattern of LIKE has already been converted to backslash escape form by like_escape
+// if ESCAPE clause is given, and the pattern of SIMILAR TO to a regular expression by similar_to_escape.
+func matchPattern(op MathOp, l, r core.Value) (core.Value, error) {
+	str, lok := l.(string)
+	pattern, rok := r.(string)
+	if !lok || !rok {
+		return nil, fmt.Errorf("ERROR:  operator does not exist: %v %v %v", core.TypeName(l), mathOpSymbols[op], core.TypeName(r))
+	}
+
+	flags := ""
+	switch op {
+	case Like, NotLike, ILike, NotILike:
+		expr, err := likeToRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		pattern = expr
+	}
+	switch op {
+	case ILike, NotILike, RegexIMatch, NotRegexIMatch:
+		flags = "i"
+	}
+
+	re, _, err := compileRegexp(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	matched := re.MatchString(str)
+	switch op {
+	case NotLike, NotILike, NotRegexMatch, NotRegexIMatch:
+		matched = !matched
+	}
+
+	return toSQLBool(matched), nil
+}
+
+// likeToRegexp converts a LIKE pattern whose escape character is backslash to an anchored regular expression
+func likeToRegexp(pattern string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch rs[i] {
+		case '\\':
+			i++
+			if i == len(rs) {
+				return "", errors.New("ERROR:  LIKE pattern must not end with escape character")
+			}
+			sb.WriteString(regexp.QuoteMeta(string(rs[i])))
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(rs[i])))
+		}
+	}
+	sb.WriteString("$")
+
+	return sb.String(), nil
+}
+
+// compileRegexp compiles a regular expression with flags of regexp_replace and so on.
+// It reports whether the flags contain g (global) too.
+// As in PostgreSQL, dot matches a newline.
+func compileRegexp(pattern, flags string) (*regexp.Regexp, bool, error) {
+	global := false
+	insensitive := false
+	for _, c := range flags {
+		switch c {
+		case 'g':
+			global = true
+		case 'i':
+			insensitive = true
+		case 'c':
+			insensitive = false
+		default:
+			return nil, false, fmt.Errorf("ERROR:  invalid regular expression option: \"%c\"", c)
+		}
+	}
+
+	prefix := "(?s)"
+	if insensitive {
+		prefix = "(?si)"
+	}
+	re, err := regexp.Compile(prefix + pattern)
+	if err != nil {
+		return nil, false, fmt.Errorf("ERROR:  invalid regular expression: %v", err)
+	}
+
+	return re, global, nil
+}
+
+// likeEscape converts a LIKE pattern with the given escape character to the one escaped by backslash.
+// An empty escape string disables escaping.
+func likeEscape(args core.Values) (core.Value, error) {
+	pattern, esc := args[0].(string), args[1].(string)
+	if esc == "" {
+		return strings.ReplaceAll(pattern, `\`, `\\`), nil
+	}
+	if utf8.RuneCountInString(esc) != 1 {
+		return nil, errors.New("ERROR:  invalid escape string")
+	}
+	escRune, _ := utf8.DecodeRuneInString(esc)
+
+	var sb strings.Builder
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch {
+		case rs[i] == escRune:
+			i++
+			if i == len(rs) {
+				return nil, errors.New("ERROR:  LIKE pattern must not end with escape character")
+			}
+			sb.WriteRune('\\')
+			sb.WriteRune(rs[i])
+		case rs[i] == '\\':
+			sb.WriteString(`\\`)
+		default:
+			sb.WriteRune(rs[i])
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// similarToEscape converts a SIMILAR TO pattern to an anchored regular expression.
+// The escape character is backslash by default, and an empty escape string disables escaping.
+func similarToEscape(args core.Values) (core.Value, error) {
+	pattern := args[0].(string)
+	esc := `\`
+	if len(args) == 2 {
+		esc = args[1].(string)
+	}
+	if utf8.RuneCountInString(esc) > 1 {
+		return nil, errors.New("ERROR:  invalid escape string")
+	}
+	escRune := rune(-1)
+	if esc != "" {
+		escRune, _ = utf8.DecodeRuneInString(esc)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^(?:")
+	inBracket := false
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		switch {
+		case c == escRune:
+			i++
+			if i == len(rs) {
+				return nil, errors.New("ERROR:  SIMILAR TO pattern must not end with escape character")
+			}
+			sb.WriteString(regexp.QuoteMeta(string(rs[i])))
+		case inBracket:
+			if c == ']' {
+				inBracket = false
+			}
+			if c == '\\' {
+				sb.WriteString(`\\`)
+			} else {
+				sb.WriteRune(c)
+			}
+		case c == '[':
+			inBracket = true
+			sb.WriteRune(c)
+		case c == '%':
+			sb.WriteString(".*")
+		case c == '_':
+			sb.WriteString(".")
+		case c == '(':
+			sb.WriteString("(?:")
+		case c == '.' || c == '^' || c == '$' || c == '\\':
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	sb.WriteString(")$")
+
+	return sb.String(), nil
+}
+
+// regexpReplace replaces the first or all (with g flag) matches of a regular expression.
+// \1 to \9 in the replacement refer to the parenthesized subexpressions, and \& refers to the whole match.
+func regexpReplace(args core.Values) (core.Value, error) {
+	src, pattern, repl := args[0].(string), args[1].(string), args[2].(string)
+	flags := ""
+	if len(args) == 4 {
+		flags = args[3].(string)
+	}
+	re, global, err := compileRegexp(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	n := 1
+	if global {
+		n = -1
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(src, n) {
+		sb.WriteString(src[last:m[0]])
+		sb.WriteString(expandReplacement(src, repl, m))
+		last = m[1]
+	}
+	sb.WriteString(src[last:])
+
+	return sb.String(), nil
+}
+
+func expandReplacement(src, repl string, match []int) string {
+	var sb strings.Builder
+	for i := 0; i < len(repl); i++ {
+		if repl[i] != '\\' || i+1 == len(repl) {
+			sb.WriteByte(repl[i])
+			continue
+		}
+		i++
+		c := repl[i]
+		switch {
+		case c >= '1' && c <= '9':
+			k, _ := strconv.Atoi(string(c))
+			if 2*k+1 < len(match) && match[2*k] >= 0 {
+				sb.WriteString(src[match[2*k]:match[2*k+1]])
+			}
+		case c == '&':
+			sb.WriteString(src[match[0]:match[1]])
+		case c == '\\':
+			sb.WriteByte('\\')
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		}
+	}
+
+	return sb.String()
+}
+
+// regexpArgs validates the arguments of regexp_matches and regexp_split_to_table.
+// It reports false if any of them is NULL.
+func regexpArgs(name string, args core.Values) (string, *regexp.Regexp, bool, bool, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", nil, false, false, fmt.Errorf("ERROR:  function %v must have 2 or 3 arguments", name)
+	}
+	strs := make([]string, 0, len(args))
+	for _, arg := range args {
+		if isNull(arg) {
+			return "", nil, false, false, nil
+		}
+		s, ok := arg.(string)
+		if !ok {
+			return "", nil, false, false, fmt.Errorf("ERROR:  function %v does not exist", name)
+		}
+		strs = append(strs, s)
+	}
+	flags := ""
+	if len(strs) == 3 {
+		flags = strs[2]
+	}
+	re, global, err := compileRegexp(strs[1], flags)
+	if err != nil {
+		return "", nil, false, false, err
+	}
+
+	return strs[0], re, global, true, nil
+}
+
+// regexpMatches returns the captured substrings of the first or all (with g flag) matches as text arrays.
+// If the pattern has no parenthesized subexpressions, the array has the whole match.
+func regexpMatches(args core.Values) (core.Values, core.ColType, error) {
+	src, re, global, ok, err := regexpArgs("regexp_matches", args)
+	if err != nil || !ok {
+		return core.Values{}, core.VarChar, err
+	}
+
+	n := 1
+	if global {
+		n = -1
+	}
+	vals := make(core.Values, 0)
+	for _, m := range re.FindAllStringSubmatchIndex(src, n) {
+		elems := make([]string, 0, re.NumSubexp())
+		if re.NumSubexp() == 0 {
+			elems = append(elems, arrayElement(src[m[0]:m[1]]))
+		}
+		for k := 1; k <= re.NumSubexp(); k++ {
+			if m[2*k] < 0 {
+				elems = append(elems, arrayElement(core.Null))
+			} else {
+				elems = append(elems, arrayElement(src[m[2*k]:m[2*k+1]]))
+			}
+		}
+		vals = append(vals, "{"+strings.Join(elems, ",")+"}")
+	}
+
+	return vals, core.VarChar, nil
+}
+
+// regexpSplitToTable splits a string using a regular expression as a delimiter
+func regexpSplitToTable(args core.Values) (core.Values, core.ColType, error) {
+	src, re, global, ok, err := regexpArgs("regexp_split_to_table", args)
+	if err != nil || !ok {
+		return core.Values{}, core.VarChar, err
+	}
+	if global {
+		return nil, core.VarChar, errors.New("ERROR:  regexp_split_to_table() does not support the \"global\" option")
+	}
+
+	vals := make(core.Values, 0)
+	for _, s := range re.Split(src, -1) {
+		vals = append(vals, s)
+	}
+
+	return vals, core.VarChar, nil
+}
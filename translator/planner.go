@@ -0,0 +1,404 @@
+package translator
+
+import (
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+)
+
+// joinTables joins ltb with rtb by cond. Equality conditions between columns of both tables
+// are used as keys of hash join or merge join, so that the join doesn't take all pairs of rows.
+func joinTables(db backend.DB, ltb, rtb backend.Table, joinType backend.JoinType, cond ExpressionNode) (backend.Table, error) {
+	lkeys, rkeys, rest := splitEquiJoinCondition(conjuncts(cond), ltb.GetColNames(), rtb.GetColNames())
+	condFn := conditionFunc(db, rest)
+	if len(lkeys) == 0 {
+		return ltb.Join(rtb, joinType, condFn)
+	}
+
+	return backend.EquiJoin(ltb, rtb, joinType, joinKeys(db, lkeys, rkeys), condFn)
+}
+
+// conjuncts splits expr by AND
+func conjuncts(expr ExpressionNode) []ExpressionNode {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+	case *ANDNode:
+		return append(conjuncts(e.Lexpr), conjuncts(e.Rexpr)...)
+	case ANDNode:
+		return append(conjuncts(e.Lexpr), conjuncts(e.Rexpr)...)
+	}
+
+	return []ExpressionNode{expr}
+}
+
+// conditionFunc makes a function which evaluates AND of conds. It is always true if conds is empty.
+func conditionFunc(db backend.DB, conds []ExpressionNode) func(backend.Row) (core.Value, error) {
+	if len(conds) == 0 {
+		return func(backend.Row) (core.Value, error) {
+			return core.True, nil
+		}
+	}
+
+	expr := conds[0]
+	for _, cond := range conds[1:] {
+		expr = &ANDNode{Lexpr: expr, Rexpr: cond}
+	}
+
+	return scoped(db, expr.Eval())
+}
+
+func joinKeys(db backend.DB, lkeys, rkeys []ExpressionNode) backend.JoinKeys {
+	keys := backend.JoinKeys{}
+	for k := range lkeys {
+		keys.Left = append(keys.Left, scoped(db, lkeys[k].Eval()))
+		keys.Right = append(keys.Right, scoped(db, rkeys[k].Eval()))
+	}
+
+	return keys
+}
+
+// splitEquiJoinCondition picks up conditions of the form `x = y` where x refers only to lnames
+// and y refers only to rnames or vice versa. It returns the expressions of the left side,
+// those of the right side and the other conditions.
+func splitEquiJoinCondition(conds []ExpressionNode, lnames, rnames core.ColumnNames) ([]ExpressionNode, []ExpressionNode, []ExpressionNode) {
+	lkeys := make([]ExpressionNode, 0)
+	rkeys := make([]ExpressionNode, 0)
+	rest := make([]ExpressionNode, 0)
+	for _, cond := range conds {
+		var op *BinOpNode
+		switch e := cond.(type) {
+		case *BinOpNode:
+			op = e
+		case BinOpNode:
+			op = &e
+		}
+		if op == nil || op.Op != EqualOp {
+			rest = append(rest, cond)
+			continue
+		}
+
+		lside := referredSide(op.Lexpr, lnames, rnames)
+		rside := referredSide(op.Rexpr, lnames, rnames)
+		switch {
+		case lside == leftSide && rside == rightSide:
+			lkeys = append(lkeys, op.Lexpr)
+			rkeys = append(rkeys, op.Rexpr)
+		case lside == rightSide && rside == leftSide:
+			lkeys = append(lkeys, op.Rexpr)
+			rkeys = append(rkeys, op.Lexpr)
+		default:
+			rest = append(rest, cond)
+		}
+	}
+
+	return lkeys, rkeys, rest
+}
+
+type joinSide int
+
+const (
+	// noSide is an expression which refers to neither table such as a constant
+	noSide joinSide = iota
+	leftSide
+	rightSide
+	// bothSides is an expression which refers to both tables or can't be analyzed
+	bothSides
+)
+
+// referredSide returns the table which columns of expr belong to
+func referredSide(expr ExpressionNode, lnames, rnames core.ColumnNames) joinSide {
+	refs, ok := columnRefs(expr)
+	if !ok {
+		return bothSides
+	}
+
+	side := noSide
+	for _, ref := range refs {
+		inLeft, inRight := haveColumn(ref, lnames), haveColumn(ref, rnames)
+		var s joinSide
+		switch {
+		case inLeft && inRight:
+			return bothSides
+		case inLeft:
+			s = leftSide
+		case inRight:
+			s = rightSide
+		default:
+			// a column of an outer query is constant in the join
+			continue
+		}
+		if side != noSide && side != s {
+			return bothSides
+		}
+		side = s
+	}
+
+	return side
+}
+
+// columnRefs collects columns referred by expr.
+// The second return value is false if expr includes an expression which can't be analyzed.
+func columnRefs(expr ExpressionNode) ([]core.ColumnName, bool) {
+	switch e := expr.(type) {
+	case IntegerNode, *IntegerNode, FloatNode, *FloatNode, StringNode, *StringNode, BoolConstNode, *BoolConstNode:
+		return nil, true
+	case ColRefNode:
+		return []core.ColumnName{e.ColName}, true
+	case *ColRefNode:
+		return []core.ColumnName{e.ColName}, true
+	case BinOpNode:
+		return columnRefsOf(e.Lexpr, e.Rexpr)
+	case *BinOpNode:
+		return columnRefsOf(e.Lexpr, e.Rexpr)
+	case ANDNode:
+		return columnRefsOf(e.Lexpr, e.Rexpr)
+	case *ANDNode:
+		return columnRefsOf(e.Lexpr, e.Rexpr)
+	case ORNode:
+		return columnRefsOf(e.Lexpr, e.Rexpr)
+	case *ORNode:
+		return columnRefsOf(e.Lexpr, e.Rexpr)
+	case NotNode:
+		return columnRefsOf(e.Expr)
+	case *NotNode:
+		return columnRefsOf(e.Expr)
+	case NullTestNode:
+		return columnRefsOf(e.Expr)
+	case *NullTestNode:
+		return columnRefsOf(e.Expr)
+	case *BoolTestNode:
+		return columnRefsOf(e.Expr)
+	case *TypeCastNode:
+		return columnRefsOf(e.Expr)
+	case *DistinctFromNode:
+		return columnRefsOf(e.Lexpr, e.Rexpr)
+	case *CaseNode:
+		exprs := append(append([]ExpressionNode{e.DefaultResult}, e.CaseWhenExprs...), e.CaseResultExprs...)
+		return columnRefsOf(exprs...)
+	case *CoalesceNode:
+		return columnRefsOf(e.Args...)
+	case *NullIfNode:
+		return columnRefsOf(e.Lexpr, e.Rexpr)
+	case *GroupedAliasNode:
+		return columnRefsOf(e.Expr)
+	case *FuncCallNode:
+		return columnRefsOf(e.Args...)
+	case *SetReturningFuncNode:
+		return columnRefsOf(e.Args...)
+	case *WindowFuncNode:
+		if e.Window == nil {
+			return nil, false
+		}
+		exprs := append(append([]ExpressionNode{}, e.Args...), e.Window.PartitionBy...)
+		if e.Filter != nil {
+			exprs = append(exprs, e.Filter)
+		}
+		for _, key := range e.Window.OrderBy {
+			exprs = append(exprs, key.Expr)
+		}
+		return columnRefsOf(exprs...)
+	}
+
+	return nil, false
+}
+
+func columnRefsOf(exprs ...ExpressionNode) ([]core.ColumnName, bool) {
+	refs := make([]core.ColumnName, 0)
+	for _, expr := range exprs {
+		r, ok := columnRefs(expr)
+		if !ok {
+			return nil, false
+		}
+		refs = append(refs, r...)
+	}
+
+	return refs, true
+}
+
+// semiJoin is a condition of WHERE clause such as EXISTS (...), NOT EXISTS (...) and x IN (...).
+// It's evaluated as a hash join between the table and the subquery
+// instead of evaluating the subquery for each row.
+type semiJoin struct {
+	cond ExpressionNode
+	// anti is true for NOT EXISTS
+	anti bool
+	// query is the subquery without its select list
+	query *WhereNode
+	// testexpr and target are compared by `=` for IN
+	testexpr ExpressionNode
+	target   ExpressionNode
+}
+
+// splitSemiJoins picks up conditions which can be evaluated as semi joins or anti joins.
+// NOT IN isn't picked up because it is NULL rather than true if the subquery has NULL.
+func splitSemiJoins(conds []ExpressionNode) ([]ExpressionNode, []*semiJoin) {
+	rest := make([]ExpressionNode, 0, len(conds))
+	semiJoins := make([]*semiJoin, 0)
+	for _, cond := range conds {
+		if s := asSemiJoin(cond); s != nil {
+			semiJoins = append(semiJoins, s)
+		} else {
+			rest = append(rest, cond)
+		}
+	}
+
+	return rest, semiJoins
+}
+
+func asSemiJoin(cond ExpressionNode) *semiJoin {
+	anti := false
+	expr := cond
+	if not, ok := cond.(*NotNode); ok {
+		anti = true
+		expr = not.Expr
+	}
+	subLink, ok := expr.(*SubLinkNode)
+	if !ok {
+		return nil
+	}
+	switch {
+	case subLink.Type == ExistsSubLink:
+	case subLink.Type == AnySubLink && subLink.Op == EqualOp && !anti:
+	default:
+		return nil
+	}
+
+	// The subquery must be a simple select. LIMIT, DISTINCT and aggregation change its rows.
+	p, ok := subLink.Subquery.(*ProjectionNode)
+	if !ok {
+		return nil
+	}
+	ra := p.RANode
+	if o, ok := ra.(*OrderByNode); ok {
+		ra = o.RANode
+	}
+	query, ok := ra.(*WhereNode)
+	if !ok || query.Table == nil {
+		return nil
+	}
+
+	s := &semiJoin{cond: cond, anti: anti, query: query}
+	if subLink.Type == AnySubLink {
+		if len(p.ResTargets) != 1 {
+			return nil
+		}
+		if _, ok := p.ResTargets[0].(ColWildcardNode); ok {
+			return nil
+		}
+		s.testexpr, s.target = subLink.Testexpr, p.ResTargets[0]
+	}
+
+	return s
+}
+
+// eval filters tb by the semi join. If the subquery can't be evaluated separately from tb,
+// the condition is evaluated for each row.
+func (s *semiJoin) eval(db backend.DB, tb backend.Table) (backend.Table, error) {
+	filter, ok := s.build(db, tb)
+	if !ok {
+		return tb.Copy().Where(conditionFunc(db, []ExpressionNode{s.cond}))
+	}
+
+	return tb.Copy().Where(filter)
+}
+
+// build evaluates the subquery and makes a hash table of its rows whose keys are
+// the expressions compared with columns of tb by `=`.
+func (s *semiJoin) build(db backend.DB, tb backend.Table) (func(backend.Row) (core.Value, error), bool) {
+	inner, err := s.query.Table.Eval(db)
+	if err != nil || inner == nil {
+		return nil, false
+	}
+
+	outerNames, innerNames := tb.GetColNames(), inner.GetColNames()
+	lkeys, rkeys, rest := splitEquiJoinCondition(conjuncts(s.query.Condition), outerNames, innerNames)
+	if s.target != nil {
+		// The test expression belongs to the outer query, so it can be anything.
+		// The target must not refer to tb because it is evaluated for rows of the subquery alone.
+		refs, ok := columnRefs(s.target)
+		if !ok {
+			return nil, false
+		}
+		for _, ref := range refs {
+			if !haveColumn(ref, innerNames) && haveColumn(ref, outerNames) {
+				return nil, false
+			}
+		}
+		lkeys, rkeys = append(lkeys, s.testexpr), append(rkeys, s.target)
+	}
+	if len(lkeys) == 0 {
+		return nil, false
+	}
+
+	// conditions which refer only to the subquery are applied before the join
+	innerConds, mixed := make([]ExpressionNode, 0), make([]ExpressionNode, 0)
+	for _, cond := range rest {
+		switch referredSide(cond, outerNames, innerNames) {
+		case rightSide, noSide:
+			innerConds = append(innerConds, cond)
+		default:
+			mixed = append(mixed, cond)
+		}
+	}
+	if len(innerConds) > 0 {
+		inner, err = inner.Copy().Where(conditionFunc(db, innerConds))
+		if err != nil {
+			return nil, false
+		}
+	}
+
+	buckets := make(map[interface{}][]backend.Row)
+	rkeyFns := joinKeys(db, lkeys, rkeys).Right
+	for _, row := range inner.GetRows() {
+		vals, err := evalKeys(rkeyFns, row)
+		if err != nil {
+			return nil, false
+		}
+		if vals != nil {
+			key := core.HashKey(vals)
+			buckets[key] = append(buckets[key], row)
+		}
+	}
+
+	lkeyFns := joinKeys(db, lkeys, rkeys).Left
+	return func(row backend.Row) (core.Value, error) {
+		vals, err := evalKeys(lkeyFns, row)
+		if err != nil {
+			return nil, err
+		}
+		matched := false
+		if vals != nil {
+			condFn := conditionFunc(withOuterRow(db, row), mixed)
+			for _, innerRow := range buckets[core.HashKey(vals)] {
+				v, err := condFn(innerRow)
+				if err != nil {
+					return nil, err
+				}
+				if v == core.True {
+					matched = true
+					break
+				}
+			}
+		}
+
+		return toSQLBool(matched != s.anti), nil
+	}, true
+}
+
+// evalKeys evaluates key values of row. It returns nil if some key is NULL
+// because NULL doesn't equal any value.
+func evalKeys(keyFns []func(backend.Row) (core.Value, error), row backend.Row) (core.Values, error) {
+	vals := make(core.Values, 0, len(keyFns))
+	for _, fn := range keyFns {
+		v, err := fn(row)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil || v == core.Null {
+			return nil, nil
+		}
+		vals = append(vals, v)
+	}
+
+	return vals, nil
+}
@@ -3,7 +3,6 @@ package translator
 import (
 	"errors"
 	"fmt"
-	"reflect"
 	"strconv"
 	"strings"
 
@@ -37,16 +36,21 @@ func (qr *QueryResult) GetColumns() []string {
 // Statement is interface of query statement
 type Statement interface {
 	Eval(backend.DB) (Result, error)
+	IsLogged(backend.DB) bool
+	LogQuery(backend.DB, string) (string, error)
 }
 
 // QueryStatement is statement of query
 type QueryStatement struct {
 	RANode RelationalAlgebraNode
+	// replay records what the last evaluation depended on
+	replay *replayDB
 }
 
 // Eval evaluates QueryStatement
 func (qs *QueryStatement) Eval(db backend.DB) (Result, error) {
-	tb, err := qs.RANode.Eval(db)
+	qs.replay = &replayDB{DB: db}
+	tb, err := qs.RANode.Eval(qs.replay)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +91,16 @@ func (qs *QueryStatement) Eval(db backend.DB) (Result, error) {
 	}, nil
 }
 
+// IsLogged reports whether the statement has to be written to the durability log.
+// It has to be called before the statement is evaluated.
+func (qs *QueryStatement) IsLogged(db backend.DB) bool {
+	if node, ok := qs.RANode.(loggedNode); ok {
+		return node.isLogged(db)
+	}
+
+	return false
+}
+
 // Translator is an interface for translator of SQL parse
 type Translator interface {
 	Translate() RelationalAlgebraNode
@@ -95,6 +109,8 @@ type Translator interface {
 // PGTranlator is translator for PostgreSQL syntax
 type PGTranlator struct {
 	query string
+	// nested is true when a subquery is translated
+	nested bool
 }
 
 // NewPGTranslator is a constructor of PGTranlator
@@ -104,6 +120,14 @@ func NewPGTranslator(query string) *PGTranlator {
 	}
 }
 
+// subTranslator returns a translator of a subquery
+func (pg *PGTranlator) subTranslator() *PGTranlator {
+	return &PGTranlator{
+		query:  pg.query,
+		nested: true,
+	}
+}
+
 // Translate translates a postgres parse tree into RelationalAlgebraNode
 func (pg *PGTranlator) Translate() (Statement, error) {
 	result, err := pg_query.Parse(pg.query)
@@ -118,14 +142,24 @@ func (pg *PGTranlator) Translate() (Statement, error) {
 	}
 	stmt := result.Stmts[0].Stmt
 	if node := stmt.GetSelectStmt(); node != nil {
-		ra, err = pg.TranslateSelect(node)
+		if node.GetIntoClause() != nil {
+			ra, err = pg.TranslateSelectInto(node)
+		} else {
+			ra, err = pg.TranslateSelect(node)
+		}
 	}
 	if node := stmt.GetCreateStmt(); node != nil {
 		ra, err = pg.TranslateCreateTable(node)
 	}
+	if node := stmt.GetCreateTableAsStmt(); node != nil {
+		ra, err = pg.TranslateCreateTableAs(node)
+	}
 	if node := stmt.GetDropStmt(); node != nil {
 		ra, err = pg.TranslateDropTable(node)
 	}
+	if node := stmt.GetTruncateStmt(); node != nil {
+		ra, err = pg.TranslateTruncate(node)
+	}
 	if node := stmt.GetInsertStmt(); node != nil {
 		ra, err = pg.TranslateInsert(node)
 	}
@@ -136,6 +170,9 @@ func (pg *PGTranlator) Translate() (Statement, error) {
 		ra, err = pg.TranslateDelete(node)
 	}
 
+	if err != nil {
+		return nil, err
+	}
 	if ra != nil {
 		return &QueryStatement{
 			RANode: ra,
@@ -145,46 +182,111 @@ func (pg *PGTranlator) Translate() (Statement, error) {
 	return nil, fmt.Errorf("Don't support such query: %v\n", pg.query)
 }
 
-// TranslateDropTable translates sql parse tree into DropTableNode
+// TranslateDropTable translates sql parse tree into DropTableNode.
+// Tables have no dependent objects, so CASCADE and RESTRICT behave the same.
 func (pg *PGTranlator) TranslateDropTable(node *pg_query.DropStmt) (RelationalAlgebraNode, error) {
+	if node.GetRemoveType() != pg_query.ObjectType_OBJECT_TABLE {
+		return nil, fmt.Errorf("Don't support such query: %v\n", pg.query)
+	}
+
 	tableList := node.GetObjects()
 	tableNames := make([]string, 0)
 	for _, tb := range tableList {
-		tableNames = append(tableNames, tb.GetList().GetItems()[0].GetString_().GetStr())
+		items := tb.GetList().GetItems()
+		names := make([]string, 0, len(items))
+		for _, item := range items {
+			names = append(names, strings.ToLower(item.GetString_().GetStr()))
+		}
+		tableNames = append(tableNames, strings.Join(names, "."))
 	}
 
 	return &DropTableNode{
 		TableNames: tableNames,
+		MissingOk:  node.GetMissingOk(),
+	}, nil
+}
+
+// TranslateTruncate translates sql parse tree into TruncateNode.
+// Tables have no dependent objects, so CASCADE and RESTRICT behave the same.
+func (pg *PGTranlator) TranslateTruncate(node *pg_query.TruncateStmt) (RelationalAlgebraNode, error) {
+	tableNames := make([]string, 0)
+	for _, rel := range node.GetRelations() {
+		tableNames = append(tableNames, qualifiedTableName(rel.GetRangeVar()))
+	}
+
+	return &TruncateNode{
+		TableNames:      tableNames,
+		RestartIdentity: node.GetRestartSeqs(),
 	}, nil
 }
 
 // TranslateDelete translates sql parse tree into DeleteNode
 func (pg *PGTranlator) TranslateDelete(node *pg_query.DeleteStmt) (RelationalAlgebraNode, error) {
 	cond := constructExprNode(node.GetWhereClause())
-	tableName := node.GetRelation().Relname
+	tableName := qualifiedTableName(node.GetRelation())
+	using, err := pg.interpretJoinedRelations(node.GetUsingClause())
+	if err != nil {
+		return nil, err
+	}
 
-	return &DeleteNode{
+	return pg.translateWith(node.GetWithClause(), &DeleteNode{
 		Condition: cond,
 		TableName: tableName,
-	}, nil
+		Alias:     node.GetRelation().GetAlias().GetAliasname(),
+		Using:     using,
+		Returning: constructReturning(node.GetReturningList()),
+	})
 }
 
 // TranslateUpdate translates sql parse tree into UpdateNode
 func (pg *PGTranlator) TranslateUpdate(node *pg_query.UpdateStmt) (RelationalAlgebraNode, error) {
 	cond := constructExprNode(node.GetWhereClause())
-	tableName := strings.ToLower(node.GetRelation().Relname)
+	tableName := qualifiedTableName(node.GetRelation())
 	targetColNames, resTargetNodes := interpreteUpdateTargetList(node.GetTargetList())
+	from, err := pg.interpretJoinedRelations(node.GetFromClause())
+	if err != nil {
+		return nil, err
+	}
 
-	return &UpdateNode{
+	return pg.translateWith(node.GetWithClause(), &UpdateNode{
 		Condition:  cond,
 		ColNames:   targetColNames,
 		AssignExpr: resTargetNodes,
 		TableName:  tableName,
-	}, nil
+		Alias:      node.GetRelation().GetAlias().GetAliasname(),
+		From:       from,
+		Returning:  constructReturning(node.GetReturningList()),
+	})
+}
+
+// interpretJoinedRelations translates FROM clause of UPDATE and USING clause of DELETE
+func (pg *PGTranlator) interpretJoinedRelations(fromTree []*pg_query.Node) (RelationalAlgebraNode, error) {
+	if len(fromTree) == 0 {
+		return nil, nil
+	}
+
+	return pg.interpretFromClause(fromTree)
 }
 
 // TranslateSelect translates postgres a select statement into ProjectionNode
 func (pg *PGTranlator) TranslateSelect(pgtree *pg_query.SelectStmt) (RelationalAlgebraNode, error) {
+	query, err := pg.translateSelectBody(pgtree)
+	if err != nil {
+		return nil, err
+	}
+
+	return pg.translateWith(pgtree.GetWithClause(), query)
+}
+
+// translateSelectBody translates a select statement except for WITH clause
+func (pg *PGTranlator) translateSelectBody(pgtree *pg_query.SelectStmt) (RelationalAlgebraNode, error) {
+	if pgtree.GetOp() != pg_query.SetOperation_SETOP_NONE {
+		return pg.translateSetOp(pgtree)
+	}
+	if valsLists := pgtree.GetValuesLists(); valsLists != nil {
+		return translateValues(valsLists, false), nil
+	}
+
 	targetList := pgtree.GetTargetList()
 	targetColNames, resTargetNodes := interpreteTargetList(targetList)
 
@@ -192,9 +294,61 @@ func (pg *PGTranlator) TranslateSelect(pgtree *pg_query.SelectStmt) (RelationalA
 	if err != nil {
 		return nil, err
 	}
-	whereNode := constructWhereNode(pgtree.GetWhereClause(), table)
-	orderByNode := constructOrderByNode(pgtree.GetSortClause(), whereNode)
-	limitNode, err := constructLimitNode(pgtree.GetLimitCount(), orderByNode)
+	whereNode, err := constructWhereNode(pgtree.GetWhereClause(), table)
+	if err != nil {
+		return nil, err
+	}
+	sortKeys, err := resolveSortKeys(interpretSortKeys(pgtree.GetSortClause()), targetColNames, resTargetNodes)
+	if err != nil {
+		return nil, err
+	}
+	distinct, distinctOn := interpretDistinctClause(pgtree.GetDistinctClause())
+	if distinct {
+		if err := checkDistinctOrder(distinctOn, resTargetNodes, sortKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	// DISTINCT ON expressions and ORDER BY expressions are evaluated on the same rows as the select list
+	having := constructExprNode(pgtree.GetHavingClause())
+	if findExpr(having, isWindowFunc) {
+		return nil, errors.New("ERROR:  window functions are not allowed in HAVING")
+	}
+	outputs := append(append([]ExpressionNode{}, resTargetNodes...), distinctOn...)
+	for _, key := range sortKeys {
+		outputs = append(outputs, key.Expr)
+	}
+	windows, err := interpretWindowClause(pgtree.GetWindowClause())
+	if err != nil {
+		return nil, err
+	}
+	if outputs, err = resolveWindowFuncs(outputs, windows); err != nil {
+		return nil, err
+	}
+	aggNode, outputs, err := constructAggregateNode(pgtree.GetGroupClause(), targetColNames, outputs, having, whereNode)
+	if err != nil {
+		return nil, err
+	}
+	windowNode, outputs, err := constructWindowNode(outputs, aggNode)
+	if err != nil {
+		return nil, err
+	}
+	projectSetNode, outputs, err := constructProjectSetNode(outputs, windowNode)
+	if err != nil {
+		return nil, err
+	}
+	nTargets, nDistinctOn := len(resTargetNodes), len(distinctOn)
+	resTargetNodes, distinctOn = outputs[:nTargets], outputs[nTargets:nTargets+nDistinctOn]
+	for k := range sortKeys {
+		sortKeys[k].Expr = outputs[nTargets+nDistinctOn+k]
+		if pos, ok := sortKeys[k].Expr.(*TargetPosNode); ok {
+			pos.Targets = resTargetNodes
+		}
+	}
+
+	orderByNode := constructOrderByNode(sortKeys, projectSetNode)
+	distinctNode := constructDistinctNode(distinct, distinctOn, resTargetNodes, orderByNode)
+	limitNode, err := constructLimitNode(pgtree, sortKeys, distinctNode)
 	if err != nil {
 		return nil, err
 	}
@@ -206,80 +360,439 @@ func (pg *PGTranlator) TranslateSelect(pgtree *pg_query.SelectStmt) (RelationalA
 	}, nil
 }
 
-func constructLimitNode(limitNode *pg_query.Node, orderByNode RelationalAlgebraNode) (RelationalAlgebraNode, error) {
-	if limitNode == nil {
-		return orderByNode, nil
+// translateSetOp translates UNION, INTERSECT and EXCEPT.
+// ORDER BY and LIMIT are applied to the result of the set operation.
+func (pg *PGTranlator) translateSetOp(pgtree *pg_query.SelectStmt) (RelationalAlgebraNode, error) {
+	left, err := pg.subTranslator().TranslateSelect(pgtree.GetLarg())
+	if err != nil {
+		return nil, err
+	}
+	right, err := pg.subTranslator().TranslateSelect(pgtree.GetRarg())
+	if err != nil {
+		return nil, err
+	}
+
+	var op SetOpKind
+	switch pgtree.GetOp() {
+	case pg_query.SetOperation_SETOP_UNION:
+		op = Union
+	case pg_query.SetOperation_SETOP_INTERSECT:
+		op = Intersect
+	case pg_query.SetOperation_SETOP_EXCEPT:
+		op = Except
+	}
+	setOp := &SetOpNode{
+		Op:    op,
+		All:   pgtree.GetAll(),
+		Left:  left,
+		Right: right,
+	}
+
+	sortKeys, err := resolveSetOpSortKeys(interpretSortKeys(pgtree.GetSortClause()))
+	if err != nil {
+		return nil, err
+	}
+
+	return constructLimitNode(pgtree, sortKeys, constructOrderByNode(sortKeys, setOp))
+}
+
+// resolveSetOpSortKeys resolves ORDER BY keys of a set operation.
+// Only output column names and positions can be used as in PostgreSQL.
+func resolveSetOpSortKeys(keys []SortKey) ([]SortKey, error) {
+	resolved := make([]SortKey, 0, len(keys))
+	for _, key := range keys {
+		if pos, ok := key.Expr.(IntegerNode); ok {
+			key.Expr = &ColPosNode{Pos: pos.Val}
+		} else if name, ok := colRefName(key.Expr); !ok || name.TableName != "" {
+			return nil, errors.New("ERROR:  invalid UNION/INTERSECT/EXCEPT ORDER BY clause")
+		}
+		resolved = append(resolved, key)
+	}
+
+	return resolved, nil
+}
+
+// constructLimitNode makes LimitNode from LIMIT, OFFSET and FETCH FIRST clauses.
+// The arguments are evaluated when LimitNode is evaluated.
+func constructLimitNode(stmt *pg_query.SelectStmt, sortKeys []SortKey, table RelationalAlgebraNode) (RelationalAlgebraNode, error) {
+	if stmt.GetLimitCount() == nil && stmt.GetLimitOffset() == nil {
+		return table, nil
 	}
 
-	expr := constructExprNode(limitNode)
-	var r backend.Row
-	val, err := expr.Eval()(r)
+	// the parser rejects WITH TIES without ORDER BY
+	withTies := stmt.GetLimitOption() == pg_query.LimitOption_LIMIT_OPTION_WITH_TIES
+	count, err := constructLimitArg(stmt.GetLimitCount(), "LIMIT")
+	if err != nil {
+		return nil, err
+	}
+	offset, err := constructLimitArg(stmt.GetLimitOffset(), "OFFSET")
 	if err != nil {
 		return nil, err
 	}
 
-	if reflect.ValueOf(val).Kind() != reflect.Int {
-		return nil, errors.New("ERROR:  argument of LIMIT must not contain variables")
+	limit := &LimitNode{
+		Count:    count,
+		Offset:   offset,
+		WithTies: withTies,
+		RANode:   table,
+	}
+	if withTies {
+		limit.SortKeys = sortKeys
+	}
+
+	return limit, nil
+}
+
+func constructLimitArg(node *pg_query.Node, clause string) (ExpressionNode, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	expr := constructExprNode(node)
+	if findExpr(expr, isAggCall) {
+		return nil, fmt.Errorf("ERROR:  aggregate functions are not allowed in %v", clause)
+	}
+	if refs, _ := columnRefs(expr); len(refs) > 0 {
+		return nil, fmt.Errorf("ERROR:  argument of %v must not contain variables", clause)
+	}
+
+	return expr, nil
+}
+
+// interpretDistinctClause returns whether the select statement has DISTINCT and the expressions of DISTINCT ON.
+func interpretDistinctClause(distinctClause []*pg_query.Node) (bool, []ExpressionNode) {
+	if len(distinctClause) == 0 {
+		return false, nil
+	}
+	if len(distinctClause) == 1 && distinctClause[0].GetNode() == nil {
+		// SELECT DISTINCT has an empty node
+		return true, nil
 	}
 
-	return &LimitNode{
-		Count:  val.(int),
+	exprs := make([]ExpressionNode, 0, len(distinctClause))
+	for _, node := range distinctClause {
+		exprs = append(exprs, constructExprNode(node))
+	}
+
+	return true, exprs
+}
+
+// checkDistinctOrder checks that ORDER BY is consistent with DISTINCT.
+// ORDER BY expressions of SELECT DISTINCT must appear in the select list
+// and the initial ORDER BY expressions of SELECT DISTINCT ON must be DISTINCT ON expressions.
+func checkDistinctOrder(distinctOn, targets []ExpressionNode, sortKeys []SortKey) error {
+	if len(distinctOn) == 0 {
+		for _, key := range sortKeys {
+			if _, ok := key.Expr.(*TargetPosNode); ok {
+				continue
+			}
+			if !containsMatchingExpr(targets, key.Expr) {
+				return errors.New("ERROR:  for SELECT DISTINCT, ORDER BY expressions must appear in select list")
+			}
+		}
+		return nil
+	}
+
+	matched := make([]ExpressionNode, 0, len(distinctOn))
+	for _, key := range sortKeys {
+		if len(matched) == len(distinctOn) {
+			break
+		}
+		if !containsMatchingExpr(distinctOn, key.Expr) {
+			return errors.New("ERROR:  SELECT DISTINCT ON expressions must match initial ORDER BY expressions")
+		}
+		if !containsMatchingExpr(matched, key.Expr) {
+			matched = append(matched, key.Expr)
+		}
+	}
+
+	return nil
+}
+
+func constructDistinctNode(distinct bool, distinctOn, targets []ExpressionNode, orderByNode RelationalAlgebraNode) RelationalAlgebraNode {
+	if !distinct {
+		return orderByNode
+	}
+	keys := distinctOn
+	if len(keys) == 0 {
+		keys = targets
+	}
+
+	return &DistinctNode{
+		Keys:   keys,
 		RANode: orderByNode,
-	}, nil
+	}
 }
 
-func constructOrderByNode(sortTree []*pg_query.Node, whereNode RelationalAlgebraNode) RelationalAlgebraNode {
-	if len(sortTree) == 0 {
-		return whereNode
+func constructOrderByNode(sortKeys []SortKey, table RelationalAlgebraNode) RelationalAlgebraNode {
+	if len(sortKeys) == 0 {
+		return table
 	}
-	sortKeys := make(core.ColumnNames, 0)
-	sortDirs := make([]int, 0)
-	sortKey := getColName(sortTree[0].GetSortBy().GetNode().GetColumnRef())
-	sortDir := int(sortTree[0].GetSortBy().GetSortbyDir())
-	// TODO: get all specified columns
-	sortKeys = append(sortKeys, sortKey)
-	sortDirs = append(sortDirs, sortDir)
 
 	return &OrderByNode{
 		SortKeys: sortKeys,
-		SortDirs: sortDirs,
-		RANode:   whereNode,
+		RANode:   table,
+	}
+}
+
+// resolveSortKeys replaces ORDER BY keys which refer to the select list with the expressions of the select list.
+// As in PostgreSQL, an integer is a position of the select list and a bare column name
+// refers to the output column of the name in preference to the input column.
+func resolveSortKeys(keys []SortKey, targetColNames core.ColumnNames, targets []ExpressionNode) ([]SortKey, error) {
+	resolved := make([]SortKey, 0, len(keys))
+	for _, key := range keys {
+		expr, err := resolveSortExpr(key.Expr, targetColNames, targets)
+		if err != nil {
+			return nil, err
+		}
+		key.Expr = expr
+		resolved = append(resolved, key)
+	}
+
+	return resolved, nil
+}
+
+func resolveSortExpr(expr ExpressionNode, targetColNames core.ColumnNames, targets []ExpressionNode) (ExpressionNode, error) {
+	if pos, ok := expr.(IntegerNode); ok {
+		if followsWildcard(pos.Val, targets) {
+			// Targets are set after the select list is transformed by AggregateNode and WindowNode
+			return &TargetPosNode{Pos: pos.Val, Clause: "ORDER BY"}, nil
+		}
+		if pos.Val < 1 || pos.Val > len(targets) {
+			return nil, fmt.Errorf("ERROR:  ORDER BY position %d is not in select list", pos.Val)
+		}
+		return targets[pos.Val-1], nil
+	}
+
+	name, ok := colRefName(expr)
+	if !ok || name.TableName != "" {
+		return expr, nil
+	}
+	var found ExpressionNode
+	for k, colName := range targetColNames {
+		if _, ok := targets[k].(ColWildcardNode); ok || colName.Name != name.Name {
+			continue
+		}
+		if found != nil && !exprMatches(found, targets[k]) {
+			return nil, fmt.Errorf(`ERROR:  ORDER BY "%v" is ambiguous`, name.Name)
+		}
+		found = targets[k]
+	}
+	if found == nil {
+		return expr, nil
+	}
+
+	return found, nil
+}
+
+// translateWith translates WITH clause. The common table expressions are given to query by WithNode.
+func (pg *PGTranlator) translateWith(with *pg_query.WithClause, query RelationalAlgebraNode) (RelationalAlgebraNode, error) {
+	if with == nil {
+		return query, nil
+	}
+
+	ctes := make([]*CTE, 0, len(with.GetCtes()))
+	names := make(map[string]bool)
+	for _, node := range with.GetCtes() {
+		expr := node.GetCommonTableExpr()
+		if names[expr.GetCtename()] {
+			return nil, fmt.Errorf("ERROR:  WITH query name \"%v\" specified more than once", expr.GetCtename())
+		}
+		names[expr.GetCtename()] = true
+
+		cte, err := pg.translateCTE(expr, with.GetRecursive())
+		if err != nil {
+			return nil, err
+		}
+		ctes = append(ctes, cte)
+	}
+
+	return &WithNode{
+		CTEs:  ctes,
+		Query: query,
+	}, nil
+}
+
+func (pg *PGTranlator) translateCTE(expr *pg_query.CommonTableExpr, recursive bool) (*CTE, error) {
+	cte := &CTE{
+		Name:        expr.GetCtename(),
+		Materialize: interpretMaterialize(expr.GetCtematerialized()),
+	}
+	for _, col := range expr.GetAliascolnames() {
+		cte.ColNames = append(cte.ColNames, col.GetString_().GetStr())
+	}
+
+	sub := pg.subTranslator()
+	query := expr.GetCtequery()
+	if stmt := query.GetSelectStmt(); stmt != nil {
+		if recursive && refersTo(stmt, cte.Name) {
+			if err := sub.translateRecursiveCTE(cte, stmt); err != nil {
+				return nil, err
+			}
+			return cte, nil
+		}
+		ra, err := sub.TranslateSelect(stmt)
+		if err != nil {
+			return nil, err
+		}
+		cte.Query = ra
+		return cte, nil
+	}
+
+	if pg.nested {
+		return nil, errors.New("ERROR:  WITH clause containing a data-modifying statement must be at the top level")
+	}
+	var ra RelationalAlgebraNode
+	var err error
+	switch {
+	case query.GetInsertStmt() != nil:
+		ra, err = sub.TranslateInsert(query.GetInsertStmt())
+	case query.GetUpdateStmt() != nil:
+		ra, err = sub.TranslateUpdate(query.GetUpdateStmt())
+	case query.GetDeleteStmt() != nil:
+		ra, err = sub.TranslateDelete(query.GetDeleteStmt())
+	default:
+		err = fmt.Errorf("Don't support such query in WITH clause: %v\n", pg.query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cte.Query = ra
+
+	return cte, nil
+}
+
+// translateRecursiveCTE translates a common table expression which refers to itself.
+// It must be of the form `non-recursive-term UNION [ALL] recursive-term`.
+func (pg *PGTranlator) translateRecursiveCTE(cte *CTE, stmt *pg_query.SelectStmt) error {
+	if stmt.GetOp() != pg_query.SetOperation_SETOP_UNION {
+		return fmt.Errorf("ERROR:  recursive query \"%v\" does not have the form non-recursive-term UNION [ALL] recursive-term", cte.Name)
+	}
+	if err := checkRecursiveClauses(stmt); err != nil {
+		return err
+	}
+	if refersTo(stmt.GetLarg(), cte.Name) {
+		return fmt.Errorf("ERROR:  recursive reference to query \"%v\" must not appear within its non-recursive term", cte.Name)
 	}
+	if err := checkRecursiveTerm(stmt.GetRarg()); err != nil {
+		return err
+	}
+
+	query, err := pg.TranslateSelect(stmt.GetLarg())
+	if err != nil {
+		return err
+	}
+	recursive, err := pg.TranslateSelect(stmt.GetRarg())
+	if err != nil {
+		return err
+	}
+	cte.Query, cte.Recursive, cte.UnionAll = query, recursive, stmt.GetAll()
+
+	return nil
+}
+
+// checkRecursiveClauses checks that the recursive query has no ORDER BY, LIMIT and OFFSET
+func checkRecursiveClauses(stmt *pg_query.SelectStmt) error {
+	if len(stmt.GetSortClause()) > 0 {
+		return errors.New("ERROR:  ORDER BY in a recursive query is not implemented")
+	}
+	if stmt.GetLimitOffset() != nil {
+		return errors.New("ERROR:  OFFSET in a recursive query is not implemented")
+	}
+	if stmt.GetLimitCount() != nil {
+		return errors.New("ERROR:  LIMIT in a recursive query is not implemented")
+	}
+
+	return nil
+}
+
+// checkRecursiveTerm checks that the recursive term is evaluated row by row over the rows of the previous iteration,
+// that is, it has neither aggregate functions, window functions, ORDER BY, LIMIT nor OFFSET.
+func checkRecursiveTerm(stmt *pg_query.SelectStmt) error {
+	if err := checkRecursiveClauses(stmt); err != nil {
+		return err
+	}
+
+	_, exprs := interpreteTargetList(stmt.GetTargetList())
+	exprs = append(exprs, constructExprNode(stmt.GetHavingClause()))
+	for _, expr := range exprs {
+		if findExpr(expr, isAggCall) {
+			return errors.New("ERROR:  aggregate functions are not allowed in a recursive query's recursive term")
+		}
+		if findExpr(expr, isWindowFunc) {
+			return errors.New("ERROR:  window functions are not allowed in a recursive query's recursive term")
+		}
+	}
+
+	return nil
+}
+
+func interpretMaterialize(m pg_query.CTEMaterialize) CTEMaterialize {
+	switch m {
+	case pg_query.CTEMaterialize_CTEMaterializeAlways:
+		return MaterializeAlways
+	case pg_query.CTEMaterialize_CTEMaterializeNever:
+		return MaterializeNever
+	}
+
+	return MaterializeDefault
+}
+
+// refersTo reports whether FROM clauses of stmt refer to the table named name
+func refersTo(stmt *pg_query.SelectStmt, name string) bool {
+	if stmt == nil {
+		return false
+	}
+	if refersTo(stmt.GetLarg(), name) || refersTo(stmt.GetRarg(), name) {
+		return true
+	}
+	for _, item := range stmt.GetFromClause() {
+		if fromItemRefersTo(item, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fromItemRefersTo(item *pg_query.Node, name string) bool {
+	if rv := item.GetRangeVar(); rv != nil {
+		return rv.GetSchemaname() == "" && rv.GetRelname() == name
+	}
+	if join := item.GetJoinExpr(); join != nil {
+		return fromItemRefersTo(join.GetLarg(), name) || fromItemRefersTo(join.GetRarg(), name)
+	}
+	if sub := item.GetRangeSubselect(); sub != nil {
+		return refersTo(sub.GetSubquery().GetSelectStmt(), name)
+	}
+
+	return false
 }
 
 func (pg *PGTranlator) interpretFromClause(fromTree []*pg_query.Node) (RelationalAlgebraNode, error) {
 	tables := make([]RelationalAlgebraNode, 0, len(fromTree))
 
 	for _, relation := range fromTree {
-		if relation.GetRangeVar() != nil {
-			tableName := relation.GetRangeVar().Relname
-			alias := relation.GetRangeVar().Alias.GetAliasname()
-			if alias == "" {
-				tables = append(tables, &TableNode{TableName: tableName})
-			} else {
-				tables = append(tables, &RenameTableNode{
-					Alias: alias,
-					Table: &TableNode{
-						TableName: tableName,
-					},
-				})
-			}
+		table, err := pg.interpretFromItem(relation)
+		if err != nil {
+			return nil, err
 		}
-		if relation.GetRangeSubselect() != nil {
-			subQueryTree := relation.GetRangeSubselect().GetSubquery().GetSelectStmt()
-			alias := relation.GetRangeSubselect().Alias.GetAliasname()
-			ra, err := pg.TranslateSelect(subQueryTree)
-			if err != nil {
-				return nil, err
-			}
-			tables = append(tables, &RenameTableNode{
-				Alias: alias,
-				Table: ra,
-			})
+		if table == nil {
+			continue
 		}
-		if relation.GetJoinExpr() != nil {
-			// Not Implemented
+		if isLateral(relation) && len(tables) > 0 {
+			// A LATERAL item is evaluated for each row of the preceding items.
+			tables = []RelationalAlgebraNode{
+				&JoinNode{
+					JoinType: backend.InnerJoin,
+					Left:     crossJoinRA(tables),
+					Right:    table,
+					Lateral:  true,
+				},
+			}
+			continue
 		}
+		tables = append(tables, table)
 	}
 
 	table := crossJoinRA(tables)
@@ -287,6 +800,131 @@ func (pg *PGTranlator) interpretFromClause(fromTree []*pg_query.Node) (Relationa
 	return table, nil
 }
 
+func (pg *PGTranlator) interpretFromItem(relation *pg_query.Node) (RelationalAlgebraNode, error) {
+	if relation.GetRangeVar() != nil {
+		tableName := qualifiedTableName(relation.GetRangeVar())
+		alias := relation.GetRangeVar().Alias.GetAliasname()
+		if alias == "" {
+			return &TableNode{TableName: tableName}, nil
+		}
+		return &RenameTableNode{
+			Alias: alias,
+			Table: &TableNode{
+				TableName: tableName,
+			},
+		}, nil
+	}
+	if relation.GetRangeSubselect() != nil {
+		subQueryTree := relation.GetRangeSubselect().GetSubquery().GetSelectStmt()
+		alias := relation.GetRangeSubselect().Alias.GetAliasname()
+		ra, err := pg.subTranslator().TranslateSelect(subQueryTree)
+		if err != nil {
+			return nil, err
+		}
+		return &RenameTableNode{
+			Alias: alias,
+			Table: ra,
+		}, nil
+	}
+	if relation.GetJoinExpr() != nil {
+		return pg.interpretJoinExpr(relation.GetJoinExpr())
+	}
+	if relation.GetRangeFunction() != nil {
+		return interpretRangeFunction(relation.GetRangeFunction())
+	}
+
+	return nil, nil
+}
+
+// isLateral reports whether the from item can refer to columns of preceding from items.
+// Functions in from clause can always refer to them.
+func isLateral(relation *pg_query.Node) bool {
+	if v := relation.GetRangeSubselect(); v != nil {
+		return v.GetLateral()
+	}
+
+	return relation.GetRangeFunction() != nil
+}
+
+func interpretRangeFunction(rf *pg_query.RangeFunction) (RelationalAlgebraNode, error) {
+	funcs := rf.GetFunctions()
+	if len(funcs) != 1 || rf.GetIsRowsfrom() {
+		return nil, errors.New("ERROR:  ROWS FROM is not supported")
+	}
+	funcCall := funcs[0].GetList().GetItems()[0].GetFuncCall()
+	if funcCall == nil {
+		return nil, errors.New("ERROR:  only function calls are supported in FROM")
+	}
+
+	args := make([]ExpressionNode, 0, len(funcCall.GetArgs()))
+	for _, arg := range funcCall.GetArgs() {
+		args = append(args, constructExprNode(arg))
+	}
+	colAliases := make([]string, 0)
+	for _, name := range rf.GetAlias().GetColnames() {
+		colAliases = append(colAliases, name.GetString_().GetStr())
+	}
+
+	return &FunctionTableNode{
+		FuncName:       funcName(funcCall),
+		Args:           args,
+		Alias:          rf.GetAlias().GetAliasname(),
+		ColAliases:     colAliases,
+		WithOrdinality: rf.GetOrdinality(),
+	}, nil
+}
+
+func (pg *PGTranlator) interpretJoinExpr(join *pg_query.JoinExpr) (RelationalAlgebraNode, error) {
+	left, err := pg.interpretFromItem(join.GetLarg())
+	if err != nil {
+		return nil, err
+	}
+	right, err := pg.interpretFromItem(join.GetRarg())
+	if err != nil {
+		return nil, err
+	}
+
+	var joinType backend.JoinType
+	switch join.GetJointype() {
+	case pg_query.JoinType_JOIN_INNER:
+		joinType = backend.InnerJoin
+	case pg_query.JoinType_JOIN_LEFT:
+		joinType = backend.LeftJoin
+	case pg_query.JoinType_JOIN_RIGHT:
+		joinType = backend.RightJoin
+	case pg_query.JoinType_JOIN_FULL:
+		joinType = backend.FullJoin
+	default:
+		return nil, fmt.Errorf("Don't support such query: %v\n", pg.query)
+	}
+
+	usingCols := make([]string, 0)
+	for _, col := range join.GetUsingClause() {
+		usingCols = append(usingCols, strings.ToLower(col.GetString_().GetStr()))
+	}
+	if len(usingCols) == 0 {
+		usingCols = nil
+	}
+
+	var ra RelationalAlgebraNode = &JoinNode{
+		JoinType:  joinType,
+		Left:      left,
+		Right:     right,
+		Condition: constructExprNode(join.GetQuals()),
+		UsingCols: usingCols,
+		Natural:   join.GetIsNatural(),
+		Lateral:   isLateral(join.GetRarg()),
+	}
+	if alias := join.GetAlias().GetAliasname(); alias != "" {
+		ra = &RenameTableNode{
+			Alias: alias,
+			Table: ra,
+		}
+	}
+
+	return ra, nil
+}
+
 func crossJoinRA(ras []RelationalAlgebraNode) RelationalAlgebraNode {
 
 	return &CrossJoinNode{
@@ -294,12 +932,22 @@ func crossJoinRA(ras []RelationalAlgebraNode) RelationalAlgebraNode {
 	}
 }
 
-func constructWhereNode(whereTree *pg_query.Node, table RelationalAlgebraNode) RelationalAlgebraNode {
+func constructWhereNode(whereTree *pg_query.Node, table RelationalAlgebraNode) (RelationalAlgebraNode, error) {
 	cond := constructExprNode(whereTree)
+	if findExpr(cond, isAggCall) {
+		return nil, errors.New("ERROR:  aggregate functions are not allowed in WHERE")
+	}
+	if findExpr(cond, isGroupingFunc) {
+		return nil, errors.New("ERROR:  grouping operations are not allowed in WHERE")
+	}
+	if findExpr(cond, isWindowFunc) {
+		return nil, errors.New("ERROR:  window functions are not allowed in WHERE")
+	}
+
 	return &WhereNode{
 		Condition: cond,
 		Table:     table,
-	}
+	}, nil
 }
 
 func interpreteTargetList(targetList []*pg_query.Node) (core.ColumnNames, []ExpressionNode) {
@@ -320,17 +968,60 @@ func interpreteTargetList(targetList []*pg_query.Node) (core.ColumnNames, []Expr
 				names = append(names, colName)
 				resExprs = append(resExprs, ColRefNode{ColName: colName})
 			}
+		} else if funcCall := val.GetFuncCall(); funcCall != nil {
+			// The column is named after the function as in PostgreSQL
+			names = append(names, core.ColumnName{Name: funcName(funcCall)})
+			resExprs = append(resExprs, constructExprNode(val))
+		} else if name, ok := builtinExprName(val); ok {
+			names = append(names, core.ColumnName{Name: name})
+			resExprs = append(resExprs, constructExprNode(val))
+		} else if typeCast := val.GetTypeCast(); typeCast != nil {
+			names = append(names, typeCastColName(typeCast))
+			resExprs = append(resExprs, constructExprNode(val))
+		} else if val.GetSubLink() != nil {
+			expr := constructExprNode(val)
+			names = append(names, subLinkColName(expr))
+			resExprs = append(resExprs, expr)
+		} else if val.GetGroupingFunc() != nil {
+			names = append(names, core.ColumnName{Name: "grouping"})
+			resExprs = append(resExprs, constructExprNode(val))
 		} else {
 			// The column is an expression.
 			// This column is not included in given table.
 			names = append(names, core.ColumnName{})
 			resExprs = append(resExprs, constructExprNode(val))
 		}
+		if alias := target.GetResTarget().GetName(); alias != "" {
+			names[len(names)-1] = core.ColumnName{Name: alias}
+		}
 	}
 
 	return names, resExprs
 }
 
+// builtinExprName returns the name of the function for COALESCE, NULLIF, GREATEST and LEAST,
+// which have their own syntax
+func builtinExprName(node *pg_query.Node) (string, bool) {
+	switch {
+	case node.GetCoalesceExpr() != nil:
+		return "coalesce", true
+	case node.GetMinMaxExpr() != nil:
+		return minMaxFuncName(node.GetMinMaxExpr()), true
+	case node.GetAExpr().GetKind() == pg_query.A_Expr_Kind_AEXPR_NULLIF:
+		return "nullif", true
+	}
+
+	return "", false
+}
+
+func minMaxFuncName(node *pg_query.MinMaxExpr) string {
+	if node.GetOp() == pg_query.MinMaxOp_IS_LEAST {
+		return "least"
+	}
+
+	return "greatest"
+}
+
 func interpreteUpdateTargetList(targetList []*pg_query.Node) (core.ColumnNames, []ExpressionNode) {
 	if targetList == nil {
 		return nil, nil
@@ -352,61 +1043,238 @@ func interpreteUpdateTargetList(targetList []*pg_query.Node) (core.ColumnNames,
 func (pg *PGTranlator) TranslateCreateTable(stmt *pg_query.CreateStmt) (RelationalAlgebraNode, error) {
 	tableName := strings.ToLower(stmt.GetRelation().GetRelname())
 	colDefs := prepareColDefs(stmt.GetTableElts(), tableName)
+	persistence := interpretPersistence(stmt.GetRelation().GetRelpersistence())
+	onCommit, err := interpretOnCommit(stmt.GetOncommit(), persistence)
+	if err != nil {
+		return nil, err
+	}
+
+	uniqueKeys, err := prepareUniqueKeys(stmt.GetTableElts(), tableName)
+	if err != nil {
+		return nil, err
+	}
 
 	return &CreateTableNode{
-		TableName:  tableName,
-		ColumnDefs: colDefs,
+		TableName:   tableName,
+		ColumnDefs:  colDefs,
+		UniqueKeys:  uniqueKeys,
+		Persistence: persistence,
+		OnCommit:    onCommit,
+		IfNotExists: stmt.GetIfNotExists(),
+	}, nil
+}
+
+// TranslateCreateTableAs translates sql parse tree into CreateTableAsNode
+func (pg *PGTranlator) TranslateCreateTableAs(stmt *pg_query.CreateTableAsStmt) (RelationalAlgebraNode, error) {
+	query := stmt.GetQuery().GetSelectStmt()
+	if stmt.GetRelkind() != pg_query.ObjectType_OBJECT_TABLE || query == nil {
+		return nil, fmt.Errorf("Don't support such query: %v\n", pg.query)
+	}
+
+	ra, err := pg.TranslateSelect(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCreateTableAsNode(stmt.GetInto(), ra, stmt.GetIfNotExists())
+}
+
+// TranslateSelectInto translates SELECT INTO statement into CreateTableAsNode
+func (pg *PGTranlator) TranslateSelectInto(stmt *pg_query.SelectStmt) (RelationalAlgebraNode, error) {
+	ra, err := pg.TranslateSelect(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCreateTableAsNode(stmt.GetIntoClause(), ra, false)
+}
+
+func newCreateTableAsNode(into *pg_query.IntoClause, query RelationalAlgebraNode, ifNotExists bool) (RelationalAlgebraNode, error) {
+	persistence := interpretPersistence(into.GetRel().GetRelpersistence())
+	onCommit, err := interpretOnCommit(into.GetOnCommit(), persistence)
+	if err != nil {
+		return nil, err
+	}
+
+	colNames := make([]string, 0)
+	for _, name := range into.GetColNames() {
+		colNames = append(colNames, name.GetString_().GetStr())
+	}
+
+	return &CreateTableAsNode{
+		TableName:   strings.ToLower(into.GetRel().GetRelname()),
+		ColNames:    colNames,
+		Persistence: persistence,
+		OnCommit:    onCommit,
+		IfNotExists: ifNotExists,
+		WithNoData:  into.GetSkipData(),
+		Query:       query,
 	}, nil
 }
 
-// TranslateInsert translates sql parse tree into InsertNode
-func (pg *PGTranlator) TranslateInsert(stmt *pg_query.InsertStmt) (RelationalAlgebraNode, error) {
-	tableName := strings.ToLower(stmt.GetRelation().GetRelname())
-	rawValsLists := stmt.GetSelectStmt().GetSelectStmt().GetValuesLists()
+func interpretOnCommit(action pg_query.OnCommitAction, persistence core.Persistence) (core.OnCommitAction, error) {
+	var onCommit core.OnCommitAction
+	switch action {
+	case pg_query.OnCommitAction_ONCOMMIT_DELETE_ROWS:
+		onCommit = core.DeleteRows
+	case pg_query.OnCommitAction_ONCOMMIT_DROP:
+		onCommit = core.Drop
+	}
+	if onCommit != core.PreserveRows && persistence != core.Temporary {
+		return onCommit, errors.New("ERROR:  ON COMMIT can only be used on temporary tables")
+	}
+
+	return onCommit, nil
+}
+
+func interpretPersistence(relpersistence string) core.Persistence {
+	switch relpersistence {
+	case "t":
+		return core.Temporary
+	case "u":
+		return core.Unlogged
+	}
+
+	return core.Permanent
+}
+
+// qualifiedTableName returns the table name of RangeVar including its schema name if it is specified.
+func qualifiedTableName(rv *pg_query.RangeVar) string {
+	tableName := strings.ToLower(rv.GetRelname())
+	if schema := rv.GetSchemaname(); schema != "" {
+		return strings.ToLower(schema) + "." + tableName
+	}
+
+	return tableName
+}
+
+// TranslateInsert translates sql parse tree into InsertNode.
+// The rows to be inserted are given by any query, and DEFAULT VALUES is expressed by nil Source.
+func (pg *PGTranlator) TranslateInsert(stmt *pg_query.InsertStmt) (RelationalAlgebraNode, error) {
+	tableName := qualifiedTableName(stmt.GetRelation())
+
+	var source RelationalAlgebraNode
+	if sel := stmt.GetSelectStmt().GetSelectStmt(); sel != nil {
+		var err error
+		if sel.GetValuesLists() != nil {
+			// DEFAULT is allowed only in VALUES directly given to INSERT
+			source = translateValues(sel.GetValuesLists(), true)
+		} else {
+			source, err = pg.TranslateSelect(sel)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cols := stmt.GetCols()
+	colNames := make(core.ColumnNames, 0, len(cols))
+	for _, col := range cols {
+		colNames = append(colNames, core.ColumnName{
+			TableName: strings.ToLower(stmt.GetRelation().GetRelname()),
+			Name:      strings.ToLower(col.GetResTarget().GetName()),
+		})
+	}
+
+	onConflict, err := constructOnConflict(stmt.GetOnConflictClause())
+	if err != nil {
+		return nil, err
+	}
+
+	return pg.translateWith(stmt.GetWithClause(), &InsertNode{
+		TableName:   tableName,
+		Alias:       stmt.GetRelation().GetAlias().GetAliasname(),
+		ColumnNames: colNames,
+		Source:      source,
+		OnConflict:  onConflict,
+		Returning:   constructReturning(stmt.GetReturningList()),
+	})
+}
+
+func constructOnConflict(clause *pg_query.OnConflictClause) (*OnConflictClause, error) {
+	if clause == nil {
+		return nil, nil
+	}
+	if clause.GetAction() == pg_query.OnConflictAction_ONCONFLICT_UPDATE && clause.GetInfer() == nil {
+		return nil, errors.New("ERROR:  ON CONFLICT DO UPDATE requires inference specification or constraint name")
+	}
+
+	target := make(core.ColumnNames, 0)
+	for _, elem := range clause.GetInfer().GetIndexElems() {
+		target = append(target, core.ColumnName{Name: strings.ToLower(elem.GetIndexElem().GetName())})
+	}
+	onConflict := &OnConflictClause{
+		Target:         target,
+		ConstraintName: clause.GetInfer().GetConname(),
+		DoUpdate:       clause.GetAction() == pg_query.OnConflictAction_ONCONFLICT_UPDATE,
+		Condition:      constructExprNode(clause.GetWhereClause()),
+	}
+	onConflict.ColNames, onConflict.AssignExpr = interpreteUpdateTargetList(clause.GetTargetList())
+
+	return onConflict, nil
+}
+
+// constructReturning translates RETURNING clause into ProjectionNode.
+// The table to be projected is given when the statement is evaluated.
+func constructReturning(returningList []*pg_query.Node) *ProjectionNode {
+	if len(returningList) == 0 {
+		return nil
+	}
+	targetColNames, resTargetNodes := interpreteTargetList(returningList)
+
+	return &ProjectionNode{
+		TargetColNames: targetColNames,
+		ResTargets:     resTargetNodes,
+	}
+}
 
-	valsLists := make(core.ValuesList, 0, len(rawValsLists))
-	for _, rawVals := range rawValsLists {
-		items := rawVals.GetList().GetItems()
-		vals := make(core.Values, 0, len(items))
+func translateValues(valsLists []*pg_query.Node, allowDefault bool) RelationalAlgebraNode {
+	exprsList := make([][]ExpressionNode, 0, len(valsLists))
+	for _, vals := range valsLists {
+		items := vals.GetList().GetItems()
+		exprs := make([]ExpressionNode, 0, len(items))
 		for _, item := range items {
-			var r backend.Row
-			val, err := constructExprNode(item).Eval()(r)
-			if err != nil {
-				return nil, err
+			if allowDefault && item.GetSetToDefault() != nil {
+				exprs = append(exprs, DefaultNode{})
+				continue
 			}
-			vals = append(vals, val)
+			exprs = append(exprs, constructExprNode(item))
 		}
-		valsLists = append(valsLists, vals)
+		exprsList = append(exprsList, exprs)
 	}
 
-	cols := stmt.GetCols()
-	colNames := make(core.ColumnNames, 0, len(cols))
-	for _, col := range cols {
-		colNames = append(colNames, core.ColumnName{
-			TableName: tableName,
-			Name:      strings.ToLower(col.GetResTarget().GetName()),
-		})
+	return &ValuesNode{
+		ExprsList: exprsList,
 	}
-
-	return &InsertNode{
-		TableName:   tableName,
-		ColumnNames: colNames,
-		ValuesList:  valsLists,
-	}, nil
 }
 
 func prepareColDefs(defNodes []*pg_query.Node, tableName string) core.Cols {
 	colTyps := make(core.Cols, 0, len(defNodes))
 	for _, defNode := range defNodes {
 		def := defNode.GetColumnDef()
+		if def == nil {
+			// table constraint
+			continue
+		}
 		name := def.GetColname()
-		typ := mapGoType(def.GetTypeName().GetNames()[1].GetString_().GetStr())
+		typNames := def.GetTypeName().GetNames()
+		typName := typNames[len(typNames)-1].GetString_().GetStr()
 		col := core.Col{
 			ColName: core.ColumnName{
 				TableName: strings.ToLower(tableName),
 				Name:      strings.ToLower(name),
 			},
-			ColType: typ,
+			ColType: mapGoType(typName),
+		}
+		if isSerialType(typName) || hasIdentityConstraint(def) {
+			col.Sequence = &core.Sequence{}
+		}
+		if node := defaultExprNode(def); node != nil {
+			fn := constructExprNode(node).Eval()
+			col.Default = func() (core.Value, error) {
+				return fn(&EmptyTableRow{})
+			}
+			col.DefaultExpr = deparseExpr(node)
 		}
 		colTyps = append(colTyps, col)
 	}
@@ -414,12 +1282,116 @@ func prepareColDefs(defNodes []*pg_query.Node, tableName string) core.Cols {
 	return colTyps
 }
 
+// prepareUniqueKeys collects primary key and unique constraints of columns and the table.
+// Unnamed constraints are named in the same way as PostgreSQL.
+func prepareUniqueKeys(defNodes []*pg_query.Node, tableName string) ([]core.UniqueKey, error) {
+	var keys []core.UniqueKey
+	hasPrimaryKey := false
+	for _, defNode := range defNodes {
+		var constraints []*pg_query.Node
+		var colNames []string
+		if def := defNode.GetColumnDef(); def != nil {
+			constraints = def.GetConstraints()
+			colNames = []string{strings.ToLower(def.GetColname())}
+		} else {
+			constraints = []*pg_query.Node{defNode}
+		}
+
+		for _, node := range constraints {
+			c := node.GetConstraint()
+			if c == nil {
+				continue
+			}
+			if c.GetContype() != pg_query.ConstrType_CONSTR_PRIMARY && c.GetContype() != pg_query.ConstrType_CONSTR_UNIQUE {
+				continue
+			}
+
+			names := colNames
+			if c.GetKeys() != nil {
+				names = make([]string, 0, len(c.GetKeys()))
+				for _, k := range c.GetKeys() {
+					names = append(names, strings.ToLower(k.GetString_().GetStr()))
+				}
+			}
+
+			name := c.GetConname()
+			if c.GetContype() == pg_query.ConstrType_CONSTR_PRIMARY {
+				if hasPrimaryKey {
+					return nil, fmt.Errorf(`ERROR:  multiple primary keys for table "%v" are not allowed`, tableName)
+				}
+				hasPrimaryKey = true
+				if name == "" {
+					name = tableName + "_pkey"
+				}
+			} else if name == "" {
+				name = tableName + "_" + strings.Join(names, "_") + "_key"
+			}
+
+			key := core.UniqueKey{Name: name, Primary: c.GetContype() == pg_query.ConstrType_CONSTR_PRIMARY}
+			for _, n := range names {
+				key.ColNames = append(key.ColNames, core.ColumnName{TableName: tableName, Name: n})
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func isSerialType(typ string) bool {
+	switch typ {
+	case "serial", "serial2", "serial4", "serial8", "smallserial", "bigserial":
+		return true
+	}
+
+	return false
+}
+
+func hasIdentityConstraint(def *pg_query.ColumnDef) bool {
+	for _, c := range def.GetConstraints() {
+		if c.GetConstraint().GetContype() == pg_query.ConstrType_CONSTR_IDENTITY {
+			return true
+		}
+	}
+
+	return false
+}
+
+func defaultExprNode(def *pg_query.ColumnDef) *pg_query.Node {
+	for _, c := range def.GetConstraints() {
+		if c.GetConstraint().GetContype() == pg_query.ConstrType_CONSTR_DEFAULT {
+			return c.GetConstraint().GetRawExpr()
+		}
+	}
+
+	return nil
+}
+
+// deparseExpr returns the SQL text of the expression, which is shown by pg_get_expr
+func deparseExpr(node *pg_query.Node) string {
+	stmt := &pg_query.SelectStmt{TargetList: []*pg_query.Node{pg_query.MakeResTargetNodeWithVal(node, 0)}}
+	query, err := pg_query.Deparse(&pg_query.ParseResult{
+		Stmts: []*pg_query.RawStmt{{Stmt: &pg_query.Node{Node: &pg_query.Node_SelectStmt{SelectStmt: stmt}}}},
+	})
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(query, "SELECT ")
+}
+
 func mapGoType(typ string) core.ColType {
 	switch typ {
 	case "int4":
 		return core.Integer
-	case "varchar":
+	case "varchar", "text", "bpchar":
 		return core.VarChar
+	case "bool":
+		return core.Boolean
+	case "float4", "float8", "numeric":
+		return core.Float
+	case "date":
+		return core.Date
 	}
 
 	return core.Integer
@@ -439,9 +1411,15 @@ func getColName(colRef *pg_query.ColumnRef) core.ColumnName {
 		return core.ColumnName{TableName: tableName, Name: colName}
 	}
 
+	if len(fields) > 2 {
+		// column is specified by schema name, table name and column name
+		tableName := strings.ToLower(fields[len(fields)-2].GetString_().GetStr())
+		colName := strings.ToLower(fields[len(fields)-1].GetString_().GetStr())
+		return core.ColumnName{TableName: tableName, Name: colName}
+	}
+
 	// Not Implemented
-	// This columnRef includes schema name or something.
-	fmt.Println("Not Implemented: This columnRef includes schema name or something.")
+	fmt.Println("Not Implemented: This columnRef has no fields.")
 	return core.ColumnName{}
 }
 
@@ -481,9 +1459,38 @@ func constructExprNode(node *pg_query.Node) ExpressionNode {
 	if v := node.GetNullTest(); v != nil {
 		return constructNullTest(v)
 	}
+	if v := node.GetBooleanTest(); v != nil {
+		return constructBoolTest(v)
+	}
 	if v := node.GetCaseExpr(); v != nil {
 		return constructCaseNode(v)
 	}
+	if v := node.GetCollateClause(); v != nil {
+		return constructCollateClause(v)
+	}
+	if node.GetSetToDefault() != nil {
+		// DEFAULT is allowed only as an item of VALUES of INSERT, which is handled by translateValues
+		return &errorNode{err: errors.New("ERROR:  DEFAULT is not allowed in this context")}
+	}
+	if v := node.GetFuncCall(); v != nil {
+		return constructFuncCall(v)
+	}
+	if v := node.GetCoalesceExpr(); v != nil {
+		return &CoalesceNode{Args: constructExprNodes(v.GetArgs())}
+	}
+	if v := node.GetMinMaxExpr(); v != nil {
+		return &FuncCallNode{FuncName: minMaxFuncName(v), Args: constructExprNodes(v.GetArgs())}
+	}
+	if v := node.GetSubLink(); v != nil {
+		return constructSubLink(v)
+	}
+	if v := node.GetGroupingFunc(); v != nil {
+		args := make([]ExpressionNode, 0, len(v.GetArgs()))
+		for _, arg := range v.GetArgs() {
+			args = append(args, constructExprNode(arg))
+		}
+		return &GroupingFuncNode{Args: args}
+	}
 
 	// Not Implemented
 	fmt.Println("Not Implemented")
@@ -491,6 +1498,488 @@ func constructExprNode(node *pg_query.Node) ExpressionNode {
 	return dummy
 }
 
+// constructCollateClause translates `x COLLATE collation` to x.
+// Only the collations which compare strings byte by byte are supported, so the clause doesn't change the result.
+func constructCollateClause(c *pg_query.CollateClause) ExpressionNode {
+	names := c.GetCollname()
+	name := names[len(names)-1].GetString_().GetStr()
+	switch name {
+	case "default", "C", "POSIX":
+		return constructExprNode(c.GetArg())
+	}
+
+	return &errorNode{err: fmt.Errorf(`ERROR:  collation "%v" for encoding "UTF8" does not exist`, name)}
+}
+
+// constructSubLink translates a subquery in an expression.
+// An error is returned as errorNode because it's reported when the expression is evaluated.
+func constructSubLink(node *pg_query.SubLink) ExpressionNode {
+	subLink := &SubLinkNode{}
+	switch node.GetSubLinkType() {
+	case pg_query.SubLinkType_EXISTS_SUBLINK:
+		subLink.Type = ExistsSubLink
+	case pg_query.SubLinkType_EXPR_SUBLINK:
+		subLink.Type = ExprSubLink
+	case pg_query.SubLinkType_ANY_SUBLINK, pg_query.SubLinkType_ALL_SUBLINK:
+		subLink.Type = AnySubLink
+		if node.GetSubLinkType() == pg_query.SubLinkType_ALL_SUBLINK {
+			subLink.Type = AllSubLink
+		}
+		if node.GetTestexpr().GetRowExpr() != nil {
+			return &errorNode{err: errors.New("ERROR:  row comparison with subquery is not supported")}
+		}
+		subLink.Testexpr = constructExprNode(node.GetTestexpr())
+		// IN has no operator name
+		op := "="
+		if names := node.GetOperName(); len(names) > 0 {
+			op = names[len(names)-1].GetString_().GetStr()
+		}
+		subLink.Op = mathOperator(op)
+		if !isComparisonOp(subLink.Op) {
+			return &errorNode{err: fmt.Errorf("ERROR:  operator %v must return type boolean", op)}
+		}
+	default:
+		return &errorNode{err: errors.New("ERROR:  this kind of subquery is not supported")}
+	}
+
+	query, err := (&PGTranlator{nested: true}).TranslateSelect(node.GetSubselect().GetSelectStmt())
+	if err != nil {
+		return &errorNode{err: err}
+	}
+	subLink.Subquery = query
+
+	return subLink
+}
+
+func isComparisonOp(op MathOp) bool {
+	switch op {
+	case EqualOp, NotEqualOp, GT, LT, GEQ, LEQ:
+		return true
+	}
+
+	return false
+}
+
+// subLinkColName returns the name of the output column of a subquery in the select list
+func subLinkColName(expr ExpressionNode) core.ColumnName {
+	subLink, ok := expr.(*SubLinkNode)
+	if !ok {
+		return core.ColumnName{}
+	}
+	switch subLink.Type {
+	case ExistsSubLink:
+		return core.ColumnName{Name: "exists"}
+	case ExprSubLink:
+		// A scalar subquery is named after the column of the subquery
+		if p, ok := subLink.Subquery.(*ProjectionNode); ok && len(p.TargetColNames) > 0 {
+			return core.ColumnName{Name: p.TargetColNames[0].Name}
+		}
+	}
+
+	return core.ColumnName{}
+}
+
+func constructFuncCall(node *pg_query.FuncCall) ExpressionNode {
+	name := funcName(node)
+	if over := node.GetOver(); over != nil {
+		return constructWindowFunc(node, over)
+	}
+	if _, ok := windowFuncs[name]; ok {
+		return &errorNode{err: fmt.Errorf("ERROR:  window function %v requires an OVER clause", name)}
+	}
+	if _, ok := aggregateFuncs[name]; ok {
+		args := make([]ExpressionNode, 0, len(node.GetArgs()))
+		for _, arg := range node.GetArgs() {
+			args = append(args, constructExprNode(arg))
+		}
+		return &AggCallNode{
+			FuncName: name,
+			Args:     args,
+			Star:     node.GetAggStar(),
+			Distinct: node.GetAggDistinct(),
+			Filter:   constructExprNode(node.GetAggFilter()),
+			OrderBy:  interpretSortKeys(node.GetAggOrder()),
+		}
+	}
+	switch {
+	case node.GetAggStar():
+		return &errorNode{err: fmt.Errorf("ERROR:  %v(*) specified, but %v is not an aggregate function", name, name)}
+	case node.GetAggDistinct():
+		return &errorNode{err: fmt.Errorf("ERROR:  DISTINCT specified, but %v is not an aggregate function", name)}
+	case len(node.GetAggOrder()) > 0:
+		return &errorNode{err: fmt.Errorf("ERROR:  ORDER BY specified, but %v is not an aggregate function", name)}
+	case node.GetAggFilter() != nil:
+		return &errorNode{err: fmt.Errorf("ERROR:  FILTER specified, but %v is not an aggregate function", name)}
+	}
+	if _, ok := setReturningFuncs[name]; ok {
+		return &SetReturningFuncNode{FuncName: name, Args: constructExprNodes(node.GetArgs())}
+	}
+
+	return &FuncCallNode{FuncName: name, Args: constructExprNodes(node.GetArgs())}
+}
+
+func constructExprNodes(nodes []*pg_query.Node) []ExpressionNode {
+	exprs := make([]ExpressionNode, 0, len(nodes))
+	for _, node := range nodes {
+		exprs = append(exprs, constructExprNode(node))
+	}
+
+	return exprs
+}
+
+// constructWindowFunc constructs a window function call. An aggregate function with OVER clause
+// is also a window function call.
+func constructWindowFunc(node *pg_query.FuncCall, over *pg_query.WindowDef) ExpressionNode {
+	name := funcName(node)
+	_, isWindowFunc := windowFuncs[name]
+	_, isAgg := aggregateFuncs[name]
+	switch {
+	case !isWindowFunc && !isAgg:
+		return &errorNode{err: fmt.Errorf("ERROR:  OVER specified, but %v is not a window function nor an aggregate function", name)}
+	case node.GetAggDistinct():
+		return &errorNode{err: errors.New("ERROR:  DISTINCT is not implemented for window functions")}
+	case len(node.GetAggOrder()) > 0:
+		return &errorNode{err: errors.New("ERROR:  aggregate ORDER BY is not implemented for window functions")}
+	case node.GetAggFilter() != nil && !isAgg:
+		return &errorNode{err: errors.New("ERROR:  FILTER is not implemented for non-aggregate window functions")}
+	}
+
+	args := make([]ExpressionNode, 0, len(node.GetArgs()))
+	for _, arg := range node.GetArgs() {
+		args = append(args, constructExprNode(arg))
+	}
+	fn := &WindowFuncNode{
+		FuncName: name,
+		Args:     args,
+		Star:     node.GetAggStar(),
+		Filter:   constructExprNode(node.GetAggFilter()),
+	}
+	if over.GetName() != "" {
+		// OVER name refers to the window in WINDOW clause as it is
+		fn.WindowName = over.GetName()
+	} else {
+		fn.Window = constructWindowDef(over)
+	}
+
+	return fn
+}
+
+// frame options of WindowDef
+const (
+	frameOptionNonDefault              = 0x1
+	frameOptionRows                    = 0x4
+	frameOptionGroups                  = 0x8
+	frameOptionBetween                 = 0x10
+	frameOptionStartUnboundedPreceding = 0x20
+	frameOptionEndUnboundedPreceding   = 0x40
+	frameOptionStartUnboundedFollowing = 0x80
+	frameOptionEndUnboundedFollowing   = 0x100
+	frameOptionStartCurrentRow         = 0x200
+	frameOptionEndCurrentRow           = 0x400
+	frameOptionStartOffsetPreceding    = 0x800
+	frameOptionEndOffsetPreceding      = 0x1000
+	frameOptionStartOffsetFollowing    = 0x2000
+	frameOptionEndOffsetFollowing      = 0x4000
+	frameOptionExcludeCurrentRow       = 0x8000
+	frameOptionExcludeGroup            = 0x10000
+	frameOptionExcludeTies             = 0x20000
+)
+
+func constructWindowDef(def *pg_query.WindowDef) *WindowDef {
+	partitionBy := make([]ExpressionNode, 0, len(def.GetPartitionClause()))
+	for _, expr := range def.GetPartitionClause() {
+		partitionBy = append(partitionBy, constructExprNode(expr))
+	}
+
+	return &WindowDef{
+		RefName:     def.GetRefname(),
+		PartitionBy: partitionBy,
+		OrderBy:     interpretSortKeys(def.GetOrderClause()),
+		Frame:       constructWindowFrame(def),
+	}
+}
+
+// constructWindowFrame constructs the frame clause. It returns nil if the window doesn't have frame clause.
+func constructWindowFrame(def *pg_query.WindowDef) *WindowFrame {
+	opts := def.GetFrameOptions()
+	if opts&frameOptionNonDefault == 0 {
+		return nil
+	}
+
+	frame := &WindowFrame{Mode: RangeFrame}
+	switch {
+	case opts&frameOptionRows != 0:
+		frame.Mode = RowsFrame
+	case opts&frameOptionGroups != 0:
+		frame.Mode = GroupsFrame
+	}
+
+	switch {
+	case opts&frameOptionStartUnboundedPreceding != 0:
+		frame.Start = FrameBound{Type: UnboundedPreceding}
+	case opts&frameOptionStartUnboundedFollowing != 0:
+		frame.Start = FrameBound{Type: UnboundedFollowing}
+	case opts&frameOptionStartOffsetPreceding != 0:
+		frame.Start = FrameBound{Type: OffsetPreceding, Offset: constructExprNode(def.GetStartOffset())}
+	case opts&frameOptionStartOffsetFollowing != 0:
+		frame.Start = FrameBound{Type: OffsetFollowing, Offset: constructExprNode(def.GetStartOffset())}
+	default:
+		frame.Start = FrameBound{Type: CurrentRow}
+	}
+
+	switch {
+	case opts&frameOptionBetween == 0:
+		// the frame without BETWEEN ends at the current row
+		frame.End = FrameBound{Type: CurrentRow}
+	case opts&frameOptionEndUnboundedPreceding != 0:
+		frame.End = FrameBound{Type: UnboundedPreceding}
+	case opts&frameOptionEndUnboundedFollowing != 0:
+		frame.End = FrameBound{Type: UnboundedFollowing}
+	case opts&frameOptionEndOffsetPreceding != 0:
+		frame.End = FrameBound{Type: OffsetPreceding, Offset: constructExprNode(def.GetEndOffset())}
+	case opts&frameOptionEndOffsetFollowing != 0:
+		frame.End = FrameBound{Type: OffsetFollowing, Offset: constructExprNode(def.GetEndOffset())}
+	default:
+		frame.End = FrameBound{Type: CurrentRow}
+	}
+
+	switch {
+	case opts&frameOptionExcludeCurrentRow != 0:
+		frame.Exclude = ExcludeCurrentRow
+	case opts&frameOptionExcludeGroup != 0:
+		frame.Exclude = ExcludeGroup
+	case opts&frameOptionExcludeTies != 0:
+		frame.Exclude = ExcludeTies
+	}
+
+	return frame
+}
+
+// interpretWindowClause interprets WINDOW clause. A window can be based on the windows defined before it.
+func interpretWindowClause(windowClause []*pg_query.Node) (map[string]*WindowDef, error) {
+	windows := make(map[string]*WindowDef)
+	for _, node := range windowClause {
+		def := node.GetWindowDef()
+		name := def.GetName()
+		if _, ok := windows[name]; ok {
+			return nil, fmt.Errorf("ERROR:  window \"%v\" is already defined", name)
+		}
+		window, err := resolveWindow(constructWindowDef(def), windows)
+		if err != nil {
+			return nil, err
+		}
+		windows[name] = window
+	}
+
+	return windows, nil
+}
+
+// resolveWindow resolves the window which the window is based on.
+// The window inherits PARTITION BY clause and ORDER BY clause but can't override them.
+func resolveWindow(def *WindowDef, windows map[string]*WindowDef) (*WindowDef, error) {
+	if def.RefName == "" {
+		return def, nil
+	}
+	ref, ok := windows[def.RefName]
+	if !ok {
+		return nil, fmt.Errorf("ERROR:  window \"%v\" does not exist", def.RefName)
+	}
+	if len(def.PartitionBy) > 0 {
+		return nil, fmt.Errorf("ERROR:  cannot override PARTITION BY clause of window \"%v\"", def.RefName)
+	}
+	if len(ref.OrderBy) > 0 && len(def.OrderBy) > 0 {
+		return nil, fmt.Errorf("ERROR:  cannot override ORDER BY clause of window \"%v\"", def.RefName)
+	}
+	if ref.Frame != nil {
+		return nil, fmt.Errorf("ERROR:  cannot copy window \"%v\" because it has a frame clause", def.RefName)
+	}
+
+	window := &WindowDef{PartitionBy: ref.PartitionBy, OrderBy: def.OrderBy, Frame: def.Frame}
+	if len(def.OrderBy) == 0 {
+		window.OrderBy = ref.OrderBy
+	}
+
+	return window, nil
+}
+
+// resolveWindowFuncs resolves the windows of window functions in exprs by WINDOW clause
+func resolveWindowFuncs(exprs []ExpressionNode, windows map[string]*WindowDef) ([]ExpressionNode, error) {
+	var err error
+	resolved := make([]ExpressionNode, 0, len(exprs))
+	for _, expr := range exprs {
+		resolved = append(resolved, transformExpr(expr, func(e ExpressionNode) (ExpressionNode, bool) {
+			fn, ok := e.(*WindowFuncNode)
+			if !ok {
+				return nil, false
+			}
+			newFn := *fn
+			if fn.WindowName != "" {
+				window, ok := windows[fn.WindowName]
+				if !ok {
+					err = fmt.Errorf("ERROR:  window \"%v\" does not exist", fn.WindowName)
+					return e, true
+				}
+				newFn.WindowName, newFn.Window = "", window
+				return &newFn, true
+			}
+			window, resolveErr := resolveWindow(fn.Window, windows)
+			if resolveErr != nil {
+				err = resolveErr
+			}
+			newFn.Window = window
+			return &newFn, true
+		}))
+	}
+
+	return resolved, err
+}
+
+// constructWindowNode makes WindowNode when outputs have window functions.
+// Window function calls are replaced with references to the columns of WindowNode.
+func constructWindowNode(outputs []ExpressionNode, table RelationalAlgebraNode) (RelationalAlgebraNode, []ExpressionNode, error) {
+	window := &WindowNode{RANode: table}
+	var err error
+	newOutputs := make([]ExpressionNode, 0, len(outputs))
+	for _, output := range outputs {
+		newOutputs = append(newOutputs, transformExpr(output, func(e ExpressionNode) (ExpressionNode, bool) {
+			fn, ok := e.(*WindowFuncNode)
+			if !ok {
+				return nil, false
+			}
+			if addErr := window.addFunc(fn); addErr != nil {
+				err = addErr
+			}
+			return &ColRefNode{ColName: windowColName(len(window.Funcs) - 1)}, true
+		}))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(window.Funcs) == 0 {
+		return table, outputs, nil
+	}
+
+	return window, newOutputs, nil
+}
+
+// constructProjectSetNode makes ProjectSetNode when outputs have set-returning functions.
+// Set-returning function calls are replaced with references to the columns of ProjectSetNode.
+func constructProjectSetNode(outputs []ExpressionNode, table RelationalAlgebraNode) (RelationalAlgebraNode, []ExpressionNode, error) {
+	projectSet := &ProjectSetNode{RANode: table}
+	var err error
+	newOutputs := make([]ExpressionNode, 0, len(outputs))
+	for _, output := range outputs {
+		newOutputs = append(newOutputs, transformExpr(output, func(e ExpressionNode) (ExpressionNode, bool) {
+			fn, ok := e.(*SetReturningFuncNode)
+			if !ok {
+				return nil, false
+			}
+			for _, arg := range fn.Args {
+				if findExpr(arg, isSetReturningFunc) {
+					err = errors.New("ERROR:  set-returning function calls cannot be nested")
+				}
+			}
+			projectSet.Funcs = append(projectSet.Funcs, fn)
+			return &ColRefNode{ColName: srfColName(len(projectSet.Funcs) - 1)}, true
+		}))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(projectSet.Funcs) == 0 {
+		return table, outputs, nil
+	}
+
+	return projectSet, newOutputs, nil
+}
+
+func (w *WindowNode) addFunc(fn *WindowFuncNode) error {
+	w.Funcs = append(w.Funcs, fn)
+
+	exprs := append([]ExpressionNode{fn.Filter}, fn.Args...)
+	exprs = append(exprs, fn.Window.PartitionBy...)
+	for _, key := range fn.Window.OrderBy {
+		exprs = append(exprs, key.Expr)
+	}
+	for _, expr := range exprs {
+		if findExpr(expr, isWindowFunc) {
+			return errors.New("ERROR:  window function calls cannot be nested")
+		}
+	}
+
+	if wf, ok := windowFuncs[fn.FuncName]; ok {
+		if fn.Star {
+			return fmt.Errorf("ERROR:  function %v(*) does not exist", fn.FuncName)
+		}
+		if len(fn.Args) < wf.minArgs || len(fn.Args) > wf.maxArgs {
+			return fmt.Errorf("ERROR:  function %v with %d arguments does not exist", fn.FuncName, len(fn.Args))
+		}
+	} else {
+		agg := aggregateFuncs[fn.FuncName]
+		if fn.Star {
+			if !agg.allowStar {
+				return fmt.Errorf("ERROR:  function %v(*) does not exist", fn.FuncName)
+			}
+		} else if len(fn.Args) != agg.nargs {
+			return fmt.Errorf("ERROR:  function %v with %d arguments does not exist", fn.FuncName, len(fn.Args))
+		}
+	}
+
+	frame := fn.Window.Frame
+	if frame == nil {
+		return nil
+	}
+	for _, bound := range []FrameBound{frame.Start, frame.End} {
+		if bound.Offset == nil {
+			continue
+		}
+		if refs, ok := columnRefs(bound.Offset); !ok || len(refs) > 0 {
+			return fmt.Errorf("ERROR:  argument of %v must not contain variables", frame.Mode)
+		}
+		if frame.Mode == RangeFrame && len(fn.Window.OrderBy) != 1 {
+			return errors.New("ERROR:  RANGE with offset PRECEDING/FOLLOWING requires exactly one ORDER BY column")
+		}
+	}
+
+	return nil
+}
+
+func interpretSortKeys(sortClause []*pg_query.Node) []SortKey {
+	if len(sortClause) == 0 {
+		return nil
+	}
+
+	keys := make([]SortKey, 0, len(sortClause))
+	for _, node := range sortClause {
+		sortBy := node.GetSortBy()
+		key := SortKey{
+			Expr: constructExprNode(sortBy.GetNode()),
+			Desc: sortBy.GetSortbyDir() == pg_query.SortByDir_SORTBY_DESC,
+		}
+		switch sortBy.GetSortbyNulls() {
+		case pg_query.SortByNulls_SORTBY_NULLS_FIRST:
+			key.NullsFirst = true
+		case pg_query.SortByNulls_SORTBY_NULLS_LAST:
+			key.NullsFirst = false
+		default:
+			key.NullsFirst = key.Desc
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// funcName returns the name of the function without schema name
+func funcName(node *pg_query.FuncCall) string {
+	names := node.GetFuncname()
+	if len(names) == 0 {
+		return ""
+	}
+
+	return strings.ToLower(names[len(names)-1].GetString_().GetStr())
+}
+
 func constructCaseNode(node *pg_query.CaseExpr) ExpressionNode {
 	var caseWhenExprs, caseResultExprs []ExpressionNode
 	if arg := node.GetArg(); arg != nil {
@@ -563,6 +2052,20 @@ func constructNullTest(node *pg_query.NullTest) ExpressionNode {
 	return nil
 }
 
+func constructBoolTest(node *pg_query.BooleanTest) ExpressionNode {
+	expr := constructExprNode(node.GetArg())
+	testTypes := map[pg_query.BoolTestType]BoolTestType{
+		pg_query.BoolTestType_IS_TRUE:        IsTrue,
+		pg_query.BoolTestType_IS_NOT_TRUE:    IsNotTrue,
+		pg_query.BoolTestType_IS_FALSE:       IsFalse,
+		pg_query.BoolTestType_IS_NOT_FALSE:   IsNotFalse,
+		pg_query.BoolTestType_IS_UNKNOWN:     IsUnknown,
+		pg_query.BoolTestType_IS_NOT_UNKNOWN: IsNotUnknown,
+	}
+
+	return &BoolTestNode{TestType: testTypes[node.GetBooltesttype()], Expr: expr}
+}
+
 func constructBoolExprNode(node *pg_query.BoolExpr) ExpressionNode {
 	opType := node.GetBoolop()
 	switch opType {
@@ -570,6 +2073,8 @@ func constructBoolExprNode(node *pg_query.BoolExpr) ExpressionNode {
 		return constructANDExpr(node.GetArgs())
 	case 2: // OR
 		return constructORExpr(node.GetArgs())
+	case 3: // NOT
+		return &NotNode{Expr: constructExprNode(node.GetArgs()[0])}
 	}
 
 	return nil
@@ -624,19 +2129,73 @@ func constructColumnRef(node *pg_query.ColumnRef) ExpressionNode {
 	}
 }
 
+// interpretTypeCast translates a type cast. TRUE and FALSE are also casts of 't' and 'f' to bool.
 func interpretTypeCast(c *pg_query.TypeCast) ExpressionNode {
-	// Now, only support bool
-	boolStr := c.GetArg().GetAConst().GetVal().GetString_().GetStr()
-	if boolStr == "t" {
-		return BoolConstNode{Bool: core.True}
+	name := typeCastName(c)
+	if len(c.GetTypeName().GetArrayBounds()) > 0 {
+		return &errorNode{err: fmt.Errorf("ERROR:  array type %v[] is not supported", name)}
+	}
+	if _, ok := castTypes[name]; !ok {
+		return &errorNode{err: fmt.Errorf(`ERROR:  type "%v" does not exist`, name)}
 	}
-	return BoolConstNode{Bool: core.False}
+
+	var mods []int
+	for _, mod := range c.GetTypeName().GetTypmods() {
+		mods = append(mods, int(mod.GetAConst().GetVal().GetInteger().GetIval()))
+	}
+
+	return &TypeCastNode{
+		Expr:     constructExprNode(c.GetArg()),
+		TypeName: name,
+		TypeMods: mods,
+	}
+}
+
+func typeCastName(c *pg_query.TypeCast) string {
+	names := c.GetTypeName().GetNames()
+
+	return strings.ToLower(names[len(names)-1].GetString_().GetStr())
+}
+
+// typeCastColName names the column of a type cast after its argument as in PostgreSQL.
+// If the argument is neither a column nor a function call, the column is named after the outermost type.
+func typeCastColName(c *pg_query.TypeCast) core.ColumnName {
+	arg := c.GetArg()
+	for arg.GetTypeCast() != nil {
+		arg = arg.GetTypeCast().GetArg()
+	}
+	switch {
+	case arg.GetColumnRef() != nil && arg.GetColumnRef().GetFields()[0].GetAStar() == nil:
+		return core.ColumnName{Name: getColName(arg.GetColumnRef()).Name}
+	case arg.GetFuncCall() != nil:
+		return core.ColumnName{Name: funcName(arg.GetFuncCall())}
+	}
+
+	return core.ColumnName{Name: typeCastName(c)}
 }
 
 func constructGetAExprNode(aExpr *pg_query.A_Expr) ExpressionNode {
-	op := mathOperator(aExpr.GetName()[0].GetString_().GetStr())
 	lexpr := constructExprNode(aExpr.GetLexpr())
+	switch kind := aExpr.GetKind(); kind {
+	case pg_query.A_Expr_Kind_AEXPR_OP, pg_query.A_Expr_Kind_AEXPR_LIKE, pg_query.A_Expr_Kind_AEXPR_ILIKE, pg_query.A_Expr_Kind_AEXPR_SIMILAR:
+	case pg_query.A_Expr_Kind_AEXPR_IN:
+		return constructInListExpr(aExpr, lexpr)
+	case pg_query.A_Expr_Kind_AEXPR_BETWEEN, pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN,
+		pg_query.A_Expr_Kind_AEXPR_BETWEEN_SYM, pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN_SYM:
+		return constructBetweenExpr(aExpr, lexpr)
+	case pg_query.A_Expr_Kind_AEXPR_NULLIF:
+		return &NullIfNode{Lexpr: lexpr, Rexpr: constructExprNode(aExpr.GetRexpr())}
+	case pg_query.A_Expr_Kind_AEXPR_DISTINCT, pg_query.A_Expr_Kind_AEXPR_NOT_DISTINCT:
+		return &DistinctFromNode{
+			Not:   kind == pg_query.A_Expr_Kind_AEXPR_NOT_DISTINCT,
+			Lexpr: lexpr,
+			Rexpr: constructExprNode(aExpr.GetRexpr()),
+		}
+	default:
+		return &errorNode{err: fmt.Errorf("ERROR:  %v is not implemented", kind)}
+	}
 	rexpr := constructExprNode(aExpr.GetRexpr())
+	op := mathOperator(operatorName(aExpr))
 
 	return &BinOpNode{
 		Op:    op,
@@ -645,6 +2204,57 @@ func constructGetAExprNode(aExpr *pg_query.A_Expr) ExpressionNode {
 	}
 }
 
+// operatorName returns the name of the operator.
+// A schema-qualified operator such as OPERATOR(pg_catalog.~) is the built-in one.
+func operatorName(aExpr *pg_query.A_Expr) string {
+	names := aExpr.GetName()
+
+	return names[len(names)-1].GetString_().GetStr()
+}
+
+// constructInListExpr translates `x IN (a, b, ...)` to `x = a OR x = b OR ...`
+// and `x NOT IN (a, b, ...)` to `x <> a AND x <> b AND ...` as PostgreSQL does.
+func constructInListExpr(aExpr *pg_query.A_Expr, lexpr ExpressionNode) ExpressionNode {
+	op := mathOperator(operatorName(aExpr))
+	var expr ExpressionNode
+	for _, item := range aExpr.GetRexpr().GetList().GetItems() {
+		cmp := &BinOpNode{Op: op, Lexpr: lexpr, Rexpr: constructExprNode(item)}
+		switch {
+		case expr == nil:
+			expr = cmp
+		case op == NotEqualOp:
+			expr = &ANDNode{Lexpr: expr, Rexpr: cmp}
+		default:
+			expr = &ORNode{Lexpr: expr, Rexpr: cmp}
+		}
+	}
+
+	return expr
+}
+
+// constructBetweenExpr translates `x BETWEEN a AND b` to `x >= a AND x <= b`.
+// `x BETWEEN SYMMETRIC a AND b` is also true if `x >= b AND x <= a`.
+func constructBetweenExpr(aExpr *pg_query.A_Expr, lexpr ExpressionNode) ExpressionNode {
+	bounds := constructExprNodes(aExpr.GetRexpr().GetList().GetItems())
+	between := func(lower, upper ExpressionNode) ExpressionNode {
+		return &ANDNode{
+			Lexpr: &BinOpNode{Op: GEQ, Lexpr: lexpr, Rexpr: lower},
+			Rexpr: &BinOpNode{Op: LEQ, Lexpr: lexpr, Rexpr: upper},
+		}
+	}
+
+	expr := between(bounds[0], bounds[1])
+	kind := aExpr.GetKind()
+	if kind == pg_query.A_Expr_Kind_AEXPR_BETWEEN_SYM || kind == pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN_SYM {
+		expr = &ORNode{Lexpr: expr, Rexpr: between(bounds[1], bounds[0])}
+	}
+	if kind == pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN || kind == pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN_SYM {
+		return &NotNode{Expr: expr}
+	}
+
+	return expr
+}
+
 func mathOperator(op string) MathOp {
 	// ref: translator/const.go: MathOp
 	// ref: translator/expression.go: func (e BinOpNode) Eval()
@@ -672,6 +2282,22 @@ func mathOperator(op string) MathOp {
 		return LEQ
 	case "||":
 		return CONCAT
+	case "~~":
+		return Like
+	case "!~~":
+		return NotLike
+	case "~~*":
+		return ILike
+	case "!~~*":
+		return NotILike
+	case "~":
+		return RegexMatch
+	case "~*":
+		return RegexIMatch
+	case "!~":
+		return NotRegexMatch
+	case "!~*":
+		return NotRegexIMatch
 	}
 
 	fmt.Println("Not Implemented math operator")
@@ -3,6 +3,7 @@ package translator_test
 import (
 	"testing"
 
+	"github.com/goropikari/psqlittle/backend"
 	"github.com/goropikari/psqlittle/core"
 	trans "github.com/goropikari/psqlittle/translator"
 	"github.com/stretchr/testify/assert"
@@ -64,6 +65,383 @@ func TestTranslateSelect(t *testing.T) {
 			},
 			query: "SELECT * FROM foo",
 		},
+		{
+			name: "test left join",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{
+						core.ColumnName{Name: "*"},
+					},
+					ResTargets: []trans.ExpressionNode{
+						trans.ColWildcardNode{},
+					},
+					RANode: &trans.WhereNode{
+						Condition: nil,
+						Table: &trans.CrossJoinNode{
+							RANodes: []trans.RelationalAlgebraNode{
+								&trans.JoinNode{
+									JoinType: backend.LeftJoin,
+									Left:     &trans.TableNode{TableName: "foo"},
+									Right: &trans.RenameTableNode{
+										Alias: "b",
+										Table: &trans.TableNode{TableName: "bar"},
+									},
+									Condition: &trans.BinOpNode{
+										Op:    trans.EqualOp,
+										Lexpr: &trans.ColRefNode{core.ColumnName{TableName: "foo", Name: "id"}},
+										Rexpr: &trans.ColRefNode{core.ColumnName{TableName: "b", Name: "id"}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT * FROM foo LEFT JOIN bar b ON foo.id = b.id",
+		},
+		{
+			name: "test group by",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{
+						{Name: "name"},
+						{Name: "count"},
+					},
+					ResTargets: []trans.ExpressionNode{
+						trans.ColRefNode{core.ColumnName{Name: "name"}},
+						&trans.ColRefNode{core.ColumnName{Name: "?agg1?"}},
+					},
+					RANode: &trans.AggregateNode{
+						GroupKeys: []trans.ExpressionNode{
+							&trans.ColRefNode{core.ColumnName{Name: "name"}},
+						},
+						Aggregates: []*trans.AggCallNode{
+							{FuncName: "count", Args: []trans.ExpressionNode{}, Star: true},
+						},
+						Outputs: []trans.ExpressionNode{
+							trans.ColRefNode{core.ColumnName{Name: "name"}},
+							&trans.ColRefNode{core.ColumnName{Name: "?agg1?"}},
+						},
+						RANode: &trans.WhereNode{
+							Table: &trans.CrossJoinNode{
+								RANodes: []trans.RelationalAlgebraNode{
+									&trans.TableNode{TableName: "foo"},
+								},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT name, count(*) FROM foo GROUP BY name",
+		},
+		{
+			name: "test distinct on",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{
+						{Name: "name"},
+					},
+					ResTargets: []trans.ExpressionNode{
+						trans.ColRefNode{core.ColumnName{Name: "name"}},
+					},
+					RANode: &trans.DistinctNode{
+						Keys: []trans.ExpressionNode{
+							&trans.ColRefNode{core.ColumnName{Name: "id"}},
+						},
+						RANode: &trans.WhereNode{
+							Table: &trans.CrossJoinNode{
+								RANodes: []trans.RelationalAlgebraNode{
+									&trans.TableNode{TableName: "foo"},
+								},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT DISTINCT ON (id) name FROM foo",
+		},
+		{
+			name: "test union",
+			expected: &trans.QueryStatement{
+				RANode: &trans.OrderByNode{
+					SortKeys: []trans.SortKey{
+						{Expr: &trans.ColPosNode{Pos: 1}, Desc: true, NullsFirst: true},
+					},
+					RANode: &trans.SetOpNode{
+						Op:  trans.Union,
+						All: true,
+						Left: &trans.ProjectionNode{
+							TargetColNames: core.ColumnNames{{Name: "id"}},
+							ResTargets: []trans.ExpressionNode{
+								trans.ColRefNode{core.ColumnName{Name: "id"}},
+							},
+							RANode: &trans.WhereNode{
+								Table: &trans.CrossJoinNode{
+									RANodes: []trans.RelationalAlgebraNode{
+										&trans.TableNode{TableName: "foo"},
+									},
+								},
+							},
+						},
+						Right: &trans.ProjectionNode{
+							TargetColNames: core.ColumnNames{{Name: "id"}},
+							ResTargets: []trans.ExpressionNode{
+								trans.ColRefNode{core.ColumnName{Name: "id"}},
+							},
+							RANode: &trans.WhereNode{
+								Table: &trans.CrossJoinNode{
+									RANodes: []trans.RelationalAlgebraNode{
+										&trans.TableNode{TableName: "bar"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT id FROM foo UNION ALL SELECT id FROM bar ORDER BY 1 DESC",
+		},
+		{
+			name: "test subquery",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{{Name: "id"}},
+					ResTargets: []trans.ExpressionNode{
+						trans.ColRefNode{core.ColumnName{Name: "id"}},
+					},
+					RANode: &trans.WhereNode{
+						Condition: &trans.NotNode{
+							Expr: &trans.SubLinkNode{
+								Type:     trans.AnySubLink,
+								Testexpr: &trans.ColRefNode{core.ColumnName{Name: "id"}},
+								Op:       trans.EqualOp,
+								Subquery: &trans.ProjectionNode{
+									TargetColNames: core.ColumnNames{{Name: "id"}},
+									ResTargets: []trans.ExpressionNode{
+										trans.ColRefNode{core.ColumnName{Name: "id"}},
+									},
+									RANode: &trans.WhereNode{
+										Table: &trans.CrossJoinNode{
+											RANodes: []trans.RelationalAlgebraNode{
+												&trans.TableNode{TableName: "bar"},
+											},
+										},
+									},
+								},
+							},
+						},
+						Table: &trans.CrossJoinNode{
+							RANodes: []trans.RelationalAlgebraNode{
+								&trans.TableNode{TableName: "foo"},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT id FROM foo WHERE id NOT IN (SELECT id FROM bar)",
+		},
+		{
+			name: "test with",
+			expected: &trans.QueryStatement{
+				RANode: &trans.WithNode{
+					CTEs: []*trans.CTE{
+						{
+							Name:     "x",
+							ColNames: []string{"a"},
+							Query: &trans.ProjectionNode{
+								TargetColNames: core.ColumnNames{{Name: "id"}},
+								ResTargets: []trans.ExpressionNode{
+									trans.ColRefNode{core.ColumnName{Name: "id"}},
+								},
+								RANode: &trans.WhereNode{
+									Table: &trans.CrossJoinNode{
+										RANodes: []trans.RelationalAlgebraNode{
+											&trans.TableNode{TableName: "foo"},
+										},
+									},
+								},
+							},
+							Materialize: trans.MaterializeAlways,
+						},
+					},
+					Query: &trans.ProjectionNode{
+						TargetColNames: core.ColumnNames{{Name: "a"}},
+						ResTargets: []trans.ExpressionNode{
+							trans.ColRefNode{core.ColumnName{Name: "a"}},
+						},
+						RANode: &trans.WhereNode{
+							Table: &trans.CrossJoinNode{
+								RANodes: []trans.RelationalAlgebraNode{
+									&trans.TableNode{TableName: "x"},
+								},
+							},
+						},
+					},
+				},
+			},
+			query: "WITH x(a) AS MATERIALIZED (SELECT id FROM foo) SELECT a FROM x",
+		},
+		{
+			name: "test window",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{{Name: "rank"}},
+					ResTargets: []trans.ExpressionNode{
+						&trans.ColRefNode{core.ColumnName{Name: "?window1?"}},
+					},
+					RANode: &trans.WindowNode{
+						Funcs: []*trans.WindowFuncNode{
+							{
+								FuncName: "rank",
+								Args:     []trans.ExpressionNode{},
+								Window: &trans.WindowDef{
+									PartitionBy: []trans.ExpressionNode{
+										&trans.ColRefNode{core.ColumnName{Name: "name"}},
+									},
+									OrderBy: []trans.SortKey{
+										{Expr: &trans.ColRefNode{core.ColumnName{Name: "id"}}},
+									},
+									Frame: &trans.WindowFrame{
+										Mode:  trans.RowsFrame,
+										Start: trans.FrameBound{Type: trans.OffsetPreceding, Offset: trans.IntegerNode{Val: 1}},
+										End:   trans.FrameBound{Type: trans.CurrentRow},
+									},
+								},
+							},
+						},
+						RANode: &trans.WhereNode{
+							Table: &trans.CrossJoinNode{
+								RANodes: []trans.RelationalAlgebraNode{
+									&trans.TableNode{TableName: "foo"},
+								},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT rank() OVER w FROM foo WINDOW w AS (PARTITION BY name ORDER BY id ROWS 1 PRECEDING)",
+		},
+		{
+			name: "test function",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{{Name: "lower"}, {Name: "coalesce"}},
+					ResTargets: []trans.ExpressionNode{
+						&trans.FuncCallNode{
+							FuncName: "lower",
+							Args: []trans.ExpressionNode{
+								&trans.ColRefNode{core.ColumnName{Name: "name"}},
+							},
+						},
+						&trans.CoalesceNode{
+							Args: []trans.ExpressionNode{
+								&trans.ColRefNode{core.ColumnName{Name: "id"}},
+								trans.IntegerNode{Val: 0},
+							},
+						},
+					},
+					RANode: &trans.WhereNode{
+						Table: &trans.CrossJoinNode{
+							RANodes: []trans.RelationalAlgebraNode{
+								&trans.TableNode{TableName: "foo"},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT lower(name), coalesce(id, 0) FROM foo",
+		},
+		{
+			name: "test like",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{{Name: "id"}},
+					ResTargets: []trans.ExpressionNode{
+						trans.ColRefNode{core.ColumnName{Name: "id"}},
+					},
+					RANode: &trans.WhereNode{
+						Condition: &trans.BinOpNode{
+							Op:    trans.NotLike,
+							Lexpr: &trans.ColRefNode{core.ColumnName{Name: "name"}},
+							Rexpr: &trans.FuncCallNode{
+								FuncName: "like_escape",
+								Args: []trans.ExpressionNode{
+									trans.StringNode{Val: "a!%%"},
+									trans.StringNode{Val: "!"},
+								},
+							},
+						},
+						Table: &trans.CrossJoinNode{
+							RANodes: []trans.RelationalAlgebraNode{
+								&trans.TableNode{TableName: "foo"},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT id FROM foo WHERE name NOT LIKE 'a!%%' ESCAPE '!'",
+		},
+		{
+			name: "test in list and boolean test",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{{Name: "id"}},
+					ResTargets: []trans.ExpressionNode{
+						trans.ColRefNode{core.ColumnName{Name: "id"}},
+					},
+					RANode: &trans.WhereNode{
+						Condition: &trans.BoolTestNode{
+							TestType: trans.IsNotTrue,
+							Expr: &trans.ORNode{
+								Lexpr: &trans.BinOpNode{
+									Op:    trans.EqualOp,
+									Lexpr: &trans.ColRefNode{core.ColumnName{Name: "id"}},
+									Rexpr: trans.IntegerNode{Val: 1},
+								},
+								Rexpr: &trans.BinOpNode{
+									Op:    trans.EqualOp,
+									Lexpr: &trans.ColRefNode{core.ColumnName{Name: "id"}},
+									Rexpr: trans.IntegerNode{Val: 2},
+								},
+							},
+						},
+						Table: &trans.CrossJoinNode{
+							RANodes: []trans.RelationalAlgebraNode{
+								&trans.TableNode{TableName: "foo"},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT id FROM foo WHERE id IN (1, 2) IS NOT TRUE",
+		},
+		{
+			name: "test type cast",
+			expected: &trans.QueryStatement{
+				RANode: &trans.ProjectionNode{
+					TargetColNames: core.ColumnNames{{Name: "id"}, {Name: "date"}},
+					ResTargets: []trans.ExpressionNode{
+						&trans.TypeCastNode{
+							Expr:     &trans.ColRefNode{core.ColumnName{Name: "id"}},
+							TypeName: "varchar",
+							TypeMods: []int{10},
+						},
+						&trans.TypeCastNode{
+							Expr:     trans.StringNode{Val: "2024-01-01"},
+							TypeName: "date",
+						},
+					},
+					RANode: &trans.WhereNode{
+						Table: &trans.CrossJoinNode{
+							RANodes: []trans.RelationalAlgebraNode{
+								&trans.TableNode{TableName: "foo"},
+							},
+						},
+					},
+				},
+			},
+			query: "SELECT CAST(id AS varchar(10)), DATE '2024-01-01' FROM foo",
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -130,8 +508,10 @@ func TestTranslateInsert(t *testing.T) {
 				RANode: &trans.InsertNode{
 					TableName:   "foo",
 					ColumnNames: core.ColumnNames{},
-					ValuesList: core.ValuesList{
-						core.Values{1, "mike"},
+					Source: &trans.ValuesNode{
+						ExprsList: [][]trans.ExpressionNode{
+							{trans.IntegerNode{Val: 1}, trans.StringNode{Val: "mike"}},
+						},
 					},
 				},
 			},
@@ -153,14 +533,66 @@ func TestTranslateInsert(t *testing.T) {
 							Name:      "name",
 						},
 					},
-					ValuesList: core.ValuesList{
-						core.Values{1, "mike"},
-						core.Values{100, "taro"},
+					Source: &trans.ValuesNode{
+						ExprsList: [][]trans.ExpressionNode{
+							{trans.IntegerNode{Val: 1}, trans.StringNode{Val: "mike"}},
+							{trans.IntegerNode{Val: 100}, trans.StringNode{Val: "taro"}},
+						},
 					},
 				},
 			},
 			query: "INSERT INTO foo (id, name) values (1, 'mike'), (100, 'taro')",
 		},
+		{
+			name:      "test insert default values",
+			tableName: "foo",
+			expected: &trans.QueryStatement{
+				RANode: &trans.InsertNode{
+					TableName:   "foo",
+					ColumnNames: core.ColumnNames{},
+				},
+			},
+			query: "INSERT INTO foo DEFAULT VALUES",
+		},
+		{
+			name:      "test insert returning",
+			tableName: "foo",
+			expected: &trans.QueryStatement{
+				RANode: &trans.InsertNode{
+					TableName:   "foo",
+					ColumnNames: core.ColumnNames{},
+					Returning: &trans.ProjectionNode{
+						TargetColNames: core.ColumnNames{{Name: "id"}},
+						ResTargets: []trans.ExpressionNode{
+							trans.ColRefNode{ColName: core.ColumnName{Name: "id"}},
+						},
+					},
+				},
+			},
+			query: "INSERT INTO foo DEFAULT VALUES RETURNING id",
+		},
+		{
+			name:      "test insert select",
+			tableName: "foo",
+			expected: &trans.QueryStatement{
+				RANode: &trans.InsertNode{
+					TableName:   "foo",
+					ColumnNames: core.ColumnNames{},
+					Source: &trans.ProjectionNode{
+						TargetColNames: core.ColumnNames{{Name: "*"}},
+						ResTargets:     []trans.ExpressionNode{trans.ColWildcardNode{}},
+						RANode: &trans.WhereNode{
+							Table: &trans.CrossJoinNode{
+								RANodes: []trans.RelationalAlgebraNode{
+									&trans.TableNode{TableName: "bar"},
+								},
+							},
+						},
+					},
+				},
+			},
+			query: "INSERT INTO foo SELECT * FROM bar",
+		},
 	}
 
 	for _, tt := range tests {
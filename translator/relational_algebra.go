@@ -5,6 +5,8 @@ package translator
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 
 	"github.com/goropikari/psqlittle/backend"
 	"github.com/goropikari/psqlittle/core"
@@ -15,6 +17,21 @@ type RelationalAlgebraNode interface {
 	Eval(backend.DB) (backend.Table, error)
 }
 
+// loggedNode is implemented by nodes which modify the database.
+type loggedNode interface {
+	isLogged(backend.DB) bool
+}
+
+// isLoggedTable reports whether changes of the table are written to the durability log.
+func isLoggedTable(db backend.DB, tableName string) bool {
+	tb, err := db.GetTable(tableName)
+	if err != nil {
+		return false
+	}
+
+	return tb.GetPersistence() == core.Permanent
+}
+
 // TableNode is Node of table
 type TableNode struct {
 	TableName string
@@ -71,26 +88,65 @@ func (p *ProjectionNode) Eval(db backend.DB) (backend.Table, error) {
 		return nil, err
 	}
 	if tb == nil {
-		return p.makeEmptyTable()
+		return p.makeEmptyTable(db)
 	}
 	newTable := tb.Copy()
 
-	resFuncs := p.constructResFunc()
+	resFuncs := p.constructResFunc(db)
+
+	srcColNames := newTable.GetColNames()
+	srcCols := newTable.GetCols()
+	if err := validateTargetColumn(append(srcColNames.Copy(), outerColNames(db)...), p.ResTargets); err != nil {
+		return nil, err
+	}
 
-	if err := validateTargetColumn(newTable.GetColNames(), p.TargetColNames); err != nil {
+	projected, err := newTable.Project(p.TargetColNames, resFuncs)
+	if err != nil {
 		return nil, err
 	}
+	projected.SetCols(p.deriveCols(srcColNames, srcCols, projected))
 
-	return newTable.Project(p.TargetColNames, resFuncs)
+	return hideInternalCols(projected), nil
 }
 
-func validateTargetColumn(tbCols core.ColumnNames, targets core.ColumnNames) error {
-	for _, tc := range targets {
-		if (tc == core.ColumnName{Name: "*"}) {
-			continue
+// internalColName matches names of columns which are made by AggregateNode, WindowNode and ProjectSetNode
+var internalColName = regexp.MustCompile(`^\?(group|agg|grouping|window|srf)\d+\?$`)
+
+// hideInternalCols removes the columns made by AggregateNode, WindowNode and ProjectSetNode.
+// They appear in the projected table only through the wildcard.
+func hideInternalCols(tb backend.Table) backend.Table {
+	cols := tb.GetCols()
+	idxs := make([]int, 0, len(cols))
+	for k, col := range cols {
+		if col.ColName.TableName != "" || !internalColName.MatchString(col.ColName.Name) {
+			idxs = append(idxs, k)
+		}
+	}
+	if len(idxs) == len(cols) {
+		return tb
+	}
+
+	newCols := make(core.Cols, 0, len(idxs))
+	for _, k := range idxs {
+		newCols = append(newCols, cols[k])
+	}
+	valsList := make(core.ValuesList, 0, len(tb.GetRows()))
+	for _, row := range tb.GetRows() {
+		vals := make(core.Values, 0, len(idxs))
+		for _, k := range idxs {
+			vals = append(vals, row.GetValues()[k])
 		}
-		if (tc == core.ColumnName{}) {
-			// expresison
+		valsList = append(valsList, vals)
+	}
+
+	return backend.NewTable(tb.GetName(), newCols, valsList)
+}
+
+func validateTargetColumn(tbCols core.ColumnNames, targets []ExpressionNode) error {
+	for _, target := range targets {
+		tc, ok := colRefName(target)
+		if !ok {
+			// wildcard or expression
 			continue
 		}
 		if !haveColumn(tc, tbCols) {
@@ -101,9 +157,67 @@ func validateTargetColumn(tbCols core.ColumnNames, targets core.ColumnNames) err
 	return nil
 }
 
+// colRefName returns the column name if the expression is a column reference.
+func colRefName(expr ExpressionNode) (core.ColumnName, bool) {
+	switch n := expr.(type) {
+	case ColRefNode:
+		return n.ColName, true
+	case *ColRefNode:
+		return n.ColName, true
+	}
+
+	return core.ColumnName{}, false
+}
+
+// deriveCols derives columns of the projected table. Types of the items are derived from their expressions,
+// and types of expressions which can't be derived such as NULL are inferred from the projected values.
+func (p *ProjectionNode) deriveCols(srcColNames core.ColumnNames, srcCols core.Cols, tb backend.Table) core.Cols {
+	exprs := make([]ExpressionNode, 0, len(p.ResTargets))
+	for _, target := range p.ResTargets {
+		if _, ok := target.(ColWildcardNode); ok {
+			for _, name := range srcColNames {
+				exprs = append(exprs, ColRefNode{ColName: name})
+			}
+			continue
+		}
+		exprs = append(exprs, target)
+	}
+
+	names := tb.GetColNames()
+	rows := tb.GetRows()
+	cols := make(core.Cols, 0, len(names))
+	for k, name := range names {
+		col := core.Col{ColName: name}
+		if typ, ok := exprType(exprs[k], srcCols); ok {
+			col.ColType = typ
+		} else {
+			col.ColType = inferColType(rows, k)
+		}
+		cols = append(cols, col)
+	}
+
+	return cols
+}
+
+// inferColType infers the type of k-th column from its first non-null value.
+// The type defaults to varchar as an unknown literal in PostgreSQL is resolved to text.
+func inferColType(rows []backend.Row, k int) core.ColType {
+	for _, row := range rows {
+		vals := row.GetValues()
+		if k >= len(vals) {
+			continue
+		}
+		if typ, ok := core.TypeOf(vals[k]); ok {
+			return typ
+		}
+	}
+
+	return core.VarChar
+}
+
 func haveColumn(c core.ColumnName, cs core.ColumnNames) bool {
 	for _, col := range cs {
-		if c == col {
+		if c.Matches(col) {
 			return true
 		}
 	}
@@ -118,104 +232,51 @@ func makeColName(c core.ColumnName) string {
 	return c.TableName + "." + c.Name
 }
 
-func (p *ProjectionNode) constructResFunc() []func(row backend.Row) (core.Value, error) {
+func (p *ProjectionNode) constructResFunc(db backend.DB) []func(row backend.Row) (core.Value, error) {
 	resFuncs := make([]func(backend.Row) (core.Value, error), 0, len(p.ResTargets))
 	for _, target := range p.ResTargets {
-		resFuncs = append(resFuncs, target.Eval())
+		resFuncs = append(resFuncs, scoped(db, target.Eval()))
 	}
 
 	return resFuncs
 }
 
-func (p *ProjectionNode) makeEmptyTable() (backend.Table, error) {
-	resFuncs := p.constructResFunc()
-	row := &EmptyTableRow{
-		ColNames: p.TargetColNames,
-		Values:   make(core.Values, 0),
-	}
-
+// makeEmptyTable evaluates the targets of select statement without from clause
+func (p *ProjectionNode) makeEmptyTable(db backend.DB) (backend.Table, error) {
+	resFuncs := p.constructResFunc(db)
+	vals := make(core.Values, 0, len(resFuncs))
 	for _, fn := range resFuncs {
-		v, err := fn(row)
+		v, err := fn(&EmptyTableRow{})
 		if err != nil {
 			return nil, err
 		}
-		row.Values = append(row.Values, v)
+		vals = append(vals, v)
 	}
 
-	return &EmptyTable{
-		ColNames: p.TargetColNames,
-		Rows:     []*EmptyTableRow{row},
-	}, nil
-}
-
-type EmptyTable struct {
-	ColNames core.ColumnNames
-	Rows     []*EmptyTableRow
-}
-
-func (t *EmptyTable) Copy() backend.Table {
-	return t
-}
-
-func (t *EmptyTable) GetName() string {
-	return ""
-}
-
-func (t *EmptyTable) GetColNames() core.ColumnNames {
-	return t.ColNames
-}
-
-func (t *EmptyTable) GetRows() []backend.Row {
-	rows := make([]backend.Row, 0, len(t.Rows))
-	for _, row := range t.Rows {
-		rows = append(rows, row)
+	cols := make(core.Cols, 0, len(p.TargetColNames))
+	for k, name := range p.TargetColNames {
+		typ, ok := exprType(p.ResTargets[k], nil)
+		if !ok {
+			typ, ok = core.TypeOf(vals[k])
+		}
+		if !ok {
+			typ = core.VarChar
+		}
+		cols = append(cols, core.Col{ColName: name, ColType: typ})
 	}
-	return rows
-}
-
-func (t *EmptyTable) GetCols() core.Cols {
-	return nil
-}
-
-func (t *EmptyTable) RenameTableName(name string) {}
-
-func (t *EmptyTable) InsertValues(cs core.ColumnNames, vs core.ValuesList) error { return nil }
 
-func (t *EmptyTable) Project(cs core.ColumnNames, fns []func(backend.Row) (core.Value, error)) (backend.Table, error) {
-	return nil, nil
-}
-
-func (t *EmptyTable) Where(fn func(backend.Row) (core.Value, error)) (backend.Table, error) {
-	return nil, nil
-}
-
-func (t *EmptyTable) CrossJoin(backend.Table) (backend.Table, error) {
-	return nil, nil
-}
-
-func (t *EmptyTable) OrderBy(ns core.ColumnNames, dirs []int) (backend.Table, error) {
-	return nil, nil
-}
-
-func (t *EmptyTable) Limit(n int) (backend.Table, error) {
-	return nil, nil
-}
-
-func (t *EmptyTable) Update(colNames core.ColumnNames, condFn func(backend.Row) (core.Value, error), assignValFns []func(backend.Row) (core.Value, error)) (backend.Table, error) {
-	return nil, nil
-}
-
-func (t *EmptyTable) Delete(func(backend.Row) (core.Value, error)) (backend.Table, error) {
-	return nil, nil
+	return backend.NewTable("", cols, core.ValuesList{vals}), nil
 }
 
+// EmptyTableRow is a row which has no columns.
+// It is used to evaluate expressions which don't refer to any table.
 type EmptyTableRow struct {
 	ColNames core.ColumnNames
 	Values   core.Values
 }
 
-func (r *EmptyTableRow) GetValueByColName(core.ColumnName) (core.Value, error) {
-	return nil, nil
+func (r *EmptyTableRow) GetValueByColName(name core.ColumnName) (core.Value, error) {
+	return nil, fmt.Errorf(`ERROR:  column "%v" does not exist`, makeColName(name))
 }
 
 func (r *EmptyTableRow) GetValues() core.Values {
@@ -240,19 +301,43 @@ func (wn *WhereNode) Eval(db backend.DB) (backend.Table, error) {
 		return backend.Table(nil), nil
 	}
 
-	tb, err := wn.Table.Eval(db)
+	if wn.Condition == nil {
+		return wn.Table.Eval(db)
+	}
+
+	conds, semiJoins := splitSemiJoins(conjuncts(wn.Condition))
+	tb, err := wn.evalConds(db, conds)
 	if err != nil {
 		return nil, err
 	}
+	for _, s := range semiJoins {
+		tb, err = s.eval(db, tb)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if wn.Condition == nil {
-		return tb, nil
+	return tb, nil
+}
+
+func (wn *WhereNode) evalConds(db backend.DB, conds []ExpressionNode) (backend.Table, error) {
+	if c, ok := wn.Table.(*CrossJoinNode); ok && len(conds) > 0 && len(c.RANodes) > 1 {
+		return c.evalWhere(db, conds)
 	}
 
-	newTable := tb.Copy()
-	condFunc := wn.Condition.Eval()
+	tb, err := wn.Table.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if tb == nil {
+		// select without from clause has a row which has no columns
+		tb = backend.NewTable("", core.Cols{}, core.ValuesList{{}})
+	}
+	if len(conds) == 0 {
+		return tb, nil
+	}
 
-	return newTable.Where(condFunc)
+	return tb.Copy().Where(conditionFunc(db, conds))
 }
 
 // CrossJoinNode is a node of cross join.
@@ -262,17 +347,8 @@ type CrossJoinNode struct {
 
 // Eval evaluates CrossJoinNode
 func (c *CrossJoinNode) Eval(db backend.DB) (backend.Table, error) {
-	tbs := make([]backend.Table, 0, len(c.RANodes))
-
-	for _, ra := range c.RANodes {
-		tb, err := ra.Eval(db)
-		if err != nil {
-			return nil, err
-		}
-		tbs = append(tbs, tb)
-	}
-
-	if err := validateTableName(tbs); err != nil {
+	tbs, err := c.evalTables(db)
+	if err != nil {
 		return nil, err
 	}
 
@@ -283,13 +359,8 @@ func (c *CrossJoinNode) Eval(db backend.DB) (backend.Table, error) {
 		return tbs[0], nil
 	}
 
-	var tb backend.Table
-	var err error
-	tb = tbs[0]
-	for k, t := range tbs {
-		if k == 0 {
-			continue
-		}
+	tb := tbs[0]
+	for _, t := range tbs[1:] {
 		tb, err = crossJoinTable(tb, t)
 		if err != nil {
 			return nil, err
@@ -299,6 +370,55 @@ func (c *CrossJoinNode) Eval(db backend.DB) (backend.Table, error) {
 	return tb, nil
 }
 
+// evalWhere evaluates the cross join filtered by AND of conds.
+// Tables are joined one by one, and equality conditions between a joined table and the next table
+// are used as join keys instead of filtering the cartesian product.
+func (c *CrossJoinNode) evalWhere(db backend.DB, conds []ExpressionNode) (backend.Table, error) {
+	tbs, err := c.evalTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tb := tbs[0]
+	for _, rtb := range tbs[1:] {
+		if tb == nil || rtb == nil {
+			return nil, nil
+		}
+		lkeys, rkeys, rest := splitEquiJoinCondition(conds, tb.GetColNames(), rtb.GetColNames())
+		if len(lkeys) == 0 {
+			tb, err = tb.CrossJoin(rtb)
+		} else {
+			tb, err = backend.EquiJoin(tb, rtb, backend.InnerJoin, joinKeys(db, lkeys, rkeys), conditionFunc(db, nil))
+		}
+		if err != nil {
+			return nil, err
+		}
+		conds = rest
+	}
+	if len(conds) == 0 {
+		return tb, nil
+	}
+
+	return tb.Copy().Where(conditionFunc(db, conds))
+}
+
+func (c *CrossJoinNode) evalTables(db backend.DB) ([]backend.Table, error) {
+	tbs := make([]backend.Table, 0, len(c.RANodes))
+	for _, ra := range c.RANodes {
+		tb, err := ra.Eval(db)
+		if err != nil {
+			return nil, err
+		}
+		tbs = append(tbs, tb)
+	}
+
+	if err := validateTableName(tbs); err != nil {
+		return nil, err
+	}
+
+	return tbs, nil
+}
+
 func validateTableName(tbs []backend.Table) error {
 
 	nm := make(map[string]int)
@@ -327,131 +447,1058 @@ func crossJoinTable(tb1, tb2 backend.Table) (backend.Table, error) {
 	return tb, nil
 }
 
-// OrderByNode is a Node for order by clause
-type OrderByNode struct {
-	SortKeys core.ColumnNames
-	SortDirs []int
-	RANode   RelationalAlgebraNode
+// JoinNode is a node of JOIN in from clause
+type JoinNode struct {
+	JoinType  backend.JoinType
+	Left      RelationalAlgebraNode
+	Right     RelationalAlgebraNode
+	Condition ExpressionNode
+	// UsingCols is column names of USING clause
+	UsingCols []string
+	// Natural is true for NATURAL JOIN, which is USING all common columns
+	Natural bool
+	// Lateral is true when Right refers to columns of Left
+	Lateral bool
 }
 
-// Eval evaluates OrderByNode
-func (o *OrderByNode) Eval(db backend.DB) (backend.Table, error) {
-	tb, err := o.RANode.Eval(db)
+// Eval evaluates JoinNode
+func (j *JoinNode) Eval(db backend.DB) (backend.Table, error) {
+	ltb, err := j.Left.Eval(db)
 	if err != nil {
 		return nil, err
 	}
 
-	tb.OrderBy(o.SortKeys, o.SortDirs)
+	if j.Lateral {
+		if j.Natural || len(j.UsingCols) > 0 {
+			return nil, errors.New("ERROR:  USING and NATURAL are not supported for LATERAL join")
+		}
+		rfn := func(row backend.Row) (backend.Table, error) {
+			return j.Right.Eval(withOuterRow(db, row))
+		}
+		return ltb.LateralJoin(rfn, j.JoinType, conditionFunc(db, conjuncts(j.Condition)))
+	}
 
-	return tb, nil
-}
+	rtb, err := j.Right.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTableName([]backend.Table{ltb, rtb}); err != nil {
+		return nil, err
+	}
 
-// LimitNode is a Node for limit clause
-type LimitNode struct {
-	Count  int
-	RANode RelationalAlgebraNode
-}
+	usingCols := j.UsingCols
+	if j.Natural {
+		usingCols = commonColumnNames(ltb.GetColNames(), rtb.GetColNames())
+	}
+	if len(usingCols) == 0 {
+		return joinTables(db, ltb, rtb, j.JoinType, j.Condition)
+	}
 
-// Eval evaluates LimitNode
-func (l *LimitNode) Eval(db backend.DB) (backend.Table, error) {
-	tb, err := l.RANode.Eval(db)
+	lidxs, err := usingColumnIndexes(ltb.GetColNames(), usingCols, "left")
 	if err != nil {
 		return nil, err
 	}
+	ridxs, err := usingColumnIndexes(rtb.GetColNames(), usingCols, "right")
+	if err != nil {
+		return nil, err
+	}
+	keys := backend.JoinKeys{}
+	for k := range usingCols {
+		keys.Left = append(keys.Left, valueAt(lidxs[k]))
+		keys.Right = append(keys.Right, valueAt(ridxs[k]))
+	}
 
-	tb, err = tb.Limit(l.Count)
+	tb, err := backend.EquiJoin(ltb, rtb, j.JoinType, keys, conditionFunc(db, conjuncts(j.Condition)))
 	if err != nil {
 		return nil, err
 	}
-	return tb, nil
+
+	return j.mergeUsingColumns(tb, ltb, lidxs, ridxs), nil
 }
 
-// DropTableNode is a node of drop statement
-type DropTableNode struct {
-	TableNames []string
+// valueAt makes a function which takes idx-th value of a row
+func valueAt(idx int) func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		return row.GetValues()[idx], nil
+	}
 }
 
-// Eval evaluates DropTableNode
-func (d *DropTableNode) Eval(db backend.DB) (backend.Table, error) {
-	for _, name := range d.TableNames {
-		if err := db.DropTable(name); err != nil {
-			return nil, err
+func commonColumnNames(lnames, rnames core.ColumnNames) []string {
+	names := make([]string, 0)
+	for _, l := range lnames {
+		for _, r := range rnames {
+			if l.Name == r.Name {
+				names = append(names, l.Name)
+				break
+			}
 		}
 	}
 
-	return nil, nil
+	return names
 }
 
-// CreateTableNode is a node of create statement
-type CreateTableNode struct {
-	TableName  string
-	ColumnDefs core.Cols
+func usingColumnIndexes(names core.ColumnNames, usingCols []string, side string) ([]int, error) {
+	idxs := make([]int, 0, len(usingCols))
+	for _, col := range usingCols {
+		idx := -1
+		for k, name := range names {
+			if name.Name != col {
+				continue
+			}
+			if idx >= 0 {
+				return nil, fmt.Errorf(`ERROR:  common column name "%v" appears more than once in %v table`, col, side)
+			}
+			idx = k
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf(`ERROR:  column "%v" specified in USING clause does not exist in %v table`, col, side)
+		}
+		idxs = append(idxs, idx)
+	}
+
+	return idxs, nil
 }
 
-// Eval evaluates CreateTableNode
-func (c *CreateTableNode) Eval(db backend.DB) (backend.Table, error) {
-	if err := db.CreateTable(c.TableName, c.ColumnDefs); err != nil {
-		return nil, err
+// mergeUsingColumns makes the output of USING join as PostgreSQL does.
+// The join columns come first and only once, followed by the other columns of the left and right tables.
+// A join column is referred with the table name of the side whose value it takes.
+func (j *JoinNode) mergeUsingColumns(tb, ltb backend.Table, lidxs, ridxs []int) backend.Table {
+	names := tb.GetColNames()
+	cols := tb.GetCols()
+	numLeft := len(ltb.GetColNames())
+
+	colOf := func(k int) core.Col {
+		if len(cols) == len(names) {
+			return cols[k]
+		}
+		return core.Col{ColName: names[k], ColType: core.VarChar}
 	}
-	return nil, nil
-}
 
-// InsertNode is a node of create statement
-type InsertNode struct {
-	TableName   string
-	ColumnNames core.ColumnNames
-	ValuesList  core.ValuesList
-}
+	isJoinCol := make(map[int]bool)
+	newCols := make(core.Cols, 0, len(names))
+	for k := range lidxs {
+		li, ri := lidxs[k], numLeft+ridxs[k]
+		isJoinCol[li], isJoinCol[ri] = true, true
+		col := colOf(li)
+		switch j.JoinType {
+		case backend.RightJoin:
+			col.ColName = names[ri]
+		case backend.FullJoin:
+			col.ColName = core.ColumnName{Name: names[li].Name}
+		}
+		newCols = append(newCols, core.Col{ColName: col.ColName, ColType: col.ColType})
+	}
+	for k := range names {
+		if !isJoinCol[k] {
+			col := colOf(k)
+			newCols = append(newCols, core.Col{ColName: names[k], ColType: col.ColType})
+		}
+	}
 
-// Eval evaluates CreateTableNode
-func (c *InsertNode) Eval(db backend.DB) (backend.Table, error) {
-	tb, err := db.GetTable(c.TableName)
-	if err != nil {
-		return nil, err
+	valsList := make(core.ValuesList, 0)
+	for _, row := range tb.GetRows() {
+		vals := row.GetValues()
+		newVals := make(core.Values, 0, len(newCols))
+		for k := range lidxs {
+			l, r := vals[lidxs[k]], vals[numLeft+ridxs[k]]
+			switch {
+			case j.JoinType == backend.RightJoin:
+				newVals = append(newVals, r)
+			case j.JoinType == backend.FullJoin && (l == nil || l == core.Null):
+				newVals = append(newVals, r)
+			default:
+				newVals = append(newVals, l)
+			}
+		}
+		for k, v := range vals {
+			if !isJoinCol[k] {
+				newVals = append(newVals, v)
+			}
+		}
+		valsList = append(valsList, newVals)
 	}
-	tb.InsertValues(c.ColumnNames, c.ValuesList)
 
-	return nil, nil
+	return backend.NewTable("", newCols, valsList)
 }
 
-// UpdateNode is a node of update statement
-type UpdateNode struct {
-	Condition  ExpressionNode
-	ColNames   core.ColumnNames
-	AssignExpr []ExpressionNode
-	TableName  string
+// FunctionTableNode is a node of a set-returning function in from clause
+type FunctionTableNode struct {
+	FuncName string
+	Args     []ExpressionNode
+	Alias    string
+	// ColAliases is column names given by the alias clause
+	ColAliases     []string
+	WithOrdinality bool
 }
 
-// Eval evaluates UpdateNode
-func (u *UpdateNode) Eval(db backend.DB) (backend.Table, error) {
-	var condFunc func(backend.Row) (core.Value, error)
-	if u.Condition == nil {
-		condFunc = func(row backend.Row) (core.Value, error) {
-			return core.True, nil
-		}
-	} else {
-		condFunc = u.Condition.Eval()
+// setReturningFunc returns rows of a set-returning function and the type of its values
+type setReturningFunc func(args core.Values) (core.Values, core.ColType, error)
+
+var setReturningFuncs = map[string]setReturningFunc{
+	"generate_series":       generateSeries,
+	"regexp_matches":        regexpMatches,
+	"regexp_split_to_table": regexpSplitToTable,
+}
+
+// Eval evaluates FunctionTableNode
+func (f *FunctionTableNode) Eval(db backend.DB) (backend.Table, error) {
+	fn, ok := setReturningFuncs[f.FuncName]
+	if !ok {
+		return nil, fmt.Errorf("ERROR:  function %v does not exist", f.FuncName)
 	}
 
-	tb, err := db.GetTable(u.TableName)
+	args := make(core.Values, 0, len(f.Args))
+	for _, arg := range f.Args {
+		v, err := scoped(db, arg.Eval())(&EmptyTableRow{})
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	vals, typ, err := fn(args)
 	if err != nil {
 		return nil, err
 	}
 
-	assignValFns := make([]func(backend.Row) (core.Value, error), 0)
-	for _, expr := range u.AssignExpr {
-		assignValFns = append(assignValFns, expr.Eval())
+	tableName := f.FuncName
+	if f.Alias != "" {
+		tableName = f.Alias
+	}
+	// a function returning a scalar takes the alias as its column name as in PostgreSQL
+	colNames := []string{tableName}
+	colTypes := []core.ColType{typ}
+	if f.WithOrdinality {
+		colNames = append(colNames, "ordinality")
+		colTypes = append(colTypes, core.Integer)
+	}
+	if len(f.ColAliases) > len(colNames) {
+		return nil, fmt.Errorf(`ERROR:  table "%v" has %d columns available but %d columns specified`, tableName, len(colNames), len(f.ColAliases))
+	}
+	copy(colNames, f.ColAliases)
+
+	cols := make(core.Cols, 0, len(colNames))
+	for k, name := range colNames {
+		cols = append(cols, core.Col{
+			ColName: core.ColumnName{TableName: tableName, Name: name},
+			ColType: colTypes[k],
+		})
+	}
+	valsList := make(core.ValuesList, 0, len(vals))
+	for k, v := range vals {
+		if f.WithOrdinality {
+			valsList = append(valsList, core.Values{v, k + 1})
+		} else {
+			valsList = append(valsList, core.Values{v})
+		}
 	}
 
-	tb.Update(u.ColNames, condFunc, assignValFns)
+	return backend.NewTable(tableName, cols, valsList), nil
+}
 
-	return nil, nil
+// SetReturningFuncNode is expression of a set-returning function call in select list such as generate_series(1, 3).
+// It is replaced with a reference to a column of ProjectSetNode when the query is translated.
+type SetReturningFuncNode struct {
+	FuncName string
+	Args     []ExpressionNode
 }
 
-// DeleteNode is a node of update statement
+// Eval returns an error because a set-returning function can't be evaluated for a row
+func (f *SetReturningFuncNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(backend.Row) (core.Value, error) {
+		return nil, fmt.Errorf("ERROR:  set-returning function %v is not allowed in this context", f.FuncName)
+	}
+}
+
+// ProjectSetNode is a node of set-returning functions in select list.
+// Each row of the source table is repeated as many times as the longest result of Funcs,
+// and the results are given as the columns following the source columns.
+// Shorter results are padded with NULLs as in PostgreSQL.
+type ProjectSetNode struct {
+	Funcs  []*SetReturningFuncNode
+	RANode RelationalAlgebraNode
+}
+
+// Eval evaluates ProjectSetNode
+func (p *ProjectSetNode) Eval(db backend.DB) (backend.Table, error) {
+	tb, err := p.RANode.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if tb == nil {
+		// select without from clause has a row which has no columns
+		tb = backend.NewTable("", core.Cols{}, core.ValuesList{{}})
+	}
+
+	argFns := make([][]func(backend.Row) (core.Value, error), 0, len(p.Funcs))
+	for _, fn := range p.Funcs {
+		fns := make([]func(backend.Row) (core.Value, error), 0, len(fn.Args))
+		for _, arg := range fn.Args {
+			fns = append(fns, scoped(db, arg.Eval()))
+		}
+		argFns = append(argFns, fns)
+	}
+
+	typs := make([]core.ColType, len(p.Funcs))
+	valsList := make(core.ValuesList, 0, len(tb.GetRows()))
+	for _, row := range tb.GetRows() {
+		results := make([]core.Values, 0, len(p.Funcs))
+		n := 0
+		for k, fn := range p.Funcs {
+			args := make(core.Values, 0, len(argFns[k]))
+			for _, argFn := range argFns[k] {
+				v, err := argFn(row)
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, nullIfNil(v))
+			}
+			vals, typ, err := setReturningFuncs[fn.FuncName](args)
+			if err != nil {
+				return nil, err
+			}
+			typs[k] = typ
+			results = append(results, vals)
+			if len(vals) > n {
+				n = len(vals)
+			}
+		}
+		for i := 0; i < n; i++ {
+			vals := append(core.Values{}, row.GetValues()...)
+			for _, res := range results {
+				if i < len(res) {
+					vals = append(vals, res[i])
+				} else {
+					vals = append(vals, core.Null)
+				}
+			}
+			valsList = append(valsList, vals)
+		}
+	}
+
+	cols := append(core.Cols{}, tb.GetCols()...)
+	for k := range p.Funcs {
+		cols = append(cols, core.Col{ColName: srfColName(k), ColType: typs[k]})
+	}
+
+	return backend.NewTable(tb.GetName(), cols, valsList), nil
+}
+
+func srfColName(k int) core.ColumnName {
+	return core.ColumnName{Name: fmt.Sprintf("?srf%d?", k+1)}
+}
+
+// generateSeries generates integers or floats from start to stop by step
+func generateSeries(args core.Values) (core.Values, core.ColType, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, core.Integer, errors.New("ERROR:  function generate_series must have 2 or 3 arguments")
+	}
+	if len(args) == 2 {
+		args = append(args, 1)
+	}
+
+	isFloat := false
+	for _, arg := range args {
+		switch arg.(type) {
+		case int:
+		case float64:
+			isFloat = true
+		default:
+			if arg == nil || arg == core.Null {
+				return core.Values{}, core.Integer, nil
+			}
+			return nil, core.Integer, errors.New("ERROR:  function generate_series does not exist")
+		}
+	}
+
+	if !isFloat {
+		start, stop, step := args[0].(int), args[1].(int), args[2].(int)
+		if step == 0 {
+			return nil, core.Integer, errors.New("ERROR:  step size cannot equal zero")
+		}
+		vals := make(core.Values, 0)
+		for v := start; (step > 0 && v <= stop) || (step < 0 && v >= stop); v += step {
+			vals = append(vals, v)
+		}
+		return vals, core.Integer, nil
+	}
+
+	fargs := make([]float64, 0, len(args))
+	for _, arg := range args {
+		if i, ok := arg.(int); ok {
+			fargs = append(fargs, float64(i))
+		} else {
+			fargs = append(fargs, arg.(float64))
+		}
+	}
+	start, stop, step := fargs[0], fargs[1], fargs[2]
+	if step == 0 {
+		return nil, core.Float, errors.New("ERROR:  step size cannot equal zero")
+	}
+	vals := make(core.Values, 0)
+	for v := start; (step > 0 && v <= stop) || (step < 0 && v >= stop); v += step {
+		vals = append(vals, v)
+	}
+
+	return vals, core.Float, nil
+}
+
+// SortKey is a key of ORDER BY
+type SortKey struct {
+	Expr ExpressionNode
+	Desc bool
+	// NullsFirst places NULLs before non-NULL values. As in PostgreSQL, it defaults to Desc.
+	NullsFirst bool
+}
+
+// compareSortValues compares values of sort keys. NULLs are equal to each other.
+func compareSortValues(x, y core.Values, keys []SortKey) (int, error) {
+	for k, key := range keys {
+		xNull, yNull := isNull(x[k]), isNull(y[k])
+		var c int
+		switch {
+		case xNull && yNull:
+			c = 0
+		case xNull != yNull:
+			c = 1
+			if xNull == key.NullsFirst {
+				c = -1
+			}
+		default:
+			var err error
+			c, err = core.Compare(x[k], y[k])
+			if err != nil {
+				return 0, err
+			}
+			if key.Desc {
+				c = -c
+			}
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// OrderByNode is a Node for order by clause
+type OrderByNode struct {
+	SortKeys []SortKey
+	RANode   RelationalAlgebraNode
+}
+
+// Eval evaluates OrderByNode
+func (o *OrderByNode) Eval(db backend.DB) (backend.Table, error) {
+	tb, err := o.RANode.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if tb == nil {
+		// select without from clause has only one row
+		return nil, nil
+	}
+
+	keyFns := make([]func(backend.Row) (core.Value, error), 0, len(o.SortKeys))
+	for _, key := range o.SortKeys {
+		keyFns = append(keyFns, scoped(db, key.Expr.Eval()))
+	}
+
+	// sort a copy so as not to reorder rows of the stored table
+	return tb.Copy().OrderBy(keyFns, func(x, y core.Values) (int, error) {
+		return compareSortValues(x, y, o.SortKeys)
+	})
+}
+
+// LimitNode is a Node for limit clause
+type LimitNode struct {
+	// Count is the maximum number of rows. All rows are returned if it's nil or NULL.
+	Count ExpressionNode
+	// Offset is the number of rows to be skipped. No row is skipped if it's nil or NULL.
+	Offset ExpressionNode
+	// WithTies returns also the rows which are equal to the last row in the order of SortKeys
+	WithTies bool
+	SortKeys []SortKey
+	RANode   RelationalAlgebraNode
+}
+
+// Eval evaluates LimitNode
+func (l *LimitNode) Eval(db backend.DB) (backend.Table, error) {
+	tb, err := l.RANode.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if tb == nil {
+		// select without from clause has a row which has no columns
+		tb = backend.NewTable("", core.Cols{}, core.ValuesList{{}})
+	}
+
+	offset, _, err := evalLimitArg(db, l.Offset, "OFFSET")
+	if err != nil {
+		return nil, err
+	}
+	count, countNull, err := evalLimitArg(db, l.Count, "LIMIT")
+	if err != nil {
+		return nil, err
+	}
+
+	tb, err = tb.Offset(offset)
+	if err != nil {
+		return nil, err
+	}
+	if l.Count == nil || countNull {
+		if l.WithTies {
+			return nil, errors.New("ERROR:  row count cannot be null in FETCH FIRST ... WITH TIES clause")
+		}
+		return tb, nil
+	}
+	if l.WithTies {
+		count, err = l.countWithTies(db, tb.GetRows(), count)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tb.Limit(count)
+}
+
+// countWithTies counts the first count rows and the following rows which are equal to the last of them
+func (l *LimitNode) countWithTies(db backend.DB, rows []backend.Row, count int) (int, error) {
+	if count == 0 || count >= len(rows) {
+		return count, nil
+	}
+
+	keyFns := make([]func(backend.Row) (core.Value, error), 0, len(l.SortKeys))
+	for _, key := range l.SortKeys {
+		keyFns = append(keyFns, scoped(db, key.Expr.Eval()))
+	}
+	keyValues := func(row backend.Row) (core.Values, error) {
+		vals := make(core.Values, 0, len(keyFns))
+		for _, fn := range keyFns {
+			v, err := fn(row)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, v)
+		}
+		return vals, nil
+	}
+
+	last, err := keyValues(rows[count-1])
+	if err != nil {
+		return 0, err
+	}
+	for ; count < len(rows); count++ {
+		vals, err := keyValues(rows[count])
+		if err != nil {
+			return 0, err
+		}
+		c, err := compareSortValues(last, vals, l.SortKeys)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			break
+		}
+	}
+
+	return count, nil
+}
+
+// evalLimitArg evaluates the argument of LIMIT or OFFSET.
+// The second return value is true if the argument is nil or NULL.
+func evalLimitArg(db backend.DB, expr ExpressionNode, clause string) (int, bool, error) {
+	if expr == nil {
+		return 0, true, nil
+	}
+	v, err := scoped(db, expr.Eval())(&EmptyTableRow{})
+	if err != nil {
+		return 0, false, err
+	}
+
+	var n int
+	switch val := v.(type) {
+	case nil:
+		return 0, true, nil
+	case int:
+		n = val
+	case float64:
+		// a number is rounded to an integer as it's cast to bigint
+		n = int(math.Round(val))
+	default:
+		if v == core.Null {
+			return 0, true, nil
+		}
+		return 0, false, fmt.Errorf("ERROR:  argument of %v must be type bigint, not type %v", clause, core.TypeName(v))
+	}
+	if n < 0 {
+		return 0, false, fmt.Errorf("ERROR:  %v must not be negative", clause)
+	}
+
+	return n, false, nil
+}
+
+// DistinctNode is a node of SELECT DISTINCT. It keeps the first row of each set of rows
+// which have the same values of Keys. Keys are the select list for SELECT DISTINCT
+// and the expressions of DISTINCT ON (...) for SELECT DISTINCT ON.
+type DistinctNode struct {
+	Keys   []ExpressionNode
+	RANode RelationalAlgebraNode
+}
+
+// Eval evaluates DistinctNode
+func (d *DistinctNode) Eval(db backend.DB) (backend.Table, error) {
+	tb, err := d.RANode.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if tb == nil {
+		// select without from clause has only one row
+		return nil, nil
+	}
+
+	keyFns := make([]func(backend.Row) (core.Value, error), 0, len(d.Keys))
+	for _, key := range d.Keys {
+		keyFns = append(keyFns, scoped(db, key.Eval()))
+	}
+	seen := make(map[interface{}]bool)
+
+	return tb.Copy().Where(func(row backend.Row) (core.Value, error) {
+		vals := make(core.Values, 0, len(keyFns))
+		for k, fn := range keyFns {
+			if _, ok := d.Keys[k].(ColWildcardNode); ok {
+				vals = append(vals, row.GetValues()...)
+				continue
+			}
+			v, err := fn(row)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, v)
+		}
+
+		h := core.HashKey(vals)
+		if seen[h] {
+			return core.False, nil
+		}
+		seen[h] = true
+
+		return core.True, nil
+	})
+}
+
+// DropTableNode is a node of drop statement
+type DropTableNode struct {
+	TableNames []string
+	MissingOk  bool
+}
+
+// Eval evaluates DropTableNode
+func (d *DropTableNode) Eval(db backend.DB) (backend.Table, error) {
+	names := make([]string, 0, len(d.TableNames))
+	for _, name := range d.TableNames {
+		if _, err := db.GetTable(name); err != nil {
+			if !d.MissingOk {
+				return nil, err
+			}
+			db.AddNotice(fmt.Sprintf(`table "%v" does not exist, skipping`, name))
+			continue
+		}
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		if err := db.DropTable(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *DropTableNode) isLogged(db backend.DB) bool {
+	for _, name := range d.TableNames {
+		tb, err := db.GetTable(name)
+		if err != nil || tb.GetPersistence() != core.Temporary {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreateTableNode is a node of create statement
+type CreateTableNode struct {
+	TableName   string
+	ColumnDefs  core.Cols
+	UniqueKeys  []core.UniqueKey
+	Persistence core.Persistence
+	OnCommit    core.OnCommitAction
+	IfNotExists bool
+}
+
+// Eval evaluates CreateTableNode
+func (c *CreateTableNode) Eval(db backend.DB) (backend.Table, error) {
+	if c.IfNotExists {
+		if _, err := db.GetTable(c.TableName); err == nil {
+			db.AddNotice(fmt.Sprintf(`relation "%v" already exists, skipping`, c.TableName))
+			return nil, nil
+		}
+	}
+
+	if err := createTable(db, c.TableName, c.ColumnDefs, c.Persistence, c.OnCommit); err != nil {
+		return nil, err
+	}
+	if len(c.UniqueKeys) > 0 {
+		tb, err := db.GetTable(c.TableName)
+		if err != nil {
+			return nil, err
+		}
+		tb.SetUniqueKeys(c.UniqueKeys)
+	}
+	return nil, nil
+}
+
+// The definition of an unlogged table is logged, only its data is not.
+func (c *CreateTableNode) isLogged(db backend.DB) bool {
+	return c.Persistence != core.Temporary
+}
+
+func createTable(db backend.DB, tableName string, cols core.Cols, persistence core.Persistence, onCommit core.OnCommitAction) error {
+	switch persistence {
+	case core.Temporary:
+		return db.CreateTempTable(tableName, cols, onCommit)
+	case core.Unlogged:
+		return db.CreateUnloggedTable(tableName, cols)
+	}
+
+	return db.CreateTable(tableName, cols)
+}
+
+// CreateTableAsNode is a node of CREATE TABLE AS and SELECT INTO statements
+type CreateTableAsNode struct {
+	TableName   string
+	ColNames    []string
+	Persistence core.Persistence
+	OnCommit    core.OnCommitAction
+	IfNotExists bool
+	WithNoData  bool
+	Query       RelationalAlgebraNode
+}
+
+// Eval evaluates CreateTableAsNode
+func (c *CreateTableAsNode) Eval(db backend.DB) (backend.Table, error) {
+	if c.IfNotExists {
+		if _, err := db.GetTable(c.TableName); err == nil {
+			db.AddNotice(fmt.Sprintf(`relation "%v" already exists, skipping`, c.TableName))
+			return nil, nil
+		}
+	}
+
+	tb, err := c.Query.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if tb == nil {
+		return nil, errors.New("ERROR:  query has no result")
+	}
+
+	cols, err := c.makeColumnDefs(tb.GetCols())
+	if err != nil {
+		return nil, err
+	}
+	if err := createTable(db, c.TableName, cols, c.Persistence, c.OnCommit); err != nil {
+		return nil, err
+	}
+	if c.WithNoData {
+		return nil, nil
+	}
+
+	newTable, err := db.GetTable(c.TableName)
+	if err != nil {
+		return nil, err
+	}
+	valsList := make(core.ValuesList, 0)
+	for _, row := range tb.GetRows() {
+		valsList = append(valsList, row.GetValues())
+	}
+	if _, err := newTable.InsertValues(nil, valsList); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (c *CreateTableAsNode) makeColumnDefs(srcCols core.Cols) (core.Cols, error) {
+	if len(c.ColNames) > len(srcCols) {
+		return nil, errors.New("ERROR:  too many column names were specified")
+	}
+
+	cols := make(core.Cols, 0, len(srcCols))
+	seen := make(map[string]bool)
+	for k, src := range srcCols {
+		name := src.ColName.Name
+		if k < len(c.ColNames) {
+			name = c.ColNames[k]
+		}
+		if name == "" {
+			name = "?column?"
+		}
+		if seen[name] {
+			return nil, fmt.Errorf(`ERROR:  column "%v" specified more than once`, name)
+		}
+		seen[name] = true
+
+		cols = append(cols, core.Col{
+			ColName: core.ColumnName{TableName: c.TableName, Name: name},
+			ColType: src.ColType,
+		})
+	}
+
+	return cols, nil
+}
+
+func (c *CreateTableAsNode) isLogged(db backend.DB) bool {
+	return c.Persistence != core.Temporary
+}
+
+// TruncateNode is a node of truncate statement
+type TruncateNode struct {
+	TableNames      []string
+	RestartIdentity bool
+}
+
+// Eval evaluates TruncateNode
+func (t *TruncateNode) Eval(db backend.DB) (backend.Table, error) {
+	// All tables are looked up first so that nothing is truncated on error.
+	tbs := make([]backend.Table, 0, len(t.TableNames))
+	for _, name := range t.TableNames {
+		tb, err := db.GetTable(name)
+		if err != nil {
+			return nil, err
+		}
+		tbs = append(tbs, tb)
+	}
+
+	for _, tb := range tbs {
+		if err := tb.Truncate(t.RestartIdentity); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (t *TruncateNode) isLogged(db backend.DB) bool {
+	for _, name := range t.TableNames {
+		if isLoggedTable(db, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InsertNode is a node of insert statement
+type InsertNode struct {
+	TableName   string
+	Alias       string
+	ColumnNames core.ColumnNames
+	// Source is the query which gives rows to be inserted. nil means DEFAULT VALUES.
+	Source     RelationalAlgebraNode
+	OnConflict *OnConflictClause
+	Returning  *ProjectionNode
+}
+
+// OnConflictClause is ON CONFLICT clause of insert statement
+type OnConflictClause struct {
+	Target         core.ColumnNames
+	ConstraintName string
+	DoUpdate       bool
+	ColNames       core.ColumnNames
+	AssignExpr     []ExpressionNode
+	Condition      ExpressionNode
+}
+
+func (o *OnConflictClause) toBackend(db backend.DB, tb backend.Table) *backend.OnConflict {
+	assignValFns := make([]func(backend.Row) (core.Value, error), 0, len(o.AssignExpr))
+	for _, expr := range o.AssignExpr {
+		assignValFns = append(assignValFns, trackConflictUpdate(db, scoped(db, expr.Eval())))
+	}
+	var condFunc func(backend.Row) (core.Value, error)
+	if o.Condition != nil {
+		condFunc = trackConflictUpdate(db, scoped(db, o.Condition.Eval()))
+	}
+
+	return &backend.OnConflict{
+		Target:         o.Target,
+		ConstraintName: o.ConstraintName,
+		DoUpdate:       o.DoUpdate,
+		ColNames:       o.ColNames,
+		AssignValFns:   assignCastFns(tb, o.ColNames, assignValFns),
+		Condition:      condFunc,
+	}
+}
+
+// Eval evaluates InsertNode
+func (c *InsertNode) Eval(db backend.DB) (backend.Table, error) {
+	// the target isn't a table defined by WITH clause
+	tb, err := dbOf(db).GetTable(c.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	valsList, err := c.sourceValues(db, tb)
+	if err != nil {
+		return nil, err
+	}
+	if valsList, err = assignCastValues(tb, c.ColumnNames, valsList); err != nil {
+		return nil, err
+	}
+	recordInserted(db, valsList)
+	var onConflict *backend.OnConflict
+	if c.OnConflict != nil {
+		onConflict = c.OnConflict.toBackend(db, tb)
+		onConflict.Alias = c.Alias
+	}
+	res, err := tb.Upsert(c.ColumnNames, valsList, onConflict, returningFunc(db, c.Returning, c.Alias))
+	if err != nil || c.Returning == nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *InsertNode) sourceValues(db backend.DB, tb backend.Table) (core.ValuesList, error) {
+	if c.Source == nil {
+		vals := make(core.Values, 0, len(tb.GetColNames()))
+		for range tb.GetColNames() {
+			vals = append(vals, core.Default)
+		}
+		return core.ValuesList{vals}, nil
+	}
+
+	src, err := c.Source.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	valsList := make(core.ValuesList, 0)
+	for _, row := range src.GetRows() {
+		valsList = append(valsList, row.GetValues())
+	}
+
+	return valsList, nil
+}
+
+func (c *InsertNode) isLogged(db backend.DB) bool {
+	return isLoggedTable(db, c.TableName)
+}
+
+// ValuesNode is a node of VALUES list
+type ValuesNode struct {
+	ExprsList [][]ExpressionNode
+}
+
+// Eval evaluates ValuesNode
+func (v *ValuesNode) Eval(db backend.DB) (backend.Table, error) {
+	numCols := 0
+	if len(v.ExprsList) > 0 {
+		numCols = len(v.ExprsList[0])
+	}
+
+	valsList := make(core.ValuesList, 0, len(v.ExprsList))
+	for _, exprs := range v.ExprsList {
+		if len(exprs) != numCols {
+			return nil, errors.New("ERROR:  VALUES lists must all be the same length")
+		}
+		vals := make(core.Values, 0, numCols)
+		for _, expr := range exprs {
+			val, err := scoped(db, expr.Eval())(&EmptyTableRow{})
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		valsList = append(valsList, vals)
+	}
+
+	cols := make(core.Cols, 0, numCols)
+	for k := 0; k < numCols; k++ {
+		col := core.Col{
+			ColName: core.ColumnName{Name: fmt.Sprintf("column%d", k+1)},
+			ColType: core.VarChar,
+		}
+		for _, vals := range valsList {
+			if typ, ok := core.TypeOf(vals[k]); ok {
+				col.ColType = typ
+				break
+			}
+		}
+		cols = append(cols, col)
+	}
+
+	return backend.NewTable("*VALUES*", cols, valsList), nil
+}
+
+// UpdateNode is a node of update statement
+type UpdateNode struct {
+	Condition  ExpressionNode
+	ColNames   core.ColumnNames
+	AssignExpr []ExpressionNode
+	TableName  string
+	Alias      string
+	// From is relations of FROM clause which are joined with the table
+	From      RelationalAlgebraNode
+	Returning *ProjectionNode
+}
+
+// Eval evaluates UpdateNode
+func (u *UpdateNode) Eval(db backend.DB) (backend.Table, error) {
+	var condFunc func(backend.Row) (core.Value, error)
+	if u.Condition == nil {
+		condFunc = func(row backend.Row) (core.Value, error) {
+			return core.True, nil
+		}
+	} else {
+		condFunc = scoped(db, u.Condition.Eval())
+	}
+
+	// the target isn't a table defined by WITH clause
+	tb, err := dbOf(db).GetTable(u.TableName)
+	if err != nil {
+		return nil, err
+	}
+	from, err := evalJoinedRelations(db, u.From)
+	if err != nil {
+		return nil, err
+	}
+
+	assignValFns := make([]func(backend.Row) (core.Value, error), 0)
+	for _, expr := range u.AssignExpr {
+		assignValFns = append(assignValFns, withAlias(scoped(db, expr.Eval()), tb.GetName(), u.Alias))
+	}
+
+	assignValFns = assignCastFns(tb, u.ColNames, assignValFns)
+
+	res, err := tb.Update(from, u.ColNames, withAlias(condFunc, tb.GetName(), u.Alias), assignValFns, returningFunc(db, u.Returning, u.Alias))
+	if err != nil || u.Returning == nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (u *UpdateNode) isLogged(db backend.DB) bool {
+	return isLoggedTable(db, u.TableName)
+}
+
+// DeleteNode is a node of update statement
 type DeleteNode struct {
 	Condition ExpressionNode
 	TableName string
+	Alias     string
+	// Using is relations of USING clause which are joined with the table
+	Using     RelationalAlgebraNode
+	Returning *ProjectionNode
 }
 
 // Eval evaluates DeleteNode
@@ -462,13 +1509,107 @@ func (d *DeleteNode) Eval(db backend.DB) (backend.Table, error) {
 			return core.True, nil
 		}
 	} else {
-		condFunc = d.Condition.Eval()
+		condFunc = scoped(db, d.Condition.Eval())
 	}
 
-	tb, err := db.GetTable(d.TableName)
+	// the target isn't a table defined by WITH clause
+	tb, err := dbOf(db).GetTable(d.TableName)
+	if err != nil {
+		return nil, err
+	}
+	using, err := evalJoinedRelations(db, d.Using)
 	if err != nil {
 		return nil, err
 	}
 
-	return tb.Delete(condFunc)
+	res, err := tb.Delete(using, withAlias(condFunc, tb.GetName(), d.Alias), returningFunc(db, d.Returning, d.Alias))
+	if err != nil || d.Returning == nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func evalJoinedRelations(db backend.DB, ra RelationalAlgebraNode) (backend.Table, error) {
+	if ra == nil {
+		return nil, nil
+	}
+
+	return ra.Eval(db)
+}
+
+// aliasRow is a row of the target table of UPDATE or DELETE which is referred by its alias
+type aliasRow struct {
+	backend.Row
+	tableName string
+	alias     string
+}
+
+// GetValueByColName gets value from row by ColName qualified by the alias
+func (r aliasRow) GetValueByColName(name core.ColumnName) (core.Value, error) {
+	if name.TableName == r.alias {
+		name.TableName = r.tableName
+	}
+
+	return r.Row.GetValueByColName(name)
+}
+
+func withAlias(fn func(backend.Row) (core.Value, error), tableName, alias string) func(backend.Row) (core.Value, error) {
+	if alias == "" {
+		return fn
+	}
+
+	return func(row backend.Row) (core.Value, error) {
+		return fn(aliasRow{Row: row, tableName: tableName, alias: alias})
+	}
+}
+
+// renameAffected qualifies columns of the target table in the affected rows by the alias.
+// Columns joined from FROM or USING keep their names.
+func renameAffected(tb backend.Table, alias string) backend.Table {
+	if alias == "" {
+		return tb
+	}
+
+	cols := tb.GetCols().Copy()
+	for k := range cols {
+		if cols[k].ColName.TableName == tb.GetName() {
+			cols[k].ColName.TableName = alias
+		}
+	}
+	valsList := make(core.ValuesList, 0, len(tb.GetRows()))
+	for _, row := range tb.GetRows() {
+		valsList = append(valsList, row.GetValues())
+	}
+
+	return backend.NewTable(alias, cols, valsList)
+}
+
+// returningFunc projects rows affected by INSERT, UPDATE or DELETE by RETURNING clause.
+// It's evaluated before the rows are applied to the table, so a failing RETURNING leaves the table unchanged.
+// The statement has no result if it doesn't have RETURNING clause.
+func returningFunc(db backend.DB, returning *ProjectionNode, alias string) backend.ReturningFunc {
+	if returning == nil {
+		return nil
+	}
+
+	return func(affected backend.Table) (backend.Table, error) {
+		p := *returning
+		p.RANode = &resultTableNode{Table: renameAffected(affected, alias)}
+		return p.Eval(db)
+	}
+}
+
+// resultTableNode is a node which gives an already evaluated table
+type resultTableNode struct {
+	Table backend.Table
+}
+
+// Eval returns the table
+func (r *resultTableNode) Eval(db backend.DB) (backend.Table, error) {
+	return r.Table, nil
+}
+
+func (d *DeleteNode) isLogged(db backend.DB) bool {
+	return isLoggedTable(db, d.TableName)
 }
@@ -659,16 +659,48 @@ func (s *SpyTable) GetCols() core.Cols {
 	return nil
 }
 
-func (s *SpyTable) InsertValues(cs core.ColumnNames, vs core.ValuesList) error {
+func (s *SpyTable) SetCols(cols core.Cols) {}
+
+func (s *SpyTable) GetPersistence() core.Persistence {
+	return core.Permanent
+}
+
+func (s *SpyTable) InsertValues(cs core.ColumnNames, vs core.ValuesList) (backend.Table, error) {
+	return nil, nil
+}
+
+func (s *SpyTable) Upsert(cs core.ColumnNames, vs core.ValuesList, oc *backend.OnConflict, returning backend.ReturningFunc) (backend.Table, error) {
+	return nil, nil
+}
+
+func (s *SpyTable) GetUniqueKeys() []core.UniqueKey {
 	return nil
 }
 
+func (s *SpyTable) SetUniqueKeys(keys []core.UniqueKey) {}
+
 func (s *SpyTable) RenameTableName(name string) {}
 
 func (s *SpyTable) Project(cs core.ColumnNames, fns []func(backend.Row) (core.Value, error)) (backend.Table, error) {
 	return nil, nil
 }
 
+func (s *SpyTable) Join(backend.Table, backend.JoinType, func(backend.Row) (core.Value, error)) (backend.Table, error) {
+	return nil, nil
+}
+
+func (s *SpyTable) HashJoin(backend.Table, backend.JoinType, backend.JoinKeys, func(backend.Row) (core.Value, error)) (backend.Table, error) {
+	return nil, nil
+}
+
+func (s *SpyTable) MergeJoin(backend.Table, backend.JoinType, backend.JoinKeys, func(backend.Row) (core.Value, error)) (backend.Table, error) {
+	return nil, nil
+}
+
+func (s *SpyTable) LateralJoin(func(backend.Row) (backend.Table, error), backend.JoinType, func(backend.Row) (core.Value, error)) (backend.Table, error) {
+	return nil, nil
+}
+
 func (s *SpyTable) Where(fn func(backend.Row) (core.Value, error)) (backend.Table, error) {
 	return nil, nil
 }
@@ -677,7 +709,7 @@ func (s *SpyTable) CrossJoin(backend.Table) (backend.Table, error) {
 	return nil, nil
 }
 
-func (s *SpyTable) OrderBy(names core.ColumnNames, dirs []int) (backend.Table, error) {
+func (s *SpyTable) OrderBy(keyFns []func(backend.Row) (core.Value, error), cmp func(x, y core.Values) (int, error)) (backend.Table, error) {
 	return nil, nil
 }
 
@@ -685,14 +717,22 @@ func (s *SpyTable) Limit(n int) (backend.Table, error) {
 	return nil, nil
 }
 
-func (t *SpyTable) Update(colNames core.ColumnNames, condFn func(backend.Row) (core.Value, error), assignValFns []func(backend.Row) (core.Value, error)) (backend.Table, error) {
+func (s *SpyTable) Offset(n int) (backend.Table, error) {
+	return nil, nil
+}
+
+func (t *SpyTable) Update(from backend.Table, colNames core.ColumnNames, condFn func(backend.Row) (core.Value, error), assignValFns []func(backend.Row) (core.Value, error), returning backend.ReturningFunc) (backend.Table, error) {
 	return nil, nil
 }
 
-func (s *SpyTable) Delete(fn func(backend.Row) (core.Value, error)) (backend.Table, error) {
+func (s *SpyTable) Delete(using backend.Table, fn func(backend.Row) (core.Value, error), returning backend.ReturningFunc) (backend.Table, error) {
 	return nil, nil
 }
 
+func (s *SpyTable) Truncate(restartIdentity bool) error {
+	return nil
+}
+
 type SpyRow struct {
 	MockRow  backend.Row
 	Values   core.Values
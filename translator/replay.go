@@ -0,0 +1,308 @@
+package translator
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+	pg_query "github.com/pganalyze/pg_query_go/v2"
+)
+
+// replayDB is DB which is given to a statement when it's evaluated.
+// It records whether the statement depends on data which replaying the durability log doesn't reproduce,
+// that is rows of temporary and unlogged tables and results of volatile functions such as random().
+type replayDB struct {
+	backend.DB
+	volatile bool
+	// inserted is the values which INSERT evaluated from volatile data
+	inserted core.ValuesList
+	// conflictUpdated is true if DO UPDATE of ON CONFLICT updated rows with volatile data
+	conflictUpdated bool
+}
+
+// GetTable gets the table and records whether its rows are reproduced by replaying the log
+func (r *replayDB) GetTable(name string) (backend.Table, error) {
+	tb, err := r.DB.GetTable(name)
+	if err == nil && tb.GetPersistence() != core.Permanent {
+		r.volatile = true
+	}
+
+	return tb, err
+}
+
+func replayDBOf(db backend.DB) *replayDB {
+	r, _ := dbOf(db).(*replayDB)
+	return r
+}
+
+// markVolatile records that the statement which evaluates row calls a volatile function
+func markVolatile(row backend.Row) {
+	if s, ok := row.(*scopedRow); ok {
+		if r := replayDBOf(s.db); r != nil {
+			r.volatile = true
+		}
+	}
+}
+
+// recordInserted records the values which INSERT inserts if they are evaluated from volatile data
+func recordInserted(db backend.DB, valsList core.ValuesList) {
+	if r := replayDBOf(db); r != nil && r.volatile {
+		r.inserted = valsList
+	}
+}
+
+// trackConflictUpdate wraps a function evaluated by DO UPDATE of ON CONFLICT
+// so that it records whether the function reads volatile data
+func trackConflictUpdate(db backend.DB, fn func(backend.Row) (core.Value, error)) func(backend.Row) (core.Value, error) {
+	r := replayDBOf(db)
+	if r == nil {
+		return fn
+	}
+
+	return func(row backend.Row) (core.Value, error) {
+		volatile := r.volatile
+		r.volatile = false
+		v, err := fn(row)
+		if r.volatile {
+			r.conflictUpdated = true
+		}
+		r.volatile = r.volatile || volatile
+		return v, err
+	}
+}
+
+// LogQuery returns the query which is written to the durability log for the evaluated statement.
+// If the statement depends on data which replaying the log doesn't reproduce, the query is rewritten:
+// INSERT inserts the evaluated values and is followed by the contents of the table if DO UPDATE of ON CONFLICT
+// updated rows with volatile data, CREATE TABLE AS and SELECT INTO create the table and insert its rows,
+// and UPDATE and DELETE are replaced with the contents of the modified tables.
+// An empty string is returned when nothing has to be logged.
+func (qs *QueryStatement) LogQuery(db backend.DB, query string) (string, error) {
+	if qs.replay == nil || !qs.replay.volatile {
+		return query, nil
+	}
+
+	result, err := pg_query.Parse(query)
+	if err != nil {
+		return "", err
+	}
+	stmts := make([]*pg_query.RawStmt, 0)
+	if c, ok := qs.RANode.(*CreateTableAsNode); ok {
+		tb, err := db.GetTable(c.TableName)
+		if err != nil {
+			return "", err
+		}
+		stmts = createTableStmts(tb, c.Persistence, !c.WithNoData)
+	} else if ins := result.Stmts[0].Stmt.GetInsertStmt(); ins != nil && !hasModifyingCTE(ins.GetWithClause()) {
+		if qs.replay.inserted == nil && !qs.replay.conflictUpdated {
+			// only RETURNING depends on the volatile data
+			return query, nil
+		}
+		if qs.replay.inserted != nil {
+			if len(qs.replay.inserted) == 0 {
+				return "", nil
+			}
+			ins.WithClause = nil
+			ins.SelectStmt = valuesStmt(qs.replay.inserted)
+		}
+		ins.ReturningList = nil
+		if qs.replay.conflictUpdated {
+			// the inserted rows advance sequences, and the updated rows are reproduced by the contents of the table
+			ins.OnConflictClause.Action = pg_query.OnConflictAction_ONCONFLICT_NOTHING
+			ins.OnConflictClause.TargetList = nil
+			ins.OnConflictClause.WhereClause = nil
+		}
+		stmts = append(stmts, result.Stmts[0])
+		if qs.replay.conflictUpdated {
+			tb, err := db.GetTable(ins.GetRelation().GetRelname())
+			if err != nil {
+				return "", err
+			}
+			stmts = append(stmts, snapshotStmts(tb)...)
+		}
+	} else {
+		for _, name := range modifiedTables(qs.RANode) {
+			tb, err := db.GetTable(name)
+			if err != nil {
+				return "", err
+			}
+			stmts = append(stmts, snapshotStmts(tb)...)
+		}
+	}
+	if len(stmts) == 0 {
+		return query, nil
+	}
+
+	logQuery, err := pg_query.Deparse(&pg_query.ParseResult{Version: result.Version, Stmts: stmts})
+	if err != nil {
+		return "", err
+	}
+
+	return logQuery + ";", nil
+}
+
+// hasModifyingCTE reports whether WITH clause has INSERT, UPDATE or DELETE
+func hasModifyingCTE(with *pg_query.WithClause) bool {
+	for _, cte := range with.GetCtes() {
+		query := cte.GetCommonTableExpr().GetCtequery()
+		if query.GetInsertStmt() != nil || query.GetUpdateStmt() != nil || query.GetDeleteStmt() != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// modifiedTables returns names of the tables which are modified by ra and its WITH clause
+func modifiedTables(ra RelationalAlgebraNode) []string {
+	names := make([]string, 0)
+	if w, ok := ra.(*WithNode); ok {
+		for _, cte := range w.CTEs {
+			if name, ok := modifiedTable(cte.Query); ok {
+				names = append(names, name)
+			}
+		}
+		ra = w.Query
+	}
+	if name, ok := modifiedTable(ra); ok {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// snapshotStmts makes TRUNCATE and INSERT statements which reproduce the rows of the table.
+// Sequences are left as they are because UPDATE and DELETE don't advance them.
+func snapshotStmts(tb backend.Table) []*pg_query.RawStmt {
+	truncate := &pg_query.Node{Node: &pg_query.Node_TruncateStmt{TruncateStmt: &pg_query.TruncateStmt{
+		Relations: []*pg_query.Node{pg_query.MakeSimpleRangeVarNode(tb.GetName(), -1)},
+		Behavior:  pg_query.DropBehavior_DROP_RESTRICT,
+	}}}
+	stmts := []*pg_query.RawStmt{{Stmt: truncate}}
+	if len(tb.GetRows()) == 0 {
+		return stmts
+	}
+
+	return append(stmts, insertRowsStmt(tb))
+}
+
+// createTableStmts makes CREATE TABLE and INSERT statements which reproduce the table made by CREATE TABLE AS.
+// Rows are inserted if withData is true.
+func createTableStmts(tb backend.Table, persistence core.Persistence, withData bool) []*pg_query.RawStmt {
+	elts := make([]*pg_query.Node, 0, len(tb.GetCols()))
+	for _, col := range tb.GetCols() {
+		elts = append(elts, &pg_query.Node{Node: &pg_query.Node_ColumnDef{ColumnDef: &pg_query.ColumnDef{
+			Colname: col.ColName.Name,
+			TypeName: &pg_query.TypeName{
+				Names:   []*pg_query.Node{pg_query.MakeStrNode("pg_catalog"), pg_query.MakeStrNode(backend.InternalTypeName(col.ColType))},
+				Typemod: -1,
+			},
+			IsLocal: true,
+		}}})
+	}
+	relation := pg_query.MakeSimpleRangeVar(tb.GetName(), -1)
+	if persistence == core.Unlogged {
+		relation.Relpersistence = "u"
+	}
+	create := &pg_query.Node{Node: &pg_query.Node_CreateStmt{CreateStmt: &pg_query.CreateStmt{
+		Relation:  relation,
+		TableElts: elts,
+		Oncommit:  pg_query.OnCommitAction_ONCOMMIT_NOOP,
+	}}}
+	stmts := []*pg_query.RawStmt{{Stmt: create}}
+	if !withData || len(tb.GetRows()) == 0 {
+		return stmts
+	}
+
+	return append(stmts, insertRowsStmt(tb))
+}
+
+// insertRowsStmt makes INSERT statement which inserts the rows of the table
+func insertRowsStmt(tb backend.Table) *pg_query.RawStmt {
+	cols := make([]*pg_query.Node, 0, len(tb.GetColNames()))
+	for _, name := range tb.GetColNames() {
+		cols = append(cols, pg_query.MakeResTargetNodeWithName(name.Name, -1))
+	}
+	valsList := make(core.ValuesList, 0, len(tb.GetRows()))
+	for _, row := range tb.GetRows() {
+		valsList = append(valsList, row.GetValues())
+	}
+	insert := &pg_query.Node{Node: &pg_query.Node_InsertStmt{InsertStmt: &pg_query.InsertStmt{
+		Relation:   pg_query.MakeSimpleRangeVar(tb.GetName(), -1),
+		Cols:       cols,
+		SelectStmt: valuesStmt(valsList),
+		Override:   pg_query.OverridingKind_OVERRIDING_NOT_SET,
+	}}}
+
+	return &pg_query.RawStmt{Stmt: insert}
+}
+
+func valuesStmt(valsList core.ValuesList) *pg_query.Node {
+	lists := make([]*pg_query.Node, 0, len(valsList))
+	for _, vals := range valsList {
+		items := make([]*pg_query.Node, 0, len(vals))
+		for _, val := range vals {
+			items = append(items, constNode(val))
+		}
+		lists = append(lists, pg_query.MakeListNode(items))
+	}
+
+	return &pg_query.Node{Node: &pg_query.Node_SelectStmt{SelectStmt: &pg_query.SelectStmt{
+		ValuesLists: lists,
+		LimitOption: pg_query.LimitOption_LIMIT_OPTION_DEFAULT,
+		Op:          pg_query.SetOperation_SETOP_NONE,
+	}}}
+}
+
+// constNode makes a constant expression whose value is v when it's translated again
+func constNode(v core.Value) *pg_query.Node {
+	switch val := v.(type) {
+	case int:
+		if val >= math.MinInt32 && val <= math.MaxInt32 {
+			return pg_query.MakeAConstIntNode(int64(val), -1)
+		}
+		// an integer literal out of the range of int4 is parsed as numeric
+		return typeCastConstNode(strconv.Itoa(val), "int8")
+	case float64:
+		if math.IsInf(val, 0) || math.IsNaN(val) {
+			return typeCastConstNode(castToText(val, "float8", nil).(string), "float8")
+		}
+		s := strconv.FormatFloat(val, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			// a literal without a decimal point is parsed as an integer
+			s += ".0"
+		}
+		return &pg_query.Node{Node: &pg_query.Node_AConst{AConst: &pg_query.A_Const{
+			Val: &pg_query.Node{Node: &pg_query.Node_Float{Float: &pg_query.Float{Str: s}}},
+		}}}
+	case string:
+		return pg_query.MakeAConstStrNode(val, -1)
+	case core.DateType:
+		return typeCastConstNode(val.String(), "date")
+	case core.DefaultType:
+		return &pg_query.Node{Node: &pg_query.Node_SetToDefault{SetToDefault: &pg_query.SetToDefault{}}}
+	}
+
+	switch v {
+	case core.True:
+		return typeCastConstNode("t", "bool")
+	case core.False:
+		return typeCastConstNode("f", "bool")
+	}
+
+	return &pg_query.Node{Node: &pg_query.Node_AConst{AConst: &pg_query.A_Const{
+		Val: &pg_query.Node{Node: &pg_query.Node_Null{Null: &pg_query.Null{}}},
+	}}}
+}
+
+func typeCastConstNode(s, typeName string) *pg_query.Node {
+	return &pg_query.Node{Node: &pg_query.Node_TypeCast{TypeCast: &pg_query.TypeCast{
+		Arg: pg_query.MakeAConstStrNode(s, -1),
+		TypeName: &pg_query.TypeName{
+			Names:   []*pg_query.Node{pg_query.MakeStrNode("pg_catalog"), pg_query.MakeStrNode(typeName)},
+			Typemod: -1,
+		},
+	}}}
+}
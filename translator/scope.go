@@ -0,0 +1,141 @@
+package translator
+
+import (
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+)
+
+// scopeDB is DB which is given to a node evaluated in a scope of a query.
+// A node evaluated for each row of an outer query such as a LATERAL subquery can refer to
+// the outer row. Columns which are not found in the node's own rows are looked up in the outer row.
+// Tables defined by WITH clauses are looked up before tables of the database.
+type scopeDB struct {
+	backend.DB
+	outer backend.Row
+	ctes  map[string]*cteRef
+}
+
+// GetTable gets the table defined by WITH clause or the table of the database
+func (s *scopeDB) GetTable(name string) (backend.Table, error) {
+	if ref, ok := s.ctes[name]; ok {
+		return ref.getTable()
+	}
+
+	return s.DB.GetTable(name)
+}
+
+// withOuterRow returns DB whose outer row is row.
+// If db already has an outer row, it is searched after row.
+func withOuterRow(db backend.DB, row backend.Row) backend.DB {
+	if outer := outerRowOf(db); outer != nil {
+		row = &scopedRow{Row: row, outer: outer}
+	}
+
+	return &scopeDB{DB: dbOf(db), outer: row, ctes: ctesOf(db)}
+}
+
+// withCTE returns DB in which the table named name is ref
+func withCTE(db backend.DB, name string, ref *cteRef) backend.DB {
+	ctes := make(map[string]*cteRef)
+	for k, v := range ctesOf(db) {
+		ctes[k] = v
+	}
+	ctes[name] = ref
+
+	return &scopeDB{DB: dbOf(db), outer: outerRowOf(db), ctes: ctes}
+}
+
+func outerRowOf(db backend.DB) backend.Row {
+	if s, ok := db.(*scopeDB); ok {
+		return s.outer
+	}
+
+	return nil
+}
+
+func ctesOf(db backend.DB) map[string]*cteRef {
+	if s, ok := db.(*scopeDB); ok {
+		return s.ctes
+	}
+
+	return nil
+}
+
+func dbOf(db backend.DB) backend.DB {
+	if s, ok := db.(*scopeDB); ok {
+		return s.DB
+	}
+
+	return db
+}
+
+// outerColNames returns names of columns which are visible from the outer rows of db
+func outerColNames(db backend.DB) core.ColumnNames {
+	names := make(core.ColumnNames, 0)
+	row := outerRowOf(db)
+	for row != nil {
+		if t, ok := row.(*trackedRow); ok {
+			row = t.Row
+			continue
+		}
+		s, ok := row.(*scopedRow)
+		if !ok {
+			return append(names, row.GetColNames()...)
+		}
+		if s.Row != nil {
+			names = append(names, s.Row.GetColNames()...)
+		}
+		row = s.outer
+	}
+
+	return names
+}
+
+// scoped makes fn refer to the outer row of db when a column isn't found in the given row.
+// The row given to fn also holds db so that subqueries in the expression can be evaluated.
+func scoped(db backend.DB, fn func(backend.Row) (core.Value, error)) func(backend.Row) (core.Value, error) {
+	outer := outerRowOf(db)
+	cache := make(subqueryCache)
+
+	return func(row backend.Row) (core.Value, error) {
+		return fn(&scopedRow{Row: row, outer: outer, db: db, cache: cache})
+	}
+}
+
+// scopedRow is a row which can refer to columns of the outer row
+type scopedRow struct {
+	backend.Row
+	outer backend.Row
+	// db and cache are used to evaluate subqueries in expressions
+	db    backend.DB
+	cache subqueryCache
+}
+
+// GetValueByColName gets value from the row. If the row doesn't have the column,
+// the value is taken from the outer row.
+func (r *scopedRow) GetValueByColName(name core.ColumnName) (core.Value, error) {
+	if r.outer != nil && (r.Row == nil || !haveColumn(name, r.Row.GetColNames())) {
+		return r.outer.GetValueByColName(name)
+	}
+
+	return r.Row.GetValueByColName(name)
+}
+
+// trackedRow records whether a value of the row is referred.
+// It tells whether a subquery depends on its outer row.
+type trackedRow struct {
+	backend.Row
+	referred bool
+}
+
+// GetValueByColName gets value from the row
+func (r *trackedRow) GetValueByColName(name core.ColumnName) (core.Value, error) {
+	r.referred = true
+	return r.Row.GetValueByColName(name)
+}
+
+// GetValues gets values from the row
+func (r *trackedRow) GetValues() core.Values {
+	r.referred = true
+	return r.Row.GetValues()
+}
@@ -0,0 +1,186 @@
+package translator
+
+import (
+	"fmt"
+
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+)
+
+// SetOpKind is a kind of set operation
+type SetOpKind int
+
+const (
+	// Union returns rows of both queries
+	Union SetOpKind = iota
+
+	// Intersect returns rows which are in both queries
+	Intersect
+
+	// Except returns rows of the left query which are not in the right query
+	Except
+)
+
+func (k SetOpKind) String() string {
+	switch k {
+	case Intersect:
+		return "INTERSECT"
+	case Except:
+		return "EXCEPT"
+	}
+
+	return "UNION"
+}
+
+// SetOpNode is a node of UNION, INTERSECT and EXCEPT.
+// Duplicated rows are removed unless All is true. The columns of the result are named after the left query.
+type SetOpNode struct {
+	Op    SetOpKind
+	All   bool
+	Left  RelationalAlgebraNode
+	Right RelationalAlgebraNode
+}
+
+// Eval evaluates SetOpNode
+func (s *SetOpNode) Eval(db backend.DB) (backend.Table, error) {
+	ltb, err := s.Left.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	rtb, err := s.Right.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+
+	cols, err := s.resultCols(ltb, rtb)
+	if err != nil {
+		return nil, err
+	}
+	lvals := setOpValues(ltb, cols)
+	rvals := setOpValues(rtb, cols)
+
+	var valsList core.ValuesList
+	switch s.Op {
+	case Union:
+		valsList = append(lvals, rvals...)
+		if !s.All {
+			valsList = distinctValues(valsList)
+		}
+	case Intersect, Except:
+		valsList = s.filterLeft(lvals, rvals)
+	}
+
+	return backend.NewTable("", cols, valsList), nil
+}
+
+// resultCols checks that both tables have the same number of columns of compatible types.
+// An integer column and a float column are resolved to float.
+func (s *SetOpNode) resultCols(ltb, rtb backend.Table) (core.Cols, error) {
+	lcols, rcols := ltb.GetCols(), rtb.GetCols()
+	if len(lcols) != len(rcols) {
+		return nil, fmt.Errorf("ERROR:  each %v query must have the same number of columns", s.Op)
+	}
+
+	cols := make(core.Cols, 0, len(lcols))
+	for k := range lcols {
+		ltyp, rtyp := lcols[k].ColType, rcols[k].ColType
+		typ := ltyp
+		switch {
+		case ltyp == rtyp:
+		case allNull(ltb.GetRows(), k):
+			// the type of NULL literal is resolved by the other query
+			typ = rtyp
+		case allNull(rtb.GetRows(), k):
+		case isNumericType(ltyp) && isNumericType(rtyp):
+			typ = core.Float
+		default:
+			return nil, fmt.Errorf("ERROR:  %v types %v and %v cannot be matched", s.Op, core.ColTypeName(ltyp), core.ColTypeName(rtyp))
+		}
+		cols = append(cols, core.Col{
+			ColName: core.ColumnName{Name: lcols[k].ColName.Name},
+			ColType: typ,
+		})
+	}
+
+	return cols, nil
+}
+
+func allNull(rows []backend.Row, k int) bool {
+	for _, row := range rows {
+		if !isNull(row.GetValues()[k]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isNumericType(typ core.ColType) bool {
+	return typ == core.Integer || typ == core.Float
+}
+
+// setOpValues returns values of rows of tb. Integers in float columns are converted to floats
+// so that the same numbers are equal.
+func setOpValues(tb backend.Table, cols core.Cols) core.ValuesList {
+	valsList := make(core.ValuesList, 0, len(tb.GetRows()))
+	for _, row := range tb.GetRows() {
+		vals := make(core.Values, 0, len(cols))
+		for k, v := range row.GetValues() {
+			if i, ok := v.(int); ok && cols[k].ColType == core.Float {
+				v = float64(i)
+			}
+			vals = append(vals, v)
+		}
+		valsList = append(valsList, vals)
+	}
+
+	return valsList
+}
+
+// distinctValues removes duplicated values keeping the first ones
+func distinctValues(valsList core.ValuesList) core.ValuesList {
+	seen := make(map[interface{}]bool)
+	res := make(core.ValuesList, 0, len(valsList))
+	for _, vals := range valsList {
+		h := core.HashKey(vals)
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		res = append(res, vals)
+	}
+
+	return res
+}
+
+// filterLeft returns rows of the left query for INTERSECT and EXCEPT.
+// With ALL, a row which appears m times in the left and n times in the right appears
+// min(m, n) times in INTERSECT ALL and max(m - n, 0) times in EXCEPT ALL.
+func (s *SetOpNode) filterLeft(lvals, rvals core.ValuesList) core.ValuesList {
+	counts := make(map[interface{}]int)
+	for _, vals := range rvals {
+		counts[core.HashKey(vals)]++
+	}
+
+	seen := make(map[interface{}]bool)
+	res := make(core.ValuesList, 0)
+	for _, vals := range lvals {
+		h := core.HashKey(vals)
+		inRight := counts[h] > 0
+		if s.All {
+			if inRight {
+				counts[h]--
+			}
+		} else {
+			if seen[h] {
+				continue
+			}
+			seen[h] = true
+		}
+		if inRight == (s.Op == Intersect) {
+			res = append(res, vals)
+		}
+	}
+
+	return res
+}
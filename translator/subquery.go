@@ -0,0 +1,192 @@
+package translator
+
+import (
+	"errors"
+
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+)
+
+// SubLinkType is a kind of subquery in an expression
+type SubLinkType int
+
+const (
+	// ExistsSubLink is EXISTS (SELECT ...)
+	ExistsSubLink SubLinkType = iota
+
+	// AnySubLink is `x op ANY (SELECT ...)`. `x IN (SELECT ...)` is `x = ANY (SELECT ...)`.
+	AnySubLink
+
+	// AllSubLink is `x op ALL (SELECT ...)`
+	AllSubLink
+
+	// ExprSubLink is a scalar subquery (SELECT ...)
+	ExprSubLink
+)
+
+// SubLinkNode is expression of a subquery.
+// The subquery can refer to columns of the row for which the expression is evaluated.
+type SubLinkNode struct {
+	Type     SubLinkType
+	Testexpr ExpressionNode
+	Op       MathOp
+	Subquery RelationalAlgebraNode
+}
+
+// Eval evaluates SubLinkNode
+func (n *SubLinkNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(row backend.Row) (core.Value, error) {
+		s, ok := row.(*scopedRow)
+		if !ok {
+			return nil, errors.New("ERROR:  cannot use subquery in this context")
+		}
+		res, err := n.evalSubquery(s)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n.Type {
+		case ExistsSubLink:
+			return toSQLBool(len(res.rows) > 0), nil
+		case ExprSubLink:
+			if res.ncols != 1 {
+				return nil, errors.New("ERROR:  subquery must return only one column")
+			}
+			switch len(res.rows) {
+			case 0:
+				return core.Null, nil
+			case 1:
+				return nullIfNil(res.rows[0].GetValues()[0]), nil
+			}
+			return nil, errors.New("ERROR:  more than one row returned by a subquery used as an expression")
+		}
+
+		if res.ncols > 1 {
+			return nil, errors.New("ERROR:  subquery has too many columns")
+		}
+		lval, err := n.Testexpr.Eval()(row)
+		if err != nil {
+			return nil, err
+		}
+		if n.Type == AnySubLink && n.Op == EqualOp {
+			return res.contains(nullIfNil(lval)), nil
+		}
+
+		return n.compareAll(nullIfNil(lval), res.rows)
+	}
+}
+
+// evalSubquery evaluates the subquery for the row. The result is reused for other rows
+// unless the subquery refers to the row.
+func (n *SubLinkNode) evalSubquery(s *scopedRow) (*subqueryResult, error) {
+	if res, ok := s.cache[n]; ok {
+		return res, nil
+	}
+
+	outer := &trackedRow{Row: s.Row}
+	tb, err := n.Subquery.Eval(withOuterRow(s.db, outer))
+	if err != nil {
+		return nil, err
+	}
+	res := &subqueryResult{}
+	if tb != nil {
+		res.rows = tb.GetRows()
+		res.ncols = len(tb.GetColNames())
+	}
+	if !outer.referred {
+		s.cache[n] = res
+	}
+
+	return res, nil
+}
+
+// compareAll compares lval with the first column of rows.
+// ANY is true if some comparison is true and ALL is true if all comparisons are true.
+// Otherwise the result is NULL if some comparison is NULL.
+func (n *SubLinkNode) compareAll(lval core.Value, rows []backend.Row) (core.Value, error) {
+	var res core.Value = core.False
+	if n.Type == AllSubLink {
+		res = core.True
+	}
+	for _, row := range rows {
+		cmp := BinOpNode{Op: n.Op, Lexpr: valueNode{val: lval}, Rexpr: valueNode{val: nullIfNil(row.GetValues()[0])}}
+		v, err := cmp.Eval()(row)
+		if err != nil {
+			return nil, err
+		}
+		if n.Type == AllSubLink {
+			res = core.AND(res, v)
+			if res == core.False {
+				break
+			}
+		} else {
+			res = core.OR(res, v)
+			if res == core.True {
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// subqueryCache holds results of subqueries which don't refer to outer rows
+type subqueryCache map[*SubLinkNode]*subqueryResult
+
+type subqueryResult struct {
+	rows  []backend.Row
+	ncols int
+
+	// set is made from the first column of rows when the result is tested by `= ANY`
+	set     map[interface{}]struct{}
+	hasNull bool
+}
+
+// contains evaluates `val IN (subquery)`
+func (r *subqueryResult) contains(val core.Value) core.Value {
+	if len(r.rows) == 0 {
+		return core.False
+	}
+	if val == core.Null {
+		return core.Null
+	}
+	if r.set == nil {
+		r.set = make(map[interface{}]struct{}, len(r.rows))
+		for _, row := range r.rows {
+			v := nullIfNil(row.GetValues()[0])
+			if v == core.Null {
+				r.hasNull = true
+				continue
+			}
+			r.set[core.HashKey(core.Values{v})] = struct{}{}
+		}
+	}
+	if _, ok := r.set[core.HashKey(core.Values{val})]; ok {
+		return core.True
+	}
+	if r.hasNull {
+		return core.Null
+	}
+
+	return core.False
+}
+
+// valueNode is expression of an evaluated value
+type valueNode struct {
+	val core.Value
+}
+
+// Eval evaluates valueNode
+func (n valueNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(backend.Row) (core.Value, error) {
+		return n.val, nil
+	}
+}
+
+func nullIfNil(val core.Value) core.Value {
+	if val == nil {
+		return core.Null
+	}
+
+	return val
+}
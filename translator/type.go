@@ -0,0 +1,188 @@
+package translator
+
+import (
+	"github.com/goropikari/psqlittle/core"
+)
+
+// funcResultTypes are the types of results of scalar functions.
+// The other functions such as abs and greatest return the type of their arguments.
+var funcResultTypes = map[string]core.ColType{
+	"lower":               core.VarChar,
+	"upper":               core.VarChar,
+	"length":              core.Integer,
+	"substring":           core.VarChar,
+	"position":            core.Integer,
+	"btrim":               core.VarChar,
+	"ltrim":               core.VarChar,
+	"rtrim":               core.VarChar,
+	"replace":             core.VarChar,
+	"split_part":          core.VarChar,
+	"concat":              core.VarChar,
+	"format":              core.VarChar,
+	"power":               core.Float,
+	"pow":                 core.Float,
+	"sqrt":                core.Float,
+	"random":              core.Float,
+	"like_escape":         core.VarChar,
+	"similar_to_escape":   core.VarChar,
+	"pg_table_is_visible": core.Boolean,
+	"pg_get_userbyid":     core.VarChar,
+	"format_type":         core.VarChar,
+	"pg_get_expr":         core.VarChar,
+	"regexp_replace":      core.VarChar,
+}
+
+// exprType derives the type of the result of expr from the types of the columns which it refers to.
+// It returns false if the type can't be derived such as NULL and a scalar subquery.
+func exprType(expr ExpressionNode, cols core.Cols) (core.ColType, bool) {
+	switch e := expr.(type) {
+	case IntegerNode, *IntegerNode:
+		return core.Integer, true
+	case FloatNode, *FloatNode:
+		return core.Float, true
+	case StringNode, *StringNode:
+		return core.VarChar, true
+	case BoolConstNode:
+		return core.Boolean, e.Bool != core.Null
+	case *BoolConstNode:
+		return core.Boolean, e.Bool != core.Null
+	case ColRefNode:
+		return colTypeOf(e.ColName, cols)
+	case *ColRefNode:
+		return colTypeOf(e.ColName, cols)
+	case *TypeCastNode:
+		return castTypes[e.TypeName].colType, true
+	case BinOpNode:
+		return binOpType(e, cols)
+	case *BinOpNode:
+		return binOpType(*e, cols)
+	case ANDNode, *ANDNode, ORNode, *ORNode, NotNode, *NotNode, NullTestNode, *NullTestNode, *BoolTestNode, *DistinctFromNode:
+		return core.Boolean, true
+	case *CaseNode:
+		return firstExprType(append(append([]ExpressionNode{}, e.CaseResultExprs...), e.DefaultResult), cols)
+	case *CoalesceNode:
+		return firstExprType(e.Args, cols)
+	case *NullIfNode:
+		return exprType(e.Lexpr, cols)
+	case *FuncCallNode:
+		if typ, ok := funcResultTypes[e.FuncName]; ok {
+			return typ, true
+		}
+		return numericExprType(e.Args, cols)
+	case *AggCallNode:
+		return aggResultType(e.FuncName, e.Args, cols)
+	case *WindowFuncNode:
+		return windowResultType(e, cols)
+	case *GroupingFuncNode:
+		return core.Integer, true
+	case *GroupedAliasNode:
+		return exprType(e.Expr, cols)
+	case *SubLinkNode:
+		return core.Boolean, e.Type != ExprSubLink
+	}
+
+	return core.Integer, false
+}
+
+// colTypeOf returns the type of the column which name refers to
+func colTypeOf(name core.ColumnName, cols core.Cols) (core.ColType, bool) {
+	for _, col := range cols {
+		if name.Matches(col.ColName) {
+			return col.ColType, true
+		}
+	}
+
+	return core.Integer, false
+}
+
+// binOpType is the type of the result of an operator, which follows BinOpNode.Eval
+func binOpType(e BinOpNode, cols core.Cols) (core.ColType, bool) {
+	switch e.Op {
+	case Plus, Minus, Multiply, Divide:
+	case CONCAT:
+		return core.VarChar, true
+	default:
+		return core.Boolean, true
+	}
+
+	ltyp, lok := exprType(e.Lexpr, cols)
+	rtyp, rok := exprType(e.Rexpr, cols)
+	lDate, rDate := lok && ltyp == core.Date, rok && rtyp == core.Date
+	if lDate || rDate {
+		// date - date is the number of days, and date +/- integer is a date.
+		// A string literal operated with a date is a date.
+		_, lLit := e.Lexpr.(StringNode)
+		_, rLit := e.Rexpr.(StringNode)
+		if (lDate || lLit) && (rDate || rLit) {
+			return core.Integer, e.Op == Minus
+		}
+		return core.Date, true
+	}
+
+	return numericExprType([]ExpressionNode{e.Lexpr, e.Rexpr}, cols)
+}
+
+// numericExprType is the type of the result of a calculation of exprs.
+// It's float if any of them is float, otherwise the type of the first expression whose type is known.
+func numericExprType(exprs []ExpressionNode, cols core.Cols) (core.ColType, bool) {
+	typ, found := core.Integer, false
+	for _, expr := range exprs {
+		t, ok := exprType(expr, cols)
+		if !ok {
+			continue
+		}
+		if t == core.Float {
+			return core.Float, true
+		}
+		if !found {
+			typ, found = t, true
+		}
+	}
+
+	return typ, found
+}
+
+// firstExprType is the type of the first expression in exprs whose type is known
+func firstExprType(exprs []ExpressionNode, cols core.Cols) (core.ColType, bool) {
+	for _, expr := range exprs {
+		if typ, ok := exprType(expr, cols); ok {
+			return typ, true
+		}
+	}
+
+	return core.Integer, false
+}
+
+// aggResultType is the type of the result of the aggregate function
+func aggResultType(name string, args []ExpressionNode, cols core.Cols) (core.ColType, bool) {
+	switch name {
+	case "count":
+		return core.Integer, true
+	case "avg":
+		return core.Float, true
+	case "string_agg", "array_agg":
+		return core.VarChar, true
+	case "bool_and", "bool_or", "every":
+		return core.Boolean, true
+	}
+
+	// sum, min and max return the type of the argument
+	return firstExprType(args, cols)
+}
+
+// windowResultType is the type of the result of the window function
+func windowResultType(w *WindowFuncNode, cols core.Cols) (core.ColType, bool) {
+	switch w.FuncName {
+	case "row_number", "rank", "dense_rank", "ntile":
+		return core.Integer, true
+	case "percent_rank", "cume_dist":
+		return core.Float, true
+	case "lag", "lead", "first_value", "last_value", "nth_value":
+		if len(w.Args) == 0 {
+			return core.Integer, false
+		}
+		return exprType(w.Args[0], cols)
+	}
+
+	return aggResultType(w.FuncName, w.Args, cols)
+}
@@ -0,0 +1,799 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/goropikari/psqlittle/backend"
+	"github.com/goropikari/psqlittle/core"
+)
+
+// WindowFuncNode is expression of a window function call such as row_number() OVER (...)
+// and an aggregate function call with OVER clause.
+// It is replaced with a reference to a column of WindowNode when the query is translated.
+type WindowFuncNode struct {
+	FuncName string
+	Args     []ExpressionNode
+	// Star is true for count(*)
+	Star bool
+	// Filter is the condition of FILTER (WHERE ...) of an aggregate function
+	Filter ExpressionNode
+	// WindowName is the name of the window in WINDOW clause for OVER name. Window is nil then.
+	WindowName string
+	Window     *WindowDef
+}
+
+// Eval evaluates WindowFuncNode. It reaches here only when it's used where window functions are not allowed.
+func (w *WindowFuncNode) Eval() func(backend.Row) (core.Value, error) {
+	return func(backend.Row) (core.Value, error) {
+		return nil, errors.New("ERROR:  window functions are not allowed in this context")
+	}
+}
+
+// WindowDef is a window of OVER clause or WINDOW clause
+type WindowDef struct {
+	// RefName is the name of the window which the window is based on such as OVER (w ORDER BY x)
+	RefName     string
+	PartitionBy []ExpressionNode
+	OrderBy     []SortKey
+	// Frame is nil for the default frame, RANGE BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW
+	Frame *WindowFrame
+}
+
+// FrameMode is the unit of the offsets of a window frame
+type FrameMode int
+
+const (
+	// RangeFrame counts offsets by the value of ORDER BY key
+	RangeFrame FrameMode = iota
+
+	// RowsFrame counts offsets by rows
+	RowsFrame
+
+	// GroupsFrame counts offsets by peer groups
+	GroupsFrame
+)
+
+func (m FrameMode) String() string {
+	switch m {
+	case RowsFrame:
+		return "ROWS"
+	case GroupsFrame:
+		return "GROUPS"
+	}
+
+	return "RANGE"
+}
+
+// FrameBoundType is a kind of the start or the end of a window frame
+type FrameBoundType int
+
+const (
+	// UnboundedPreceding is UNBOUNDED PRECEDING
+	UnboundedPreceding FrameBoundType = iota
+
+	// OffsetPreceding is `offset PRECEDING`
+	OffsetPreceding
+
+	// CurrentRow is CURRENT ROW
+	CurrentRow
+
+	// OffsetFollowing is `offset FOLLOWING`
+	OffsetFollowing
+
+	// UnboundedFollowing is UNBOUNDED FOLLOWING
+	UnboundedFollowing
+)
+
+// FrameBound is the start or the end of a window frame
+type FrameBound struct {
+	Type   FrameBoundType
+	Offset ExpressionNode
+}
+
+// FrameExclusion is EXCLUDE option of a window frame
+type FrameExclusion int
+
+const (
+	// ExcludeNoOthers excludes no rows
+	ExcludeNoOthers FrameExclusion = iota
+
+	// ExcludeCurrentRow excludes the current row
+	ExcludeCurrentRow
+
+	// ExcludeGroup excludes the current row and its peers
+	ExcludeGroup
+
+	// ExcludeTies excludes the peers of the current row but not the current row itself
+	ExcludeTies
+)
+
+// WindowFrame is the set of rows of a partition for which a window function is evaluated
+type WindowFrame struct {
+	Mode    FrameMode
+	Start   FrameBound
+	End     FrameBound
+	Exclude FrameExclusion
+}
+
+// defaultFrame is the frame of a window without frame clause
+var defaultFrame = &WindowFrame{
+	Mode:  RangeFrame,
+	Start: FrameBound{Type: UnboundedPreceding},
+	End:   FrameBound{Type: CurrentRow},
+}
+
+// WindowNode is a node of window functions.
+// The result table has the columns of the source table followed by the results of Funcs.
+// Rows are returned in the order of the window of the last function as in PostgreSQL.
+type WindowNode struct {
+	Funcs  []*WindowFuncNode
+	RANode RelationalAlgebraNode
+}
+
+// Eval evaluates WindowNode
+func (w *WindowNode) Eval(db backend.DB) (backend.Table, error) {
+	tb, err := w.RANode.Eval(db)
+	if err != nil {
+		return nil, err
+	}
+	if tb == nil {
+		// select without from clause has a row which has no columns
+		tb = backend.NewTable("", core.Cols{}, core.ValuesList{{}})
+	}
+
+	rows := tb.GetRows()
+	results := make(core.ValuesList, len(rows))
+	order := make([]int, len(rows))
+	for i := range rows {
+		results[i] = make(core.Values, len(w.Funcs))
+		order[i] = i
+	}
+	for k, fn := range w.Funcs {
+		order, err = evalWindowFunc(db, fn, rows, order, func(i int, v core.Value) { results[i][k] = v })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	valsList := make(core.ValuesList, 0, len(rows))
+	for _, i := range order {
+		vals := append(core.Values{}, rows[i].GetValues()...)
+		valsList = append(valsList, append(vals, results[i]...))
+	}
+	cols := append(core.Cols{}, tb.GetCols()...)
+	nsrc := len(cols)
+	for k, fn := range w.Funcs {
+		typ, ok := exprType(fn, tb.GetCols())
+		if !ok {
+			typ = inferValuesType(valsList, nsrc+k, core.Integer)
+		}
+		cols = append(cols, core.Col{ColName: windowColName(k), ColType: typ})
+	}
+
+	return backend.NewTable(tb.GetName(), cols, valsList), nil
+}
+
+func windowColName(k int) core.ColumnName {
+	return core.ColumnName{Name: fmt.Sprintf("?window%d?", k+1)}
+}
+
+// evalWindowFunc evaluates fn for each row and gives the results to set.
+// Partitions are made in the order of rows given by order, and the order
+// in which rows are sorted by the window is returned.
+func evalWindowFunc(db backend.DB, fn *WindowFuncNode, rows []backend.Row, order []int, set func(int, core.Value)) ([]int, error) {
+	def := fn.Window
+	frame, err := evalFrame(db, def)
+	if err != nil {
+		return nil, err
+	}
+	partFns := make([]func(backend.Row) (core.Value, error), 0, len(def.PartitionBy))
+	for _, expr := range def.PartitionBy {
+		partFns = append(partFns, scoped(db, expr.Eval()))
+	}
+	keyFns := make([]func(backend.Row) (core.Value, error), 0, len(def.OrderBy))
+	for _, key := range def.OrderBy {
+		keyFns = append(keyFns, scoped(db, key.Expr.Eval()))
+	}
+	input := newAggInput(db, &AggCallNode{Args: fn.Args, Filter: fn.Filter})
+
+	parts := make([][]int, 0)
+	partIdx := make(map[interface{}]int)
+	for _, i := range order {
+		vals, err := evalValues(partFns, rows[i])
+		if err != nil {
+			return nil, err
+		}
+		h := core.HashKey(vals)
+		k, ok := partIdx[h]
+		if !ok {
+			k = len(parts)
+			partIdx[h] = k
+			parts = append(parts, nil)
+		}
+		parts[k] = append(parts[k], i)
+	}
+
+	newOrder := make([]int, 0, len(order))
+	for _, idxs := range parts {
+		p, err := newWindowPartition(rows, idxs, keyFns, def.OrderBy, frame)
+		if err != nil {
+			return nil, err
+		}
+		args := make(core.ValuesList, len(p.idxs))
+		for j, i := range p.idxs {
+			vals, ok, err := input.eval(rows[i])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				args[j] = vals
+			}
+		}
+
+		var vals core.Values
+		if wf, ok := windowFuncs[fn.FuncName]; ok {
+			vals, err = wf.eval(p, args)
+		} else {
+			vals, err = p.aggregate(fn.FuncName, args)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range p.idxs {
+			set(i, vals[j])
+		}
+		newOrder = append(newOrder, p.idxs...)
+	}
+
+	return newOrder, nil
+}
+
+func evalValues(fns []func(backend.Row) (core.Value, error), row backend.Row) (core.Values, error) {
+	vals := make(core.Values, 0, len(fns))
+	for _, fn := range fns {
+		v, err := fn(row)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+
+	return vals, nil
+}
+
+// frame is a window frame whose offsets are evaluated
+type frame struct {
+	*WindowFrame
+	start, end core.Value
+}
+
+func evalFrame(db backend.DB, def *WindowDef) (*frame, error) {
+	f := &frame{WindowFrame: def.Frame}
+	if f.WindowFrame == nil {
+		f.WindowFrame = defaultFrame
+	}
+
+	var err error
+	if f.start, err = evalFrameOffset(db, f.Mode, f.Start, "starting"); err != nil {
+		return nil, err
+	}
+	if f.end, err = evalFrameOffset(db, f.Mode, f.End, "ending"); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// evalFrameOffset evaluates the offset of a frame bound. The offset of ROWS and GROUPS is an integer,
+// and the offset of RANGE is a number which is added to or subtracted from the ORDER BY key.
+func evalFrameOffset(db backend.DB, mode FrameMode, bound FrameBound, which string) (core.Value, error) {
+	if bound.Offset == nil {
+		return nil, nil
+	}
+	v, err := scoped(db, bound.Offset.Eval())(&EmptyTableRow{})
+	if err != nil {
+		return nil, err
+	}
+
+	switch val := v.(type) {
+	case int:
+		if val < 0 {
+			return nil, fmt.Errorf("ERROR:  frame %v offset must not be negative", which)
+		}
+	case float64:
+		if val < 0 {
+			return nil, fmt.Errorf("ERROR:  frame %v offset must not be negative", which)
+		}
+		if mode != RangeFrame {
+			// a number is rounded to an integer as it's cast to bigint
+			return int(math.Round(val)), nil
+		}
+	default:
+		if isNull(v) {
+			return nil, fmt.Errorf("ERROR:  frame %v offset must not be null", which)
+		}
+		return nil, fmt.Errorf("ERROR:  argument of %v must be type bigint, not type %v", mode, core.TypeName(v))
+	}
+
+	return v, nil
+}
+
+// windowPartition is a partition of rows sorted by ORDER BY of the window.
+// Rows which have the same values of ORDER BY keys are peers.
+type windowPartition struct {
+	// idxs are indexes of the rows in the source table
+	idxs  []int
+	keys  core.ValuesList
+	frame *frame
+	// desc is true if the rows are sorted in descending order of the first ORDER BY key
+	desc bool
+	// peers[j] is the index of the peer group of j-th row
+	peers []int
+	// groups[g] is the index of the first row of g-th peer group.
+	// It has an extra element which is the number of rows.
+	groups []int
+}
+
+func newWindowPartition(rows []backend.Row, idxs []int, keyFns []func(backend.Row) (core.Value, error), sortKeys []SortKey, f *frame) (*windowPartition, error) {
+	keyOf := make(map[int]core.Values, len(idxs))
+	for _, i := range idxs {
+		vals, err := evalValues(keyFns, rows[i])
+		if err != nil {
+			return nil, err
+		}
+		keyOf[i] = vals
+	}
+
+	var sortErr error
+	sort.SliceStable(idxs, func(a, b int) bool {
+		c, err := compareSortValues(keyOf[idxs[a]], keyOf[idxs[b]], sortKeys)
+		if err != nil {
+			sortErr = err
+		}
+		return c < 0
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	p := &windowPartition{idxs: idxs, frame: f, peers: make([]int, len(idxs))}
+	if len(sortKeys) > 0 {
+		p.desc = sortKeys[0].Desc
+	}
+	for j, i := range idxs {
+		p.keys = append(p.keys, keyOf[i])
+		if j == 0 {
+			p.groups = append(p.groups, 0)
+			continue
+		}
+		c, err := compareSortValues(p.keys[j-1], p.keys[j], sortKeys)
+		if err != nil {
+			return nil, err
+		}
+		if c != 0 {
+			p.groups = append(p.groups, j)
+		}
+		p.peers[j] = len(p.groups) - 1
+	}
+	p.groups = append(p.groups, len(idxs))
+	if f.Mode == RangeFrame && (f.start != nil || f.end != nil) {
+		if err := p.checkRangeKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (p *windowPartition) size() int {
+	return len(p.idxs)
+}
+
+// peerGroup returns the first row and the next of the last row of the peer group of j-th row
+func (p *windowPartition) peerGroup(j int) (int, int) {
+	g := p.peers[j]
+	return p.groups[g], p.groups[g+1]
+}
+
+// bounds returns the first row and the next of the last row of the frame of j-th row
+func (p *windowPartition) bounds(j int) (int, int) {
+	start := p.boundIndex(p.frame.Start, p.frame.start, j, true)
+	end := p.boundIndex(p.frame.End, p.frame.end, j, false)
+	if end < start {
+		end = start
+	}
+
+	return start, end
+}
+
+// boundIndex returns the index of the first row of the frame if isStart is true,
+// otherwise the index next to the last row of the frame.
+func (p *windowPartition) boundIndex(bound FrameBound, offset core.Value, j int, isStart bool) int {
+	n := p.size()
+	switch bound.Type {
+	case UnboundedPreceding:
+		return 0
+	case UnboundedFollowing:
+		return n
+	case CurrentRow:
+		if p.frame.Mode == RowsFrame {
+			if isStart {
+				return j
+			}
+			return j + 1
+		}
+		start, end := p.peerGroup(j)
+		if isStart {
+			return start
+		}
+		return end
+	}
+
+	if p.frame.Mode == RangeFrame {
+		return p.rangeIndex(bound, offset, j, isStart)
+	}
+
+	off := offset.(int)
+	if bound.Type == OffsetPreceding {
+		off = -off
+	}
+	if p.frame.Mode == RowsFrame {
+		pos := j + off
+		if !isStart {
+			pos++
+		}
+		return clamp(pos, 0, n)
+	}
+
+	g := p.peers[j] + off
+	ngroups := len(p.groups) - 1
+	switch {
+	case g < 0:
+		return 0
+	case g >= ngroups:
+		return n
+	case isStart:
+		return p.groups[g]
+	}
+
+	return p.groups[g+1]
+}
+
+// rangeIndex finds the bound of RANGE frame whose ORDER BY key is the key of j-th row plus or minus offset.
+// Rows whose key is NULL are peers of each other, so the frame of such a row is its peer group.
+func (p *windowPartition) rangeIndex(bound FrameBound, offset core.Value, j int, isStart bool) int {
+	if isNull(p.keys[j][0]) {
+		start, end := p.peerGroup(j)
+		if isStart {
+			return start
+		}
+		return end
+	}
+
+	// NULLs are placed at either end of the partition
+	lo, hi := 0, p.size()
+	for lo < hi && isNull(p.keys[lo][0]) {
+		lo++
+	}
+	for hi > lo && isNull(p.keys[hi-1][0]) {
+		hi--
+	}
+
+	// the distance is positive for the rows which follow the current row
+	dir := 1.0
+	if p.desc {
+		dir = -1
+	}
+	cur := toFloat(p.keys[j][0])
+	limit := toFloat(offset)
+	if bound.Type == OffsetPreceding {
+		limit = -limit
+	}
+	distance := func(k int) float64 {
+		return (toFloat(p.keys[k][0]) - cur) * dir
+	}
+
+	if isStart {
+		return lo + sort.Search(hi-lo, func(k int) bool { return distance(lo+k) >= limit })
+	}
+	return lo + sort.Search(hi-lo, func(k int) bool { return distance(lo+k) > limit })
+}
+
+// checkRangeKey checks that ORDER BY key of RANGE frame with offset is a number
+func (p *windowPartition) checkRangeKey() error {
+	for _, keys := range p.keys {
+		switch v := keys[0].(type) {
+		case int, float64:
+		default:
+			if !isNull(v) {
+				return fmt.Errorf("ERROR:  RANGE with offset PRECEDING/FOLLOWING is not supported for column type %v", core.TypeName(v))
+			}
+		}
+	}
+
+	return nil
+}
+
+func toFloat(v core.Value) float64 {
+	switch val := v.(type) {
+	case int:
+		return float64(val)
+	case float64:
+		return val
+	}
+
+	return 0
+}
+
+func clamp(x, min, max int) int {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+
+	return x
+}
+
+// frameRows returns the rows of the frame of j-th row except for the rows excluded by EXCLUDE option
+func (p *windowPartition) frameRows(j int) []int {
+	start, end := p.bounds(j)
+	rows := make([]int, 0, end-start)
+	for k := start; k < end; k++ {
+		switch p.frame.Exclude {
+		case ExcludeCurrentRow:
+			if k == j {
+				continue
+			}
+		case ExcludeGroup:
+			if p.peers[k] == p.peers[j] {
+				continue
+			}
+		case ExcludeTies:
+			if k != j && p.peers[k] == p.peers[j] {
+				continue
+			}
+		}
+		rows = append(rows, k)
+	}
+
+	return rows
+}
+
+// aggregate evaluates an aggregate function over the frame of each row.
+// args are the arguments of the rows, and they are nil for the rows filtered out by FILTER clause.
+func (p *windowPartition) aggregate(name string, args core.ValuesList) (core.Values, error) {
+	newAggregator := aggregateFuncs[name].newAggregator
+	res := make(core.Values, p.size())
+
+	if p.frame.Start.Type == UnboundedPreceding && p.frame.Exclude == ExcludeNoOthers {
+		// the frame only grows, so rows are aggregated incrementally
+		ag := newAggregator()
+		next := 0
+		for j := range res {
+			_, end := p.bounds(j)
+			for ; next < end; next++ {
+				if args[next] == nil {
+					continue
+				}
+				if err := ag.step(args[next]); err != nil {
+					return nil, err
+				}
+			}
+			v, err := ag.result()
+			if err != nil {
+				return nil, err
+			}
+			res[j] = v
+		}
+		return res, nil
+	}
+
+	for j := range res {
+		ag := newAggregator()
+		for _, k := range p.frameRows(j) {
+			if args[k] == nil {
+				continue
+			}
+			if err := ag.step(args[k]); err != nil {
+				return nil, err
+			}
+		}
+		v, err := ag.result()
+		if err != nil {
+			return nil, err
+		}
+		res[j] = v
+	}
+
+	return res, nil
+}
+
+type windowFunc struct {
+	minArgs, maxArgs int
+	// eval evaluates the function for each row of the partition. args are the arguments of the rows.
+	eval func(p *windowPartition, args core.ValuesList) (core.Values, error)
+}
+
+// windowFuncs are functions which can be called only with OVER clause.
+// Aggregate functions can also be called with OVER clause.
+var windowFuncs = map[string]windowFunc{
+	"row_number":   {eval: rowNumber},
+	"rank":         {eval: rank},
+	"dense_rank":   {eval: denseRank},
+	"percent_rank": {eval: percentRank},
+	"cume_dist":    {eval: cumeDist},
+	"ntile":        {minArgs: 1, maxArgs: 1, eval: ntile},
+	"lag":          {minArgs: 1, maxArgs: 3, eval: func(p *windowPartition, args core.ValuesList) (core.Values, error) { return shift(p, args, "lag", -1) }},
+	"lead":         {minArgs: 1, maxArgs: 3, eval: func(p *windowPartition, args core.ValuesList) (core.Values, error) { return shift(p, args, "lead", 1) }},
+	"first_value":  {minArgs: 1, maxArgs: 1, eval: firstValue},
+	"last_value":   {minArgs: 1, maxArgs: 1, eval: lastValue},
+	"nth_value":    {minArgs: 2, maxArgs: 2, eval: nthValue},
+}
+
+func rowNumber(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	res := make(core.Values, p.size())
+	for j := range res {
+		res[j] = j + 1
+	}
+
+	return res, nil
+}
+
+// rank is the row number of the first peer of the row
+func rank(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	res := make(core.Values, p.size())
+	for j := range res {
+		start, _ := p.peerGroup(j)
+		res[j] = start + 1
+	}
+
+	return res, nil
+}
+
+// denseRank is the number of the peer group of the row
+func denseRank(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	res := make(core.Values, p.size())
+	for j := range res {
+		res[j] = p.peers[j] + 1
+	}
+
+	return res, nil
+}
+
+// percentRank is (rank - 1) / (number of rows - 1)
+func percentRank(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	res := make(core.Values, p.size())
+	for j := range res {
+		start, _ := p.peerGroup(j)
+		if p.size() == 1 {
+			res[j] = 0.0
+			continue
+		}
+		res[j] = float64(start) / float64(p.size()-1)
+	}
+
+	return res, nil
+}
+
+// cumeDist is (number of rows preceding or peers of the row) / (number of rows)
+func cumeDist(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	res := make(core.Values, p.size())
+	for j := range res {
+		_, end := p.peerGroup(j)
+		res[j] = float64(end) / float64(p.size())
+	}
+
+	return res, nil
+}
+
+// ntile divides rows into the given number of buckets as equally as possible.
+// Former buckets have one more row than latter ones if rows can't be divided equally.
+func ntile(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	res := make(core.Values, p.size())
+	for j := range res {
+		v := args[j][0]
+		if isNull(v) {
+			res[j] = core.Null
+			continue
+		}
+		n, ok := v.(int)
+		if !ok {
+			return nil, fmt.Errorf("ERROR:  function ntile(%v) does not exist", core.TypeName(v))
+		}
+		if n <= 0 {
+			return nil, errors.New("ERROR:  argument of ntile must be greater than zero")
+		}
+
+		size, extra := p.size()/n, p.size()%n
+		if j < extra*(size+1) {
+			res[j] = j/(size+1) + 1
+		} else {
+			res[j] = extra + (j-extra*(size+1))/size + 1
+		}
+	}
+
+	return res, nil
+}
+
+// shift evaluates lag and lead. It returns the value of the row which is offset rows before or after the row,
+// or the default value if there is no such row.
+func shift(p *windowPartition, args core.ValuesList, name string, dir int) (core.Values, error) {
+	res := make(core.Values, p.size())
+	for j := range res {
+		offset := 1
+		if len(args[j]) > 1 {
+			v := args[j][1]
+			if isNull(v) {
+				res[j] = core.Null
+				continue
+			}
+			n, ok := v.(int)
+			if !ok {
+				return nil, fmt.Errorf("ERROR:  offset of %v must be type integer, not type %v", name, core.TypeName(v))
+			}
+			offset = n
+		}
+
+		k := j + offset*dir
+		switch {
+		case 0 <= k && k < p.size():
+			res[j] = nullIfNil(args[k][0])
+		case len(args[j]) > 2:
+			res[j] = nullIfNil(args[j][2])
+		default:
+			res[j] = core.Null
+		}
+	}
+
+	return res, nil
+}
+
+func firstValue(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	return nthOfFrame(p, args, func(int, []int) (int, error) { return 0, nil })
+}
+
+func lastValue(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	return nthOfFrame(p, args, func(_ int, rows []int) (int, error) { return len(rows) - 1, nil })
+}
+
+func nthValue(p *windowPartition, args core.ValuesList) (core.Values, error) {
+	return nthOfFrame(p, args, func(j int, rows []int) (int, error) {
+		v := args[j][1]
+		if isNull(v) {
+			return -1, nil
+		}
+		n, ok := v.(int)
+		if !ok {
+			return 0, fmt.Errorf("ERROR:  function nth_value(%v, %v) does not exist", core.TypeName(args[j][0]), core.TypeName(v))
+		}
+		if n <= 0 {
+			return 0, errors.New("ERROR:  argument of nth_value must be greater than zero")
+		}
+		return n - 1, nil
+	})
+}
+
+// nthOfFrame returns the value of the row of the frame at the position given by pos.
+// The value is NULL if there is no such row.
+func nthOfFrame(p *windowPartition, args core.ValuesList, pos func(int, []int) (int, error)) (core.Values, error) {
+	res := make(core.Values, p.size())
+	for j := range res {
+		rows := p.frameRows(j)
+		k, err := pos(j, rows)
+		if err != nil {
+			return nil, err
+		}
+		if k < 0 || k >= len(rows) {
+			res[j] = core.Null
+			continue
+		}
+		res[j] = nullIfNil(args[rows[k]][0])
+	}
+
+	return res, nil
+}